@@ -7,10 +7,13 @@
 package batchio
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -25,6 +28,8 @@ type Reader struct {
 
 	read        chan int
 	pendingRead bool
+	hold        bool  // buf[:nread] holds bytes that have not been returned
+	total       int64 // number of bytes returned by Next and Finish
 }
 
 // NewReader returns a new Reader that reads batches from r. The batches will
@@ -58,18 +63,17 @@ func NewReader(r io.ReadCloser, size int, timeAfterFirstByte time.Duration) *Rea
 // Next will return either a batch or an error. Once the underlying reader has
 // returned an error, the Next will return the same error on subsequent calls.
 func (r *Reader) Next(ctx context.Context) ([]byte, error) {
+	batch, err := r.next(ctx)
+	r.total += int64(len(batch))
+	return batch, err
+}
+
+func (r *Reader) next(ctx context.Context) ([]byte, error) {
 	// Wait on leftover read from last call.
-	if r.pendingRead {
-		select {
-		case n := <-r.read:
-			r.nread = copy(r.buf, r.buf[r.nread:r.nread+n])
-			r.pendingRead = false
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		}
-	} else {
-		r.nread = 0
+	if err := r.collect(ctx); err != nil {
+		return nil, err
 	}
+	r.hold = false
 
 	var timeout <-chan time.Time
 	for r.nread < len(r.buf) && r.err == nil {
@@ -78,18 +82,7 @@ func (r *Reader) Next(ctx context.Context) ([]byte, error) {
 			defer timer.Stop()
 			timeout = timer.C
 		}
-		go func() {
-			var n int
-			for i := 0; i < 5; i++ {
-				n, r.err = r.r.Read(r.buf[r.nread:])
-				if n > 0 || r.err != nil {
-					r.read <- n
-					return
-				}
-			}
-			r.err = io.ErrNoProgress
-			r.read <- 0
-		}()
+		go r.readAsync()
 		select {
 		case n := <-r.read:
 			r.nread += n
@@ -114,25 +107,257 @@ func (r *Reader) Next(ctx context.Context) ([]byte, error) {
 // Finish closes the underlying reader and returns a final batch if a Read was
 // pending. After the first call to Finish, it returns an error.
 func (r *Reader) Finish() ([]byte, error) {
+	batch, err := r.finish()
+	r.total += int64(len(batch))
+	return batch, err
+}
+
+// Total returns the number of bytes returned by Next and Finish over the
+// lifetime of the Reader. Bytes discarded by SkipPrefix or SkipLine are not
+// counted. The total only increases, and after Finish has been called, it is
+// the total size of the stream that was delivered to the caller. Total must
+// not be called concurrently with Next or Finish.
+func (r *Reader) Total() int64 {
+	return r.total
+}
+
+func (r *Reader) finish() ([]byte, error) {
 	if r.r == nil {
 		return nil, errors.New("batchio.Reader.Finish called multiple times")
 	}
 	err := r.r.Close()
 	if !r.pendingRead {
+		if r.hold {
+			r.hold = false
+			return r.buf[:r.nread], err
+		}
 		return nil, err
 	}
 	n := <-r.read
 	r.pendingRead = false
 	r.r = nil
+	if r.hold {
+		r.hold = false
+		return r.buf[:r.nread+n], err
+	}
 	return r.buf[r.nread : r.nread+n], err
 }
 
+// Err returns the error returned by the underlying reader, or nil if the
+// underlying reader has not returned an error yet. A non-nil error will be
+// returned by Next once any buffered bytes have been returned, so a caller
+// can use Err to decide to call Finish early. Like bufio.Scanner.Err, Err
+// does not block: if a read started by a previous call to Next is still in
+// progress, its error is not reported until it completes. Err must not be
+// called concurrently with Next.
+func (r *Reader) Err() error {
+	if r.pendingRead {
+		select {
+		case n := <-r.read:
+			// The read has completed, so r.err is safe to access.
+			// Put the count back for the next call to collect.
+			r.read <- n
+		default:
+			return nil
+		}
+	}
+	return r.err
+}
+
+// ClearError discards the error returned by the underlying reader, if any,
+// so that subsequent calls to Next read from the underlying reader again.
+// It returns the error that was cleared. This allows resuming from a reader
+// that can recover from transient failures. If the stored error is io.EOF,
+// ClearError does nothing and returns nil.
+//
+// Like Err, ClearError does not block: if a read started by a previous call
+// to Next is still in progress, ClearError clears nothing and returns nil, and
+// any error from that read is returned by a later call to Next. ClearError
+// must not be called concurrently with Next.
+func (r *Reader) ClearError() error {
+	if r.pendingRead {
+		select {
+		case n := <-r.read:
+			// The read has completed, so r.err is safe to access.
+			// Put the count back for the next call to collect.
+			r.read <- n
+		default:
+			return nil
+		}
+	}
+	err := r.err
+	if err == io.EOF {
+		return nil
+	}
+	r.err = nil
+	return err
+}
+
+// A Batch is a single result from Reader.Batches. Exactly one of Data or Err
+// will be set.
+type Batch struct {
+	Data []byte
+	Err  error
+}
+
+// Batches starts a goroutine that calls Next repeatedly and sends each batch
+// on the returned channel. Each batch's Data is a copy, so it remains valid
+// after subsequent batches are received. When Next returns an error, Batches
+// sends a Batch with that error and closes the channel. If the Context is
+// Done while waiting to send a batch, the channel is closed without sending it.
+//
+// bufferSize is the capacity of the returned channel: at most bufferSize
+// batches will be read ahead of the receiver, applying backpressure to the
+// underlying reader when the receiver falls behind. With a bufferSize of 0,
+// the goroutine blocks until each batch is received before reading the next.
+//
+// The Reader must not be used by any other goroutine until the channel is
+// closed. Once the channel is closed, the caller should call Finish.
+func (r *Reader) Batches(ctx context.Context, bufferSize int) <-chan Batch {
+	if bufferSize < 0 {
+		panic("batchio.Reader.Batches(..., <negative buffer size>)")
+	}
+	c := make(chan Batch, bufferSize)
+	go func() {
+		defer close(c)
+		for {
+			batch, err := r.Next(ctx)
+			var b Batch
+			if err != nil {
+				b.Err = err
+			} else {
+				b.Data = append([]byte(nil), batch...)
+			}
+			select {
+			case c <- b:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// SkipPrefix reads and discards the first n bytes of the stream before
+// batching begins. It can be used to strip a byte-order mark or other fixed
+// preamble. If the underlying reader returns an error before n bytes have
+// been discarded, SkipPrefix returns that error, as will subsequent calls
+// to Next. Any bytes read past the prefix are retained for the next batch.
+//
+// If ctx is done before the whole prefix has been discarded, SkipPrefix
+// returns ctx.Err(). The bytes discarded up to that point are not returned by
+// Next, but the rest of the prefix is, and a read started by SkipPrefix may
+// still be in progress, as after Next returns a context error. Since the
+// number of bytes already discarded is not reported, the skip cannot be
+// resumed by calling SkipPrefix again.
+func (r *Reader) SkipPrefix(ctx context.Context, n int) error {
+	if n < 0 {
+		panic("batchio.Reader.SkipPrefix(..., <negative size>)")
+	}
+	return r.skip(ctx, func(b []byte) (int, bool) {
+		if len(b) >= n {
+			return n, true
+		}
+		n -= len(b)
+		return len(b), false
+	})
+}
+
+// SkipLine reads and discards bytes up to and including the next newline
+// ('\n'), such as a header line. If the underlying reader returns an error
+// before a newline is found, SkipLine returns that error, as will subsequent
+// calls to Next. Any bytes read past the newline are retained for the next
+// batch.
+//
+// If ctx is done before a newline is found, SkipLine returns ctx.Err(). The
+// part of the line read up to that point has been discarded, and a read
+// started by SkipLine may still be in progress, as after Next returns a
+// context error. Calling SkipLine again discards the rest of the line.
+func (r *Reader) SkipLine(ctx context.Context) error {
+	return r.skip(ctx, func(b []byte) (int, bool) {
+		if i := bytes.IndexByte(b, '\n'); i != -1 {
+			return i + 1, true
+		}
+		return len(b), false
+	})
+}
+
+// skip discards bytes from the front of the stream. discard is called with
+// the bytes that have been read but not yet returned and reports how many of
+// them to drop and whether skipping is complete. discard must drop all of
+// the bytes it is given if it reports skipping is not complete.
+func (r *Reader) skip(ctx context.Context, discard func([]byte) (int, bool)) error {
+	for {
+		if err := r.collect(ctx); err != nil {
+			return err
+		}
+		r.hold = true
+		n, done := discard(r.buf[:r.nread])
+		r.nread = copy(r.buf, r.buf[n:r.nread])
+		if done {
+			return nil
+		}
+		if r.err != nil {
+			return r.err
+		}
+		r.pendingRead = true
+		go r.readAsync()
+	}
+}
+
+// collect waits for any read still pending from a previous call and moves
+// the bytes that have not been returned to the front of the buffer, setting
+// nread to their length.
+func (r *Reader) collect(ctx context.Context) error {
+	if !r.pendingRead {
+		if !r.hold {
+			r.nread = 0
+		}
+		return nil
+	}
+	select {
+	case n := <-r.read:
+		if r.hold {
+			r.nread += n
+		} else {
+			r.nread = copy(r.buf, r.buf[r.nread:r.nread+n])
+		}
+		r.pendingRead = false
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// readAsync reads from the underlying reader into the unused portion of the
+// buffer and sends the number of bytes read on r.read.
+func (r *Reader) readAsync() {
+	var n int
+	for i := 0; i < 5; i++ {
+		n, r.err = r.r.Read(r.buf[r.nread:])
+		if n > 0 || r.err != nil {
+			r.read <- n
+			return
+		}
+	}
+	r.err = io.ErrNoProgress
+	r.read <- 0
+}
+
 // A Writer is a buffered io.Writer that writes batches to an underlying
 // io.Writer object. If an error occurs writing to a Writer, no more data will
 // be accepted and all subsequent writes, and Flush, will return the error.
 // After all data has been written, the client should call the Flush method to
 // guarantee all data has been forwarded to the underlying io.Writer object.
 type Writer struct {
+	// Accessed atomically, but only written while holding onto mu.
+	// Kept first for 64-bit alignment.
+	droppedWrites int64 // number of writes dropped in lossy mode
+	droppedBytes  int64 // number of bytes dropped in lossy mode
+
 	w         io.Writer
 	tafb      time.Duration
 	timerDone chan struct{} // sent to when the AfterFunc has completed
@@ -140,8 +365,11 @@ type Writer struct {
 	mu        sync.Mutex
 	buf       []byte // a writer goroutine is running iff len(buf) > 0
 	err       error
+	idle      time.Duration
+	lossy     bool
 	flushChan chan struct{} // signal to the writer goroutine to start (has a buffer of 1)
 	timer     *time.Timer   // return value of AfterFunc that trigger a flush
+	idleTimer *time.Timer   // return value of AfterFunc that triggers a flush after idle; may be nil
 	writeDone chan struct{} // closed when the writer goroutine returns
 }
 
@@ -166,6 +394,73 @@ func NewWriter(w io.Writer, size int, timeAfterFirstByte time.Duration) *Writer
 	}
 }
 
+// NewWriteCloser returns a new Writer like NewWriter whose Close method
+// flushes the Writer and then closes w. Close always closes w, even if the
+// flush fails. If both the flush and the close fail, the returned error wraps
+// the flush error and mentions the close error.
+func NewWriteCloser(w io.WriteCloser, size int, timeAfterFirstByte time.Duration) io.WriteCloser {
+	return writeCloser{
+		Writer: NewWriter(w, size, timeAfterFirstByte),
+		c:      w,
+	}
+}
+
+type writeCloser struct {
+	*Writer
+	c io.Closer
+}
+
+func (wc writeCloser) Close() error {
+	flushErr := wc.Flush()
+	closeErr := wc.c.Close()
+	switch {
+	case flushErr != nil && closeErr != nil:
+		return fmt.Errorf("%w (close: %v)", flushErr, closeErr)
+	case flushErr != nil:
+		return flushErr
+	default:
+		return closeErr
+	}
+}
+
+// SetIdleFlush sets the duration of write inactivity after which any buffered
+// data is written, even if the time after the first byte has not yet elapsed.
+// Each call to Write restarts the idle period. Unlike the time after the first
+// byte, which bounds the latency of a batch, the idle period lets a batch be
+// written promptly once a burst of writes has ended. A duration of zero (the
+// default) disables idle flushing. The new duration applies starting with the
+// next batch.
+func (w *Writer) SetIdleFlush(d time.Duration) {
+	if d < 0 {
+		panic("batchio.Writer.SetIdleFlush(<negative duration>)")
+	}
+	w.mu.Lock()
+	w.idle = d
+	w.mu.Unlock()
+}
+
+// SetLossy sets whether the Writer drops data rather than wait for the
+// underlying io.Writer. In lossy mode, a write that does not fit in the
+// remaining buffer space is discarded in its entirety and Write reports it as
+// successful; a full buffer is handed off to be written in the background
+// without waiting. Use Dropped to observe how much data has been discarded.
+// Write may still wait while a batch is being written to the underlying
+// io.Writer.
+func (w *Writer) SetLossy(lossy bool) {
+	w.mu.Lock()
+	w.lossy = lossy
+	w.mu.Unlock()
+}
+
+// Dropped returns the number of writes that were discarded in lossy mode and
+// their total size in bytes. In non-lossy mode, no writes are dropped, so the
+// counts only increase while lossy mode is enabled. It is safe to call Dropped
+// concurrently with other methods, and it does not wait for a batch being
+// written to the underlying io.Writer.
+func (w *Writer) Dropped() (batches int, bytes int64) {
+	return int(atomic.LoadInt64(&w.droppedWrites)), atomic.LoadInt64(&w.droppedBytes)
+}
+
 // Write writes the contents of p into the buffer. It returns the number of
 // bytes written. If n < len(p), it also returns an error explaining why the
 // write is short.
@@ -175,9 +470,41 @@ func (w *Writer) Write(p []byte) (n int, err error) {
 	}
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	return w.writeLocked(p)
+}
+
+// WriteVec writes the contents of each chunk into the buffer in order, as if
+// by calling Write with their concatenation, but without joining them first.
+// It returns the total number of bytes accepted across all chunks. If
+// n is less than the total length of the chunks, it also returns an error
+// explaining why the write is short.
+func (w *Writer) WriteVec(chunks [][]byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, p := range chunks {
+		if len(p) == 0 {
+			continue
+		}
+		nn, err := w.writeLocked(p)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// writeLocked buffers p, which must not be empty. The caller must be holding
+// onto w.mu.
+func (w *Writer) writeLocked(p []byte) (n int, err error) {
 	if w.err != nil {
 		return 0, w.err
 	}
+	if w.lossy && len(w.buf)+len(p) > cap(w.buf) {
+		atomic.AddInt64(&w.droppedWrites, 1)
+		atomic.AddInt64(&w.droppedBytes, int64(len(p)))
+		return len(p), nil
+	}
 	if len(w.buf) > 0 {
 		// Goroutine has started, but is waiting for flush.
 		// Append data to buffer without exceeding capacity.
@@ -186,6 +513,13 @@ func (w *Writer) Write(p []byte) (n int, err error) {
 		p = p[n:]
 		if len(w.buf) < cap(w.buf) {
 			// Not enough data to trigger a flush.
+			if w.idleTimer != nil {
+				w.idleTimer.Reset(w.idle)
+			}
+			return n, nil
+		}
+		if w.lossy {
+			w.signalLocked()
 			return n, nil
 		}
 		w.flushLocked()
@@ -196,12 +530,11 @@ func (w *Writer) Write(p []byte) (n int, err error) {
 	// No goroutine running. First, synchronously batch any data from the
 	// beginning of the current write until the remaining data is less than the
 	// buffer size.
-	for len(p) >= cap(w.buf) {
+	for !w.lossy && len(p) >= cap(w.buf) {
 		var nn int
 		nn, w.err = w.w.Write(p[:cap(w.buf)])
 		n += nn
-		if err != nil {
-			w.err = err
+		if w.err != nil {
 			return n, w.err
 		}
 		p = p[nn:]
@@ -215,15 +548,23 @@ func (w *Writer) Write(p []byte) (n int, err error) {
 	}
 	flushChan := make(chan struct{}, 1) // variable captured for AfterFunc
 	w.flushChan = flushChan
-	w.timer = time.AfterFunc(w.tafb, func() {
+	signal := func() {
 		select {
 		case flushChan <- struct{}{}:
 		default:
 			// Already signaled.
 		}
-	})
+	}
+	w.timer = time.AfterFunc(w.tafb, signal)
+	if w.idle > 0 {
+		w.idleTimer = time.AfterFunc(w.idle, signal)
+	}
 	w.writeDone = make(chan struct{})
 	go w.backgroundWrite()
+	if len(w.buf) == cap(w.buf) {
+		// Only possible in lossy mode.
+		w.signalLocked()
+	}
 	return n, nil
 }
 
@@ -231,6 +572,7 @@ func (w *Writer) backgroundWrite() {
 	// Wait for first of:
 	// a) buffer is full
 	// b) timer has expired
+	// c) idle timer has expired
 	<-w.flushChan
 
 	// Holding onto the lock while writing avoids having to communicate to the
@@ -245,6 +587,10 @@ func (w *Writer) backgroundWrite() {
 	w.flushChan = nil
 	w.timer.Stop()
 	w.timer = nil
+	if w.idleTimer != nil {
+		w.idleTimer.Stop()
+		w.idleTimer = nil
+	}
 	close(w.writeDone)
 	w.writeDone = nil
 }
@@ -263,13 +609,19 @@ func (w *Writer) Flush() error {
 // write and waits for it to finish. The caller must be holding onto w.mu and
 // should always check w.err afterward.
 func (w *Writer) flushLocked() {
+	w.signalLocked()
+	done := w.writeDone
+	w.mu.Unlock()
+	<-done
+	w.mu.Lock()
+}
+
+// signalLocked signals to the writer goroutine that it should proceed with the
+// write without waiting for it. The caller must be holding onto w.mu.
+func (w *Writer) signalLocked() {
 	select {
 	case w.flushChan <- struct{}{}:
 	default:
 		// Already signaled.
 	}
-	done := w.writeDone
-	w.mu.Unlock()
-	<-done
-	w.mu.Lock()
 }
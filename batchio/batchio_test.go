@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"io/ioutil"
 	"strings"
 	"sync"
 	"testing"
@@ -201,6 +202,554 @@ func TestReader(t *testing.T) {
 	})
 }
 
+func TestReaderSkip(t *testing.T) {
+	ctx := context.Background()
+	if d, ok := t.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, d)
+		t.Cleanup(cancel)
+	}
+
+	t.Run("PrefixSpanningBatches", func(t *testing.T) {
+		r := &fakeReader{
+			steps: []readStep{
+				{data: "Hello, World!\n"},
+			},
+			waits: make(chan struct{}),
+		}
+		b := NewReader(r, 5, 30*time.Second)
+		if err := b.SkipPrefix(ctx, 7); err != nil {
+			t.Fatal("SkipPrefix:", err)
+		}
+		got, err := readAllBatches(ctx, b)
+		if err != nil {
+			t.Error(err)
+		}
+		want := []string{"World", "!\n"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("batches (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ByteOrderMark", func(t *testing.T) {
+		r := &fakeReader{
+			steps: []readStep{
+				{data: "\xef\xbb"},
+				{data: "\xbfkey=value\n"},
+			},
+			waits: make(chan struct{}),
+		}
+		b := NewReader(r, 64, 30*time.Second)
+		if err := b.SkipPrefix(ctx, 3); err != nil {
+			t.Fatal("SkipPrefix:", err)
+		}
+		got, err := readAllBatches(ctx, b)
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff("key=value\n", strings.Join(got, "")); diff != "" {
+			t.Errorf("content (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("PrefixLongerThanStream", func(t *testing.T) {
+		r := &fakeReader{
+			steps: []readStep{
+				{data: "abc"},
+			},
+			waits: make(chan struct{}),
+		}
+		b := NewReader(r, 64, 30*time.Second)
+		if err := b.SkipPrefix(ctx, 10); !errors.Is(err, io.EOF) {
+			t.Errorf("SkipPrefix(ctx, 10) = %v; want %v", err, io.EOF)
+		}
+		if batch, err := b.Next(ctx); len(batch) > 0 || !errors.Is(err, io.EOF) {
+			t.Errorf("b.Next(ctx) = %q, %v; want \"\", %v", batch, err, io.EOF)
+		}
+	})
+
+	t.Run("Line", func(t *testing.T) {
+		r := &fakeReader{
+			steps: []readStep{
+				{data: "name,val"},
+				{data: "ue\nfoo,1\nbar,2\n"},
+			},
+			waits: make(chan struct{}),
+		}
+		b := NewReader(r, 4, 30*time.Second)
+		if err := b.SkipLine(ctx); err != nil {
+			t.Fatal("SkipLine:", err)
+		}
+		got, err := readAllBatches(ctx, b)
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff("foo,1\nbar,2\n", strings.Join(got, "")); diff != "" {
+			t.Errorf("content (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("LineWithoutNewline", func(t *testing.T) {
+		r := &fakeReader{
+			steps: []readStep{
+				{data: "just a header"},
+			},
+			waits: make(chan struct{}),
+		}
+		b := NewReader(r, 64, 30*time.Second)
+		if err := b.SkipLine(ctx); !errors.Is(err, io.EOF) {
+			t.Errorf("SkipLine(ctx) = %v; want %v", err, io.EOF)
+		}
+	})
+
+	t.Run("LineCanceled", func(t *testing.T) {
+		skipCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		r := &fakeReader{
+			steps: []readStep{
+				{data: "na"},
+				{triggerCancel: true, waitBefore: true, data: "me\nfoo\n"},
+			},
+			waits:  make(chan struct{}),
+			cancel: cancel,
+		}
+		b := NewReader(r, 64, 30*time.Second)
+		if err := b.SkipLine(skipCtx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("SkipLine(skipCtx) = %v; want %v", err, context.Canceled)
+		}
+		r.waits <- struct{}{}
+		// Skipping again discards the rest of the line.
+		if err := b.SkipLine(ctx); err != nil {
+			t.Fatal("SkipLine:", err)
+		}
+		got, err := readAllBatches(ctx, b)
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff("foo\n", strings.Join(got, "")); diff != "" {
+			t.Errorf("content (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("PrefixCanceled", func(t *testing.T) {
+		skipCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		r := &fakeReader{
+			steps: []readStep{
+				{data: "ab"},
+				{triggerCancel: true, waitBefore: true, data: "cdef"},
+			},
+			waits:  make(chan struct{}),
+			cancel: cancel,
+		}
+		b := NewReader(r, 64, 30*time.Second)
+		if err := b.SkipPrefix(skipCtx, 4); !errors.Is(err, context.Canceled) {
+			t.Fatalf("SkipPrefix(skipCtx, 4) = %v; want %v", err, context.Canceled)
+		}
+		r.waits <- struct{}{}
+		// The bytes discarded before cancellation stay discarded, and the
+		// rest of the prefix is returned by Next.
+		got, err := readAllBatches(ctx, b)
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := cmp.Diff("cdef", strings.Join(got, "")); diff != "" {
+			t.Errorf("content (-want +got):\n%s", diff)
+		}
+	})
+}
+
+// readAllBatches calls Next until it returns an error, then calls Finish.
+// io.EOF is not treated as an error.
+func readAllBatches(ctx context.Context, b *Reader) ([]string, error) {
+	var batches []string
+	for {
+		batch, err := b.Next(ctx)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				b.Finish()
+				return batches, err
+			}
+			break
+		}
+		batches = append(batches, string(batch))
+	}
+	last, err := b.Finish()
+	if len(last) > 0 {
+		batches = append(batches, string(last))
+	}
+	return batches, err
+}
+
+func TestReaderBatches(t *testing.T) {
+	ctx := context.Background()
+	if d, ok := t.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, d)
+		t.Cleanup(cancel)
+	}
+
+	t.Run("AllBatches", func(t *testing.T) {
+		r := &fakeReader{
+			steps: []readStep{
+				{data: "Hello, World!\n"},
+			},
+			waits: make(chan struct{}),
+		}
+		b := NewReader(r, 5, 30*time.Second)
+		var got []string
+		var lastErr error
+		for batch := range b.Batches(ctx, 0) {
+			if batch.Err != nil {
+				lastErr = batch.Err
+				continue
+			}
+			got = append(got, string(batch.Data))
+		}
+		if !errors.Is(lastErr, io.EOF) {
+			t.Errorf("last error = %v; want %v", lastErr, io.EOF)
+		}
+		if _, err := b.Finish(); err != nil {
+			t.Error("Finish:", err)
+		}
+		want := []string{"Hello", ", Wor", "ld!\n"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("batches (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Backpressure", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		r := new(countingReader)
+		b := NewReader(r, 1, 30*time.Second)
+		const bufferSize = 2
+		c := b.Batches(ctx, bufferSize)
+
+		// The goroutine fills the channel buffer, then reads one more batch
+		// that it blocks trying to send.
+		const wantReads = bufferSize + 1
+		if !r.waitForReads(ctx, wantReads) {
+			t.Fatal("Timed out waiting for reads")
+		}
+		time.Sleep(20 * time.Millisecond)
+		if got := r.reads(); got != wantReads {
+			t.Errorf("%d reads while receiver stalled; want %d", got, wantReads)
+		}
+
+		// Receiving one batch unblocks exactly one more read.
+		<-c
+		if !r.waitForReads(ctx, wantReads+1) {
+			t.Fatal("Timed out waiting for reads")
+		}
+		time.Sleep(20 * time.Millisecond)
+		if got := r.reads(); got != wantReads+1 {
+			t.Errorf("%d reads after receiving one batch; want %d", got, wantReads+1)
+		}
+
+		cancel()
+		for range c {
+		}
+		if _, err := b.Finish(); err != nil {
+			t.Error("Finish:", err)
+		}
+	})
+}
+
+// countingReader is an infinite stream of bytes that counts the number of
+// calls to Read.
+func TestReaderTotal(t *testing.T) {
+	ctx := context.Background()
+	if d, ok := t.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, d)
+		t.Cleanup(cancel)
+	}
+	release := make(chan struct{})
+	r := &recoverableReader{
+		steps: []recoverableStep{
+			{data: "Hello, World!!\n"},
+			{data: "Last"},
+		},
+		wait: map[int]<-chan struct{}{1: release},
+	}
+	b := NewReader(r, 5, 10*time.Millisecond)
+	if got := b.Total(); got != 0 {
+		t.Errorf("Total() before Next = %d; want 0", got)
+	}
+	var want int64
+	// Three full batches.
+	for want < int64(len("Hello, World!!\n")) {
+		batch, err := b.Next(ctx)
+		if err != nil {
+			t.Fatal("Next:", err)
+		}
+		want += int64(len(batch))
+		if got := b.Total(); got != want {
+			t.Errorf("Total() = %d; want %d", got, want)
+		}
+	}
+	// The read of "Last" is pending until released, so Next returns early.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := b.Next(cancelCtx); err == nil {
+		t.Fatal("Next(canceled) did not return an error")
+	}
+	close(release)
+	last, err := b.Finish()
+	if err != nil {
+		t.Error("Finish:", err)
+	}
+	if string(last) != "Last" {
+		t.Errorf("Finish() = %q; want \"Last\"", last)
+	}
+	if got, want := b.Total(), int64(len("Hello, World!!\nLast")); got != want {
+		t.Errorf("Total() after Finish = %d; want %d", got, want)
+	}
+}
+
+func TestReaderClearError(t *testing.T) {
+	ctx := context.Background()
+	if d, ok := t.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, d)
+		t.Cleanup(cancel)
+	}
+
+	transient := errors.New("transient")
+	r := &recoverableReader{
+		steps: []recoverableStep{
+			{data: "abc"},
+			{err: transient},
+			{data: "def", err: io.EOF},
+		},
+	}
+	b := NewReader(r, 3, 30*time.Second)
+	got, err := b.Next(ctx)
+	if string(got) != "abc" || err != nil {
+		t.Fatalf("first Next(ctx) = %q, %v; want \"abc\", <nil>", got, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := b.Next(ctx); err != transient {
+			t.Fatalf("Next(ctx) after failure = _, %v; want _, %v", err, transient)
+		}
+	}
+	if err := b.ClearError(); err != transient {
+		t.Errorf("ClearError() = %v; want %v", err, transient)
+	}
+	got, err = b.Next(ctx)
+	if string(got) != "def" || err != nil {
+		t.Fatalf("Next(ctx) after ClearError = %q, %v; want \"def\", <nil>", got, err)
+	}
+	if _, err := b.Next(ctx); err != io.EOF {
+		t.Fatalf("Next(ctx) at end = _, %v; want _, %v", err, io.EOF)
+	}
+	if err := b.ClearError(); err != nil {
+		t.Errorf("ClearError() at EOF = %v; want <nil>", err)
+	}
+	if _, err := b.Next(ctx); err != io.EOF {
+		t.Errorf("Next(ctx) after ClearError at EOF = _, %v; want _, %v", err, io.EOF)
+	}
+	if _, err := b.Finish(); err != nil {
+		t.Error("Finish:", err)
+	}
+}
+
+func TestReaderErr(t *testing.T) {
+	ctx := context.Background()
+	if d, ok := t.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, d)
+		t.Cleanup(cancel)
+	}
+	failure := errors.New("bork")
+
+	t.Run("WithBatch", func(t *testing.T) {
+		r := &recoverableReader{
+			steps: []recoverableStep{
+				{data: "abc", err: failure},
+			},
+		}
+		b := NewReader(r, 64, 30*time.Second)
+		if err := b.Err(); err != nil {
+			t.Errorf("Err() before Next = %v; want <nil>", err)
+		}
+		got, err := b.Next(ctx)
+		if string(got) != "abc" || err != nil {
+			t.Fatalf("Next(ctx) = %q, %v; want \"abc\", <nil>", got, err)
+		}
+		if err := b.Err(); err != failure {
+			t.Errorf("Err() = %v; want %v", err, failure)
+		}
+		if _, err := b.Next(ctx); err != failure {
+			t.Errorf("Next(ctx) = _, %v; want _, %v", err, failure)
+		}
+		if _, err := b.Finish(); err != nil {
+			t.Error("Finish:", err)
+		}
+	})
+
+	t.Run("PendingRead", func(t *testing.T) {
+		release := make(chan struct{})
+		r := &recoverableReader{
+			steps: []recoverableStep{
+				{data: "abc"},
+				{err: failure},
+			},
+			wait: map[int]<-chan struct{}{1: release},
+		}
+		b := NewReader(r, 64, 10*time.Millisecond)
+		got, err := b.Next(ctx)
+		if string(got) != "abc" || err != nil {
+			t.Fatalf("Next(ctx) = %q, %v; want \"abc\", <nil>", got, err)
+		}
+		if err := b.Err(); err != nil {
+			t.Errorf("Err() while read pending = %v; want <nil>", err)
+		}
+		close(release)
+		for b.Err() == nil {
+			select {
+			case <-time.After(time.Millisecond):
+			case <-ctx.Done():
+				t.Fatal("Err() did not report error:", ctx.Err())
+			}
+		}
+		if err := b.Err(); err != failure {
+			t.Errorf("Err() after read = %v; want %v", err, failure)
+		}
+		if _, err := b.Next(ctx); err != failure {
+			t.Errorf("Next(ctx) = _, %v; want _, %v", err, failure)
+		}
+		if _, err := b.Finish(); err != nil {
+			t.Error("Finish:", err)
+		}
+	})
+}
+
+type recoverableStep struct {
+	data string
+	err  error
+}
+
+// recoverableReader returns each step's data and error in sequence. A step's
+// error is returned once all of its data has been read. Unlike most readers,
+// it continues to return data after an error.
+type recoverableReader struct {
+	steps []recoverableStep
+	wait  map[int]<-chan struct{} // step index to channel to wait on
+	n     int
+}
+
+func (r *recoverableReader) Read(p []byte) (int, error) {
+	if len(r.steps) == 0 {
+		return 0, io.EOF
+	}
+	if c := r.wait[r.n]; c != nil {
+		<-c
+		delete(r.wait, r.n)
+	}
+	curr := &r.steps[0]
+	n := copy(p, curr.data)
+	if n < len(curr.data) {
+		curr.data = curr.data[n:]
+		return n, nil
+	}
+	err := curr.err
+	r.steps = r.steps[1:]
+	r.n++
+	return n, err
+}
+
+func (r *recoverableReader) Close() error {
+	return nil
+}
+
+func TestReaderClearErrorPendingRead(t *testing.T) {
+	ctx := context.Background()
+	if d, ok := t.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, d)
+		t.Cleanup(cancel)
+	}
+
+	transient := errors.New("transient")
+	release := make(chan struct{})
+	r := &recoverableReader{
+		steps: []recoverableStep{
+			{data: "abc"},
+			{err: transient},
+			{data: "def", err: io.EOF},
+		},
+		wait: map[int]<-chan struct{}{1: release},
+	}
+	b := NewReader(r, 3, 30*time.Second)
+	got, err := b.Next(ctx)
+	if string(got) != "abc" || err != nil {
+		t.Fatalf("first Next(ctx) = %q, %v; want \"abc\", <nil>", got, err)
+	}
+	// Leave the failing read pending.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := b.Next(canceled); err == nil {
+		t.Fatal("Next(canceled) did not return an error")
+	}
+	if err := b.ClearError(); err != nil {
+		t.Errorf("ClearError() during pending read = %v; want <nil>", err)
+	}
+	close(release)
+	if _, err := b.Next(ctx); err != transient {
+		t.Fatalf("Next(ctx) after pending read = _, %v; want _, %v", err, transient)
+	}
+	if err := b.ClearError(); err != transient {
+		t.Errorf("ClearError() = %v; want %v", err, transient)
+	}
+	got, err = b.Next(ctx)
+	if string(got) != "def" || err != nil {
+		t.Fatalf("Next(ctx) after ClearError = %q, %v; want \"def\", <nil>", got, err)
+	}
+	if _, err := b.Finish(); err != nil {
+		t.Error("Finish:", err)
+	}
+}
+
+type countingReader struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	r.n++
+	r.mu.Unlock()
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+func (r *countingReader) reads() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.n
+}
+
+// waitForReads polls until at least n calls to Read have been made. It
+// returns false if the Context is Done first.
+func (r *countingReader) waitForReads(ctx context.Context, n int) bool {
+	for r.reads() < n {
+		select {
+		case <-time.After(time.Millisecond):
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+func (r *countingReader) Close() error {
+	return nil
+}
+
 type readStep struct {
 	triggerCancel bool // close fakeReader.cancel at start of read
 	waitBefore    bool // wait until Next returns before releasing bytes
@@ -345,6 +894,288 @@ func TestWriter(t *testing.T) {
 			t.Errorf("batches (-want +got):\n%s", diff)
 		}
 	})
+
+	t.Run("LargeWriteError", func(t *testing.T) {
+		// A write at least as large as the buffer is written synchronously.
+		wantErr := errors.New("bork")
+		w := NewWriter(errorWriter{wantErr}, 5, tafb)
+		if n, err := io.WriteString(w, "Hello, World!\n"); n != 0 || !errors.Is(err, wantErr) {
+			t.Errorf("w.Write(...) = %d, %v; want 0, %v", n, err, wantErr)
+		}
+		if err := w.Flush(); !errors.Is(err, wantErr) {
+			t.Errorf("w.Flush() = %v; want %v", err, wantErr)
+		}
+	})
+}
+
+func TestWriterIdleFlush(t *testing.T) {
+	t.Run("FlushAfterBurst", func(t *testing.T) {
+		rec := new(batchRecorder)
+		w := NewWriter(rec, 64, time.Hour)
+		w.SetIdleFlush(10 * time.Millisecond)
+		const want = "Hello, World!\n"
+		writeStrings(t, w, "Hello", ", ", "World!\n")
+		if t.Failed() {
+			t.Fatal("Test already failed: skipping wait for results.")
+		}
+		// The time after first byte is an hour, so only the idle timer can
+		// trigger the write.
+		rec.waitForBytes(len(want))
+		got := rec.get()
+		if diff := cmp.Diff([]string{want}, got); diff != "" {
+			t.Errorf("batches (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("WritesDelayFlush", func(t *testing.T) {
+		rec := new(batchRecorder)
+		w := NewWriter(rec, 64, time.Hour)
+		const idle = 100 * time.Millisecond
+		w.SetIdleFlush(idle)
+		start := time.Now()
+		const n = 5
+		for i := 0; i < n; i++ {
+			writeStrings(t, w, "x")
+			time.Sleep(idle / 10)
+		}
+		got := rec.get()
+		if time.Since(start) < idle && len(got) > 0 {
+			t.Errorf("batches written during write burst: %q", got)
+		}
+		rec.waitForBytes(n)
+		if got := strings.Join(rec.get(), ""); got != strings.Repeat("x", n) {
+			t.Errorf("got %q; want %q", got, strings.Repeat("x", n))
+		}
+	})
+}
+
+func TestWriterLossy(t *testing.T) {
+	t.Run("Drops", func(t *testing.T) {
+		rec := new(batchRecorder)
+		w := NewWriter(rec, 8, time.Hour)
+		w.SetLossy(true)
+		writeStrings(t, w, "abcd", "efghij", "efgh")
+		rec.waitForBytes(8)
+		writeStrings(t, w, "0123456789", "xy")
+		if err := w.Flush(); err != nil {
+			t.Error("Flush:", err)
+		}
+		if diff := cmp.Diff([]string{"abcdefgh", "xy"}, rec.get()); diff != "" {
+			t.Errorf("batches (-want +got):\n%s", diff)
+		}
+		if batches, n := w.Dropped(); batches != 2 || n != 16 {
+			t.Errorf("w.Dropped() = %d, %d; want 2, 16", batches, n)
+		}
+	})
+
+	t.Run("DroppedDuringWrite", func(t *testing.T) {
+		gw := &gatedWriter{
+			started: make(chan struct{}),
+			release: make(chan struct{}),
+		}
+		w := NewWriter(gw, 4, time.Hour)
+		w.SetLossy(true)
+		writeStrings(t, w, "abcd")
+		<-gw.started
+		// The background write is blocked on the underlying writer, so
+		// Dropped must not wait on it.
+		if batches, n := w.Dropped(); batches != 0 || n != 0 {
+			t.Errorf("w.Dropped() = %d, %d; want 0, 0", batches, n)
+		}
+		close(gw.release)
+		if err := w.Flush(); err != nil {
+			t.Error("Flush:", err)
+		}
+	})
+
+	t.Run("NotLossy", func(t *testing.T) {
+		rec := new(batchRecorder)
+		w := NewWriter(rec, 8, time.Hour)
+		writeStrings(t, w, "abcd", "efghij", "0123456789")
+		if err := w.Flush(); err != nil {
+			t.Error("Flush:", err)
+		}
+		if got := strings.Join(rec.get(), ""); got != "abcdefghij0123456789" {
+			t.Errorf("got %q; want %q", got, "abcdefghij0123456789")
+		}
+		if batches, n := w.Dropped(); batches != 0 || n != 0 {
+			t.Errorf("w.Dropped() = %d, %d; want 0, 0", batches, n)
+		}
+	})
+}
+
+// gatedWriter blocks its first Write until release is closed.
+type gatedWriter struct {
+	started chan struct{} // closed when the first Write starts
+	release chan struct{}
+	once    sync.Once
+}
+
+func (gw *gatedWriter) Write(p []byte) (int, error) {
+	gw.once.Do(func() { close(gw.started) })
+	<-gw.release
+	return len(p), nil
+}
+
+func TestWriteCloser(t *testing.T) {
+	t.Run("FlushAndClose", func(t *testing.T) {
+		rec := new(batchRecorder)
+		wc := &closeRecorder{Writer: rec}
+		w := NewWriteCloser(wc, 64, time.Hour)
+		const want = "Hello, World!\n"
+		writeStrings(t, w, want)
+		if err := w.Close(); err != nil {
+			t.Error("w.Close():", err)
+		}
+		if diff := cmp.Diff([]string{want}, rec.get()); diff != "" {
+			t.Errorf("batches (-want +got):\n%s", diff)
+		}
+		if wc.closes != 1 {
+			t.Errorf("underlying Close called %d times; want 1", wc.closes)
+		}
+	})
+
+	t.Run("CloseError", func(t *testing.T) {
+		closeErr := errors.New("close bork")
+		wc := &closeRecorder{Writer: new(batchRecorder), err: closeErr}
+		w := NewWriteCloser(wc, 64, time.Hour)
+		writeStrings(t, w, "Hello")
+		if err := w.Close(); !errors.Is(err, closeErr) {
+			t.Errorf("w.Close() = %v; want %v", err, closeErr)
+		}
+	})
+
+	t.Run("FlushError", func(t *testing.T) {
+		flushErr := errors.New("write bork")
+		wc := &closeRecorder{Writer: errorWriter{flushErr}}
+		w := NewWriteCloser(wc, 64, time.Hour)
+		writeStrings(t, w, "Hello")
+		if err := w.Close(); !errors.Is(err, flushErr) {
+			t.Errorf("w.Close() = %v; want %v", err, flushErr)
+		}
+		if wc.closes != 1 {
+			t.Errorf("underlying Close called %d times; want 1", wc.closes)
+		}
+	})
+
+	t.Run("BothErrors", func(t *testing.T) {
+		flushErr := errors.New("write bork")
+		closeErr := errors.New("close bork")
+		wc := &closeRecorder{Writer: errorWriter{flushErr}, err: closeErr}
+		w := NewWriteCloser(wc, 64, time.Hour)
+		writeStrings(t, w, "Hello")
+		err := w.Close()
+		if !errors.Is(err, flushErr) {
+			t.Errorf("w.Close() = %v; want %v", err, flushErr)
+		}
+		if err == nil || !strings.Contains(err.Error(), closeErr.Error()) {
+			t.Errorf("w.Close() = %v; want to mention %q", err, closeErr)
+		}
+	})
+}
+
+// closeRecorder is an io.WriteCloser that counts calls to Close.
+type closeRecorder struct {
+	io.Writer
+	err    error
+	closes int
+}
+
+func (wc *closeRecorder) Close() error {
+	wc.closes++
+	return wc.err
+}
+
+func TestWriterWriteVec(t *testing.T) {
+	const tafb = 10 * time.Millisecond
+
+	t.Run("SingleBatch", func(t *testing.T) {
+		rec := new(batchRecorder)
+		w := NewWriter(rec, 64, tafb)
+		chunks := [][]byte{[]byte("Hello"), nil, []byte(", "), []byte("World!\n")}
+		const want = "Hello, World!\n"
+		if n, err := w.WriteVec(chunks); n != len(want) || err != nil {
+			t.Errorf("w.WriteVec(...) = %d, %v; want %d, <nil>", n, err, len(want))
+		}
+		if err := w.Flush(); err != nil {
+			t.Error("w.Flush():", err)
+		}
+		got := rec.get()
+		if diff := cmp.Diff([]string{want}, got); diff != "" {
+			t.Errorf("batches (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("SpansBatches", func(t *testing.T) {
+		rec := new(batchRecorder)
+		const batchSize = 5
+		w := NewWriter(rec, batchSize, tafb)
+		chunks := [][]byte{[]byte("Hel"), []byte("lo, W"), []byte("orld!\n")}
+		const want = "Hello, World!\n"
+		if n, err := w.WriteVec(chunks); n != len(want) || err != nil {
+			t.Errorf("w.WriteVec(...) = %d, %v; want %d, <nil>", n, err, len(want))
+		}
+		if err := w.Flush(); err != nil {
+			t.Error("w.Flush():", err)
+		}
+		// We can't guarantee the exact batching because it's dependent on timing.
+		if got := rec.get(); !isBatchingValid(got, want, batchSize) {
+			t.Errorf("bad batching for %q, batch size = %d: %q", want, batchSize, got)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		wantErr := errors.New("bork")
+		w := NewWriter(errorWriter{wantErr}, 5, tafb)
+		chunks := [][]byte{[]byte("Hello"), []byte(", World!\n")}
+		n, err := w.WriteVec(chunks)
+		if n > 0 || !errors.Is(err, wantErr) {
+			t.Errorf("w.WriteVec(...) = %d, %v; want 0, %v", n, err, wantErr)
+		}
+		if err := w.Flush(); !errors.Is(err, wantErr) {
+			t.Errorf("w.Flush() = %v; want %v", err, wantErr)
+		}
+	})
+}
+
+func BenchmarkWriter(b *testing.B) {
+	const (
+		chunkSize  = 16
+		chunkCount = 64
+		batchSize  = 4096
+	)
+	chunks := make([][]byte, chunkCount)
+	for i := range chunks {
+		chunks[i] = make([]byte, chunkSize)
+	}
+
+	b.Run("Write", func(b *testing.B) {
+		b.SetBytes(chunkSize * chunkCount)
+		w := NewWriter(ioutil.Discard, batchSize, time.Hour)
+		for i := 0; i < b.N; i++ {
+			for _, c := range chunks {
+				if _, err := w.Write(c); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		if err := w.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	})
+
+	b.Run("WriteVec", func(b *testing.B) {
+		b.SetBytes(chunkSize * chunkCount)
+		w := NewWriter(ioutil.Discard, batchSize, time.Hour)
+		for i := 0; i < b.N; i++ {
+			if _, err := w.WriteVec(chunks); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	})
 }
 
 func writeStrings(t *testing.T, w io.Writer, s ...string) {
@@ -365,6 +1196,15 @@ func isBatchingValid(batches []string, want string, batchSize int) bool {
 	return strings.Join(batches, "") == want
 }
 
+// errorWriter is an io.Writer that always returns the same error.
+type errorWriter struct {
+	err error
+}
+
+func (ew errorWriter) Write(p []byte) (int, error) {
+	return 0, ew.err
+}
+
 // batchRecorder is an io.Writer that saves the individual Write calls it receives.
 // It is safe to use concurrently.
 type batchRecorder struct {
@@ -0,0 +1,94 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package batchiotest provides utilities for testing code that uses package
+// batchio.
+package batchiotest
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrClosed is returned by ScriptedReader.Read after Close has been called.
+var ErrClosed = errors.New("batchiotest: read from closed reader")
+
+// A Step is a single action in a ScriptedReader's script. When a Read reaches
+// a step, it first waits for Wait to be closed (if Wait is not nil), then
+// returns bytes from Data. If Data is longer than the buffer passed to Read,
+// the remaining bytes are returned by subsequent reads. Once all of Data has
+// been returned, Err is returned alongside the last bytes and the reader moves
+// on to the next step. A step with no Data and no Err only waits; the same Read
+// then continues with the next step.
+type Step struct {
+	Wait <-chan struct{}
+	Data string
+	Err  error
+}
+
+// ScriptedReader is an io.ReadCloser that performs a sequence of steps. After
+// the last step, Read returns io.EOF. It is safe to call Close concurrently
+// with Read, but Read must not be called concurrently with itself.
+type ScriptedReader struct {
+	mu        sync.Mutex
+	steps     []Step
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewScriptedReader returns a new reader that performs the given steps.
+func NewScriptedReader(steps ...Step) *ScriptedReader {
+	return &ScriptedReader{
+		steps:  append([]Step(nil), steps...),
+		closed: make(chan struct{}),
+	}
+}
+
+// Read performs the next step in the script. If the reader is closed before or
+// while Read is waiting, Read returns ErrClosed.
+func (r *ScriptedReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		select {
+		case <-r.closed:
+			return 0, ErrClosed
+		default:
+		}
+		if len(r.steps) == 0 {
+			return 0, io.EOF
+		}
+		curr := &r.steps[0]
+		if curr.Wait != nil {
+			wait := curr.Wait
+			r.mu.Unlock()
+			select {
+			case <-wait:
+			case <-r.closed:
+			}
+			r.mu.Lock()
+			curr.Wait = nil
+			continue
+		}
+		if curr.Data == "" && curr.Err == nil {
+			r.steps = r.steps[1:]
+			continue
+		}
+		n := copy(p, curr.Data)
+		if n < len(curr.Data) {
+			curr.Data = curr.Data[n:]
+			return n, nil
+		}
+		err := curr.Err
+		r.steps = r.steps[1:]
+		return n, err
+	}
+}
+
+// Close causes any blocked or future calls to Read to return ErrClosed.
+// It always returns nil.
+func (r *ScriptedReader) Close() error {
+	r.closeOnce.Do(func() { close(r.closed) })
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package batchiotest
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestScriptedReader(t *testing.T) {
+	t.Run("Data", func(t *testing.T) {
+		r := NewScriptedReader(Step{Data: "Hello, "}, Step{Data: "World!"})
+		wantReads(t, r, 64, []read{
+			{data: "Hello, "},
+			{data: "World!"},
+			{err: io.EOF},
+			{err: io.EOF},
+		})
+	})
+
+	t.Run("PartialReads", func(t *testing.T) {
+		r := NewScriptedReader(Step{Data: "Hello, World!", Err: io.EOF})
+		wantReads(t, r, 5, []read{
+			{data: "Hello"},
+			{data: ", Wor"},
+			{data: "ld!", err: io.EOF},
+			{err: io.EOF},
+		})
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		bork := errors.New("bork")
+		r := NewScriptedReader(Step{Data: "abc", Err: bork}, Step{Err: bork}, Step{Data: "def"})
+		wantReads(t, r, 64, []read{
+			{data: "abc", err: bork},
+			{err: bork},
+			{data: "def"},
+			{err: io.EOF},
+		})
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		signal := make(chan struct{})
+		r := NewScriptedReader(Step{Data: "abc"}, Step{Wait: signal}, Step{Data: "def"})
+		wantReads(t, r, 64, []read{{data: "abc"}})
+		done := make(chan read)
+		go func() {
+			buf := make([]byte, 64)
+			n, err := r.Read(buf)
+			done <- read{data: string(buf[:n]), err: err}
+		}()
+		select {
+		case got := <-done:
+			t.Fatalf("Read returned %q, %v before signal", got.data, got.err)
+		case <-time.After(10 * time.Millisecond):
+		}
+		close(signal)
+		if got := <-done; got.data != "def" || got.err != nil {
+			t.Errorf("Read = %q, %v; want \"def\", <nil>", got.data, got.err)
+		}
+	})
+
+	t.Run("WaitWithData", func(t *testing.T) {
+		signal := make(chan struct{})
+		close(signal)
+		r := NewScriptedReader(Step{Wait: signal, Data: "abc", Err: io.EOF})
+		wantReads(t, r, 64, []read{{data: "abc", err: io.EOF}})
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		r := NewScriptedReader()
+		wantReads(t, r, 64, []read{{err: io.EOF}})
+	})
+
+	t.Run("CloseWhileBlocked", func(t *testing.T) {
+		r := NewScriptedReader(Step{Wait: make(chan struct{}), Data: "never"})
+		done := make(chan error)
+		go func() {
+			_, err := r.Read(make([]byte, 64))
+			done <- err
+		}()
+		if err := r.Close(); err != nil {
+			t.Error("Close:", err)
+		}
+		if err := <-done; err != ErrClosed {
+			t.Errorf("Read after Close = _, %v; want _, %v", err, ErrClosed)
+		}
+		if err := r.Close(); err != nil {
+			t.Error("second Close:", err)
+		}
+		if _, err := r.Read(make([]byte, 64)); err != ErrClosed {
+			t.Errorf("Read after Close = _, %v; want _, %v", err, ErrClosed)
+		}
+	})
+}
+
+type read struct {
+	data string
+	err  error
+}
+
+func wantReads(t *testing.T, r io.Reader, bufSize int, want []read) {
+	t.Helper()
+	buf := make([]byte, bufSize)
+	for i, w := range want {
+		n, err := r.Read(buf)
+		if got := string(buf[:n]); got != w.data || err != w.err {
+			t.Errorf("Read #%d = %q, %v; want %q, %v", i+1, got, err, w.data, w.err)
+		}
+	}
+}
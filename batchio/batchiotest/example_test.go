@@ -0,0 +1,38 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package batchiotest_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/yourbase/commons/batchio"
+	"github.com/yourbase/commons/batchio/batchiotest"
+)
+
+func ExampleScriptedReader() {
+	ctx := context.Background()
+	stream := batchiotest.NewScriptedReader(
+		batchiotest.Step{Data: "Hello, "},
+		batchiotest.Step{Data: "World!", Err: io.EOF},
+	)
+	reader := batchio.NewReader(stream, 5, 10*time.Second)
+	defer reader.Finish()
+	for {
+		batch, err := reader.Next(ctx)
+		if err != nil {
+			fmt.Println("Error:", err)
+			break
+		}
+		fmt.Printf("%s\n", batch)
+	}
+
+	// Output:
+	// Hello
+	// , Wor
+	// ld!
+	// Error: EOF
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package batchio
+
+import (
+	"io"
+	"sync"
+)
+
+// A FrameWriter is an io.Writer that forwards each Write to an underlying
+// io.Writer as exactly one write, without batching. This preserves message
+// boundaries for message-oriented sinks while having the same error and Flush
+// semantics as Writer: if an error occurs writing to a FrameWriter, no more
+// data will be accepted and all subsequent writes, and Flush, will return the
+// error. There is no size or time after first byte, since writes are never
+// combined or split. It is safe to call methods on a FrameWriter from
+// multiple goroutines.
+type FrameWriter struct {
+	w io.Writer
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewFrameWriter returns a new FrameWriter that writes to w.
+func NewFrameWriter(w io.Writer) *FrameWriter {
+	if w == nil {
+		panic("batchio.NewFrameWriter(nil)")
+	}
+	return &FrameWriter{w: w}
+}
+
+// Write writes p to the underlying io.Writer in a single call. Empty writes are
+// not forwarded. If the underlying io.Writer accepts fewer than len(p) bytes
+// without an error, Write returns io.ErrShortWrite.
+func (fw *FrameWriter) Write(p []byte) (n int, err error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.err != nil {
+		return 0, fw.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n, fw.err = fw.w.Write(p)
+	if fw.err == nil && n < len(p) {
+		fw.err = io.ErrShortWrite
+	}
+	return n, fw.err
+}
+
+// Flush returns the error from any previous write. Since a FrameWriter does not
+// buffer, there is never any data to write.
+func (fw *FrameWriter) Flush() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return fw.err
+}
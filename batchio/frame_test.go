@@ -0,0 +1,86 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package batchio
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFrameWriter(t *testing.T) {
+	t.Run("OneToOne", func(t *testing.T) {
+		rec := new(batchRecorder)
+		fw := NewFrameWriter(rec)
+		frames := []string{"a", "", "bc", "def", "a much longer message"}
+		for _, frame := range frames {
+			n, err := fw.Write([]byte(frame))
+			if n != len(frame) || err != nil {
+				t.Errorf("Write(%q) = %d, %v; want %d, <nil>", frame, n, err, len(frame))
+			}
+		}
+		if err := fw.Flush(); err != nil {
+			t.Error("Flush:", err)
+		}
+		want := []string{"a", "bc", "def", "a much longer message"}
+		if diff := cmp.Diff(want, rec.get()); diff != "" {
+			t.Errorf("writes (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("ErrorSticky", func(t *testing.T) {
+		bork := errors.New("bork")
+		ew := &failAfterWriter{n: 1, err: bork}
+		fw := NewFrameWriter(ew)
+		if _, err := fw.Write([]byte("first")); err != nil {
+			t.Fatal("first Write:", err)
+		}
+		if _, err := fw.Write([]byte("second")); err != bork {
+			t.Errorf("second Write error = %v; want %v", err, bork)
+		}
+		if n, err := fw.Write([]byte("third")); n != 0 || err != bork {
+			t.Errorf("third Write = %d, %v; want 0, %v", n, err, bork)
+		}
+		if err := fw.Flush(); err != bork {
+			t.Errorf("Flush() = %v; want %v", err, bork)
+		}
+		if ew.calls != 2 {
+			t.Errorf("underlying writer called %d times; want 2", ew.calls)
+		}
+	})
+
+	t.Run("ShortWrite", func(t *testing.T) {
+		fw := NewFrameWriter(shortWriter{})
+		if _, err := fw.Write([]byte("abc")); err != io.ErrShortWrite {
+			t.Errorf("Write error = %v; want %v", err, io.ErrShortWrite)
+		}
+		if err := fw.Flush(); err != io.ErrShortWrite {
+			t.Errorf("Flush() = %v; want %v", err, io.ErrShortWrite)
+		}
+	})
+}
+
+// failAfterWriter succeeds for the first n writes and then returns err.
+type failAfterWriter struct {
+	n     int
+	err   error
+	calls int
+}
+
+func (w *failAfterWriter) Write(p []byte) (int, error) {
+	w.calls++
+	if w.calls > w.n {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+// shortWriter accepts one byte less than it is given.
+type shortWriter struct{}
+
+func (shortWriter) Write(p []byte) (int, error) {
+	return len(p) - 1, nil
+}
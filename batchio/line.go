@@ -0,0 +1,113 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package batchio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrLineTooLong is returned by LineReader.Next when a line is longer than
+// the reader's maximum line size.
+var ErrLineTooLong = errors.New("batchio: line too long")
+
+// A LineReader reads an io.Reader one line at a time. Unlike bufio.Scanner,
+// a call to Next can be interrupted by canceling its Context.
+type LineReader struct {
+	r       *Reader
+	maxSize int
+
+	buf   []byte // buf[start:] holds bytes that have not been returned
+	start int
+	err   error
+}
+
+// NewLineReader returns a new LineReader that reads lines from r. Lines
+// (including their terminator) may be at most maxLineSize bytes long.
+// timeAfterFirstByte limits how long a single read from r will wait for more
+// bytes, as in NewReader. It does not cause Next to return partial lines.
+//
+// It must be safe to call r.Close concurrently with r.Read.
+func NewLineReader(r io.ReadCloser, maxLineSize int, timeAfterFirstByte time.Duration) *LineReader {
+	if r == nil {
+		panic("batchio.NewLineReader(nil, ...)")
+	}
+	if maxLineSize <= 0 {
+		panic("batchio.NewLineReader(..., <non-positive size>, ...)")
+	}
+	if timeAfterFirstByte < 0 {
+		panic("batchio.NewLineReader(..., <negative time-after-first-byte>)")
+	}
+	return &LineReader{
+		r:       NewReader(r, maxLineSize, timeAfterFirstByte),
+		maxSize: maxLineSize,
+		buf:     make([]byte, 0, 2*maxLineSize),
+	}
+}
+
+// Next reads the next line, including its terminating newline ('\n'). The last
+// line of the stream is returned without a terminator if the stream does not
+// end in a newline. The returned line is valid until the next call to Next.
+//
+// If a line is longer than the maximum line size, Next returns ErrLineTooLong.
+// Once the underlying reader has returned an error or a line is too long, Next
+// will return the same error on subsequent calls. If the Context is Done
+// before a full line is read, Next returns the Context's error and the partial
+// line will be returned by a later call to Next.
+func (lr *LineReader) Next(ctx context.Context) ([]byte, error) {
+	for {
+		pending := lr.buf[lr.start:]
+		if i := bytes.IndexByte(pending, '\n'); i != -1 {
+			if i+1 > lr.maxSize {
+				lr.fail(ErrLineTooLong)
+				return nil, lr.err
+			}
+			lr.start += i + 1
+			return pending[: i+1 : i+1], nil
+		}
+		if len(pending) > lr.maxSize {
+			lr.fail(ErrLineTooLong)
+			return nil, lr.err
+		}
+		if lr.err != nil {
+			if len(pending) == 0 {
+				return nil, lr.err
+			}
+			lr.start = len(lr.buf)
+			return pending[:len(pending):len(pending)], nil
+		}
+
+		batch, err := lr.r.Next(ctx)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil && err == ctxErr {
+				return nil, err
+			}
+			lr.err = err
+			continue
+		}
+		lr.buf = append(lr.buf[:copy(lr.buf, pending)], batch...)
+		lr.start = 0
+	}
+}
+
+// fail stores a permanent error and discards any unreturned bytes.
+func (lr *LineReader) fail(err error) {
+	lr.err = err
+	lr.buf = lr.buf[:0]
+	lr.start = 0
+}
+
+// Finish closes the underlying reader and returns any bytes that were read
+// but not returned by Next, such as a partial line. After the first call to
+// Finish, it returns an error.
+func (lr *LineReader) Finish() ([]byte, error) {
+	last, err := lr.r.Finish()
+	rest := append(lr.buf[lr.start:], last...)
+	lr.buf = nil
+	lr.start = 0
+	return rest, err
+}
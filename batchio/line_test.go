@@ -0,0 +1,184 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package batchio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestLineReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxSize int
+		steps   []recoverableStep
+		want    []string
+		wantErr error
+	}{
+		{
+			name:    "Empty",
+			maxSize: 64,
+			wantErr: io.EOF,
+		},
+		{
+			name:    "SingleRead",
+			maxSize: 64,
+			steps: []recoverableStep{
+				{data: "foo\nbar\n"},
+			},
+			want:    []string{"foo\n", "bar\n"},
+			wantErr: io.EOF,
+		},
+		{
+			name:    "SplitAcrossReads",
+			maxSize: 64,
+			steps: []recoverableStep{
+				{data: "hel"},
+				{data: "lo\nwor"},
+				{data: "ld\n"},
+			},
+			want:    []string{"hello\n", "world\n"},
+			wantErr: io.EOF,
+		},
+		{
+			name:    "SplitAcrossBuffer",
+			maxSize: 4,
+			steps: []recoverableStep{
+				{data: "ab\ncd\nef\n"},
+			},
+			want:    []string{"ab\n", "cd\n", "ef\n"},
+			wantErr: io.EOF,
+		},
+		{
+			name:    "ExactlyMaxSize",
+			maxSize: 4,
+			steps: []recoverableStep{
+				{data: "abc\nxyz\n"},
+			},
+			want:    []string{"abc\n", "xyz\n"},
+			wantErr: io.EOF,
+		},
+		{
+			name:    "NoTrailingNewline",
+			maxSize: 64,
+			steps: []recoverableStep{
+				{data: "foo\nbar"},
+			},
+			want:    []string{"foo\n", "bar"},
+			wantErr: io.EOF,
+		},
+		{
+			name:    "CRLF",
+			maxSize: 64,
+			steps: []recoverableStep{
+				{data: "foo\r\nbar\r\n"},
+			},
+			want:    []string{"foo\r\n", "bar\r\n"},
+			wantErr: io.EOF,
+		},
+		{
+			name:    "TooLong",
+			maxSize: 4,
+			steps: []recoverableStep{
+				{data: "ab\n"},
+				{data: "abcdefgh\n"},
+				{data: "ab\n"},
+			},
+			want:    []string{"ab\n"},
+			wantErr: ErrLineTooLong,
+		},
+		{
+			name:    "TooLongWithTerminator",
+			maxSize: 4,
+			steps: []recoverableStep{
+				{data: "abcd\n"},
+			},
+			wantErr: ErrLineTooLong,
+		},
+		{
+			name:    "ReaderError",
+			maxSize: 64,
+			steps: []recoverableStep{
+				{data: "foo\nba", err: errors.New("bork")},
+			},
+			want:    []string{"foo\n", "ba"},
+			wantErr: errors.New("bork"),
+		},
+	}
+
+	ctx := context.Background()
+	if d, ok := t.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, d)
+		t.Cleanup(cancel)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lr := NewLineReader(&recoverableReader{steps: test.steps}, test.maxSize, 30*time.Second)
+			var got []string
+			var err error
+			for {
+				var line []byte
+				line, err = lr.Next(ctx)
+				if err != nil {
+					break
+				}
+				got = append(got, string(line))
+			}
+			if diff := cmp.Diff(test.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("lines (-want +got):\n%s", diff)
+			}
+			if err == nil || test.wantErr == nil || err.Error() != test.wantErr.Error() {
+				t.Errorf("final error = %v; want %v", err, test.wantErr)
+			}
+			if _, err2 := lr.Next(ctx); err2 != err {
+				t.Errorf("Next after error = _, %v; want _, %v", err2, err)
+			}
+			if _, err := lr.Finish(); err != nil {
+				t.Error("Finish:", err)
+			}
+		})
+	}
+}
+
+func TestLineReaderCancel(t *testing.T) {
+	ctx := context.Background()
+	if d, ok := t.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, d)
+		t.Cleanup(cancel)
+	}
+	release := make(chan struct{})
+	r := &recoverableReader{
+		steps: []recoverableStep{
+			{data: "hel"},
+			{data: "lo\n"},
+		},
+		wait: map[int]<-chan struct{}{1: release},
+	}
+	lr := NewLineReader(r, 64, 10*time.Millisecond)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if line, err := lr.Next(cancelCtx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Next(canceled) = %q, %v; want _, %v", line, err, context.Canceled)
+	}
+	close(release)
+	line, err := lr.Next(ctx)
+	if string(line) != "hello\n" || err != nil {
+		t.Errorf("Next(ctx) = %q, %v; want \"hello\\n\", <nil>", line, err)
+	}
+	if _, err := lr.Next(ctx); err != io.EOF {
+		t.Errorf("Next(ctx) at end = _, %v; want _, %v", err, io.EOF)
+	}
+	if _, err := lr.Finish(); err != nil {
+		t.Error("Finish:", err)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package awsprofile reads named profiles from the AWS shared config
+// (~/.aws/config) and credentials (~/.aws/credentials) files.
+//
+// The two files name their sections differently: the config file uses
+// "[profile NAME]" for every profile except "[default]", while the
+// credentials file uses "[NAME]". This package hides the difference.
+package awsprofile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yourbase/commons/ini"
+)
+
+// ErrNotFound is returned when a profile is not present in either file.
+var ErrNotFound = errors.New("awsprofile: profile not found")
+
+// DefaultName is the name of the profile used when none is specified.
+const DefaultName = "default"
+
+// A Profile is the merged settings for a named profile.
+type Profile struct {
+	Name string
+
+	Region          string
+	Output          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// RoleARN, SourceProfile, CredentialSource, ExternalID, MFASerial, and
+	// RoleSessionName configure assuming an IAM role. Use Config.Chain to
+	// follow SourceProfile.
+	RoleARN          string
+	SourceProfile    string
+	CredentialSource string
+	ExternalID       string
+	MFASerial        string
+	RoleSessionName  string
+
+	// Settings holds every property of the profile, including ones without a
+	// dedicated field. Values from the credentials file come after values
+	// from the config file, so Settings.Get prefers the credentials file.
+	Settings ini.Section
+}
+
+// Config is a pair of parsed shared config and credentials files.
+type Config struct {
+	config      *ini.File
+	credentials *ini.File
+}
+
+// New returns a Config for the given parsed files. Either file may be nil.
+func New(config, credentials *ini.File) *Config {
+	return &Config{config: config, credentials: credentials}
+}
+
+// Load parses the files at the given paths. Missing files are treated as
+// empty.
+func Load(configPath, credentialsPath string) (*Config, error) {
+	fset, err := ini.ParseFiles(nil, configPath, credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("load aws profiles: %w", err)
+	}
+	return New(fset[0], fset[1]), nil
+}
+
+// LoadDefault parses the files at the paths returned by DefaultPaths.
+func LoadDefault() (*Config, error) {
+	configPath, credentialsPath, err := DefaultPaths()
+	if err != nil {
+		return nil, fmt.Errorf("load aws profiles: %w", err)
+	}
+	return Load(configPath, credentialsPath)
+}
+
+// DefaultPaths returns the paths of the shared config and credentials files
+// used by the AWS CLI. The AWS_CONFIG_FILE and AWS_SHARED_CREDENTIALS_FILE
+// environment variables override the default locations in ~/.aws.
+func DefaultPaths() (config, credentials string, err error) {
+	config = os.Getenv("AWS_CONFIG_FILE")
+	credentials = os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if config != "" && credentials != "" {
+		return config, credentials, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", err
+	}
+	if config == "" {
+		config = filepath.Join(home, ".aws", "config")
+	}
+	if credentials == "" {
+		credentials = filepath.Join(home, ".aws", "credentials")
+	}
+	return config, credentials, nil
+}
+
+// configSections returns the config file section names for a profile in
+// increasing order of precedence.
+func configSections(name string) []string {
+	if name == DefaultName {
+		return []string{DefaultName, "profile " + DefaultName}
+	}
+	return []string{"profile " + name}
+}
+
+// Names returns the sorted names of the profiles defined in either file.
+// Config file sections that are not profiles, such as "[sso-session NAME]",
+// are ignored.
+func (c *Config) Names() []string {
+	seen := make(map[string]bool)
+	for section := range c.config.Sections() {
+		switch {
+		case section == DefaultName:
+			seen[DefaultName] = true
+		case strings.HasPrefix(section, "profile "):
+			seen[strings.TrimSpace(strings.TrimPrefix(section, "profile "))] = true
+		}
+	}
+	for section := range c.credentials.Sections() {
+		if section != "" {
+			seen[section] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the merged settings for the named profile. It returns an
+// error wrapping ErrNotFound if neither file has properties for the profile.
+func (c *Config) Profile(name string) (*Profile, error) {
+	settings := make(ini.Section)
+	for _, section := range configSections(name) {
+		for key, values := range c.config.Section(section) {
+			settings[key] = append(settings[key], values...)
+		}
+	}
+	for key, values := range c.credentials.Section(name) {
+		settings[key] = append(settings[key], values...)
+	}
+	if len(settings) == 0 {
+		return nil, fmt.Errorf("aws profile %q: %w", name, ErrNotFound)
+	}
+	return &Profile{
+		Name:             name,
+		Region:           settings.Get("region"),
+		Output:           settings.Get("output"),
+		AccessKeyID:      settings.Get("aws_access_key_id"),
+		SecretAccessKey:  settings.Get("aws_secret_access_key"),
+		SessionToken:     settings.Get("aws_session_token"),
+		RoleARN:          settings.Get("role_arn"),
+		SourceProfile:    settings.Get("source_profile"),
+		CredentialSource: settings.Get("credential_source"),
+		ExternalID:       settings.Get("external_id"),
+		MFASerial:        settings.Get("mfa_serial"),
+		RoleSessionName:  settings.Get("role_session_name"),
+		Settings:         settings,
+	}, nil
+}
+
+// Chain returns the named profile followed by the profiles reached by
+// following SourceProfile. The last profile in the chain is the one whose
+// credentials are used to assume the first role. A profile whose
+// SourceProfile names itself ends the chain, as the AWS CLI uses that
+// profile's own credentials. Chain returns an error if a profile is missing
+// or the chain has a cycle.
+func (c *Config) Chain(name string) ([]*Profile, error) {
+	var chain []*Profile
+	visited := make(map[string]bool)
+	for {
+		if visited[name] {
+			return nil, fmt.Errorf("aws profile %q: source_profile cycle", chain[0].Name)
+		}
+		visited[name] = true
+		p, err := c.Profile(name)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, p)
+		if p.SourceProfile == "" || p.SourceProfile == p.Name {
+			return chain, nil
+		}
+		name = p.SourceProfile
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package awsprofile
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testConfig = `[default]
+region = us-east-1
+
+[profile dev]
+region = us-west-2
+output = json
+
+[profile admin]
+role_arn = arn:aws:iam::123456789012:role/admin
+source_profile = dev
+
+[profile loop1]
+source_profile = loop2
+
+[profile loop2]
+source_profile = loop1
+
+[sso-session corp]
+sso_region = us-east-1
+`
+
+const testCredentials = `[default]
+aws_access_key_id = AKIADEFAULT
+aws_secret_access_key = secret-default
+
+[dev]
+aws_access_key_id = AKIADEV
+aws_secret_access_key = secret-dev
+region = eu-west-1
+
+[ci]
+aws_access_key_id = AKIACI
+`
+
+func loadTestConfig(t *testing.T) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config")
+	credentialsPath := filepath.Join(dir, "credentials")
+	if err := ioutil.WriteFile(configPath, []byte(testConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(credentialsPath, []byte(testCredentials), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(configPath, credentialsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestNames(t *testing.T) {
+	c := loadTestConfig(t)
+	want := []string{"admin", "ci", "default", "dev", "loop1", "loop2"}
+	if diff := cmp.Diff(want, c.Names()); diff != "" {
+		t.Errorf("Names() (-want +got):\n%s", diff)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	c := loadTestConfig(t)
+	got, err := c.Profile("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Profile{
+		Name:            "dev",
+		Region:          "eu-west-1",
+		Output:          "json",
+		AccessKeyID:     "AKIADEV",
+		SecretAccessKey: "secret-dev",
+	}
+	if diff := cmp.Diff([]string{"us-west-2", "eu-west-1"}, got.Settings["region"]); diff != "" {
+		t.Errorf("Profile(\"dev\").Settings[\"region\"] (-want +got):\n%s", diff)
+	}
+	got.Settings = nil
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Profile(\"dev\") (-want +got):\n%s", diff)
+	}
+
+	def, err := c.Profile("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Region != "us-east-1" || def.AccessKeyID != "AKIADEFAULT" {
+		t.Errorf("Profile(\"default\") = %+v; want region us-east-1 and key AKIADEFAULT", def)
+	}
+
+	if _, err := c.Profile("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Profile(\"missing\") error = %v; want %v", err, ErrNotFound)
+	}
+}
+
+func TestChain(t *testing.T) {
+	c := loadTestConfig(t)
+	chain, err := c.Chain("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range chain {
+		names = append(names, p.Name)
+	}
+	if diff := cmp.Diff([]string{"admin", "dev"}, names); diff != "" {
+		t.Errorf("Chain(\"admin\") names (-want +got):\n%s", diff)
+	}
+
+	if _, err := c.Chain("loop1"); err == nil {
+		t.Error("Chain(\"loop1\") did not return an error")
+	}
+
+	if _, err := New(nil, nil).Chain("dev"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("New(nil, nil).Chain(\"dev\") error = %v; want %v", err, ErrNotFound)
+	}
+}
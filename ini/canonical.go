@@ -0,0 +1,77 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"bytes"
+	"strings"
+)
+
+// CheckCanonical reports which lines of an INI file would change if the file
+// were parsed with the given options and serialized with MarshalText. It
+// returns the 1-based line numbers that differ, in ascending order, or an
+// empty slice if data is already in canonical form. Differences include
+// extra whitespace, non-canonical quoting, and missing or redundant blank
+// lines. A missing blank line is reported on the line that it should precede.
+// A missing newline at the end of the file is reported on the last line.
+func CheckCanonical(data []byte, opts *ParseOptions) ([]int, error) {
+	f, err := Parse(bytes.NewReader(data), opts)
+	if err != nil {
+		return nil, err
+	}
+	out, err := f.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	src := splitLines(string(data))
+	want := splitLines(string(out))
+
+	var lines []int
+	report := func(i int) {
+		if n := i + 1; len(lines) == 0 || lines[len(lines)-1] != n {
+			lines = append(lines, n)
+		}
+	}
+	i, j := 0, 0
+	for i < len(src) && j < len(want) {
+		switch {
+		case src[i] == want[j]:
+			i++
+			j++
+		case strings.TrimSpace(src[i]) == "":
+			// Redundant blank line.
+			report(i)
+			i++
+		case want[j] == "":
+			// Missing blank line.
+			report(i)
+			j++
+		default:
+			report(i)
+			i++
+			j++
+		}
+	}
+	for ; i < len(src); i++ {
+		report(i)
+	}
+	if j < len(want) && len(src) > 0 {
+		report(len(src) - 1)
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		report(len(src) - 1)
+	}
+	if lines == nil {
+		lines = []int{}
+	}
+	return lines, nil
+}
+
+// splitLines splits s into lines without their terminating newlines.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestCheckCanonical(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		opts   *ParseOptions
+		want   []int
+	}{
+		{
+			name:   "Empty",
+			source: "",
+			want:   []int{},
+		},
+		{
+			name: "Canonical",
+			source: "# Comment\n" +
+				"global=1\n" +
+				"\n" +
+				"[build]\n" +
+				"cmd=\" make \"\n",
+			want: []int{},
+		},
+		{
+			name: "Whitespace",
+			source: "a = 1\n" +
+				"b=2\n" +
+				"\n" +
+				"[ build ]\n" +
+				"cmd=make   \n",
+			want: []int{1, 4, 5},
+		},
+		{
+			name: "Quoting",
+			source: "a=\"hello\"\n" +
+				"b=\" padded \"\n" +
+				"c=plain\n",
+			want: []int{1},
+		},
+		{
+			name:   "Comment",
+			source: "#comment\nkey=value\n",
+			want:   []int{1},
+		},
+		{
+			name: "RedundantBlankLines",
+			source: "\n" +
+				"a=1\n" +
+				"\n" +
+				"\n" +
+				"[build]\n" +
+				"cmd=make\n" +
+				"\n",
+			want: []int{1, 4, 7},
+		},
+		{
+			name: "MissingBlankLine",
+			source: "a=1\n" +
+				"[build]\n" +
+				"cmd=make\n",
+			want: []int{2},
+		},
+		{
+			name:   "MissingFinalNewline",
+			source: "a=1\nb=2",
+			want:   []int{2},
+		},
+		{
+			name:   "CRLF",
+			source: "a=1\r\nb=2\r\n",
+			want:   []int{1, 2},
+		},
+		{
+			name:   "Options",
+			source: "[Build]\nCMD=make\n",
+			opts: &ParseOptions{
+				NormalizeSection: func(name string) string { return "build" },
+			},
+			want: []int{1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := CheckCanonical([]byte(test.source), test.opts)
+			if err != nil {
+				t.Fatal("CheckCanonical:", err)
+			}
+			if diff := cmp.Diff(test.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("CheckCanonical(%q, ...) (-want +got):\n%s", test.source, diff)
+			}
+		})
+	}
+
+	t.Run("ParseError", func(t *testing.T) {
+		if got, err := CheckCanonical([]byte("[build\n"), nil); err == nil {
+			t.Errorf("CheckCanonical(\"[build\\n\", nil) = %v, <nil>; want error", got)
+		}
+	})
+}
@@ -0,0 +1,243 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CommentTags returns the tags found in the comment lines directly above the
+// last property with the given key in the given section. A tag is a comment
+// line of the form:
+//
+//	; @name: value
+//
+// The name is the text between the '@' and the first colon and must not be
+// empty or contain whitespace. The value is the rest of the line with
+// surrounding whitespace removed, and may be empty. Either comment character
+// ('#' or ';') may be used. Comment lines that do not match this form are
+// ignored. If the same name appears multiple times, the last value is used.
+// CommentTags returns nil if the property does not exist or has no tags.
+func (f *File) CommentTags(section, key string) map[string]string {
+	prop := f.last(section, key)
+	if prop == nil {
+		return nil
+	}
+	var tags map[string]string
+	for _, comment := range prop.comments {
+		name, value, ok := parseCommentTag(comment)
+		if !ok {
+			continue
+		}
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		tags[name] = value
+	}
+	return tags
+}
+
+// parseCommentTag parses a comment line of the form "; @name: value".
+func parseCommentTag(comment string) (name, value string, ok bool) {
+	text := strings.TrimSpace(comment[1:])
+	if !strings.HasPrefix(text, "@") {
+		return "", "", false
+	}
+	i := strings.IndexByte(text, ':')
+	if i == -1 {
+		return "", "", false
+	}
+	name = text[1:i]
+	if name == "" || strings.IndexFunc(name, unicode.IsSpace) != -1 {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(text[i+1:]), true
+}
+
+// Documentation returns the text of the file's comments, without comment
+// characters, grouped under the section or property they annotate. This can
+// be used to generate a reference from a well-commented file. The text is
+// formatted as a series of blocks separated by blank lines:
+//
+//	[section]
+//	Comments above the section header.
+//
+//	key: Comments above the property,
+//	  with each subsequent non-blank line indented by two spaces.
+//
+//	Comments at the end of the file.
+//
+// A section header line appears before the first block of each named section
+// that has comments on it or on any of its properties. Properties in the
+// global section are listed before any section header. Documentation returns
+// the empty string if the file has no comments.
+func (f *File) Documentation() string {
+	if f == nil {
+		return ""
+	}
+	var blocks []string
+	for _, s := range f.sections {
+		var sectionBlock []string
+		if s.name != "" {
+			sectionBlock = append(sectionBlock, "["+s.name+"]")
+			for _, comment := range s.comments {
+				sectionBlock = append(sectionBlock, commentText(comment))
+			}
+		}
+		for _, prop := range s.properties {
+			if len(prop.comments) == 0 {
+				continue
+			}
+			if sectionBlock != nil {
+				blocks = append(blocks, strings.Join(sectionBlock, "\n"))
+				sectionBlock = nil
+			}
+			lines := make([]string, 0, len(prop.comments))
+			lines = append(lines, strings.TrimSuffix(prop.key+": "+commentText(prop.comments[0]), " "))
+			for _, comment := range prop.comments[1:] {
+				if text := commentText(comment); text != "" {
+					lines = append(lines, "  "+text)
+				} else {
+					lines = append(lines, "")
+				}
+			}
+			blocks = append(blocks, strings.Join(lines, "\n"))
+		}
+		if len(s.comments) > 0 && sectionBlock != nil {
+			blocks = append(blocks, strings.Join(sectionBlock, "\n"))
+		}
+	}
+	if len(f.trailingComments) > 0 {
+		lines := make([]string, 0, len(f.trailingComments))
+		for _, comment := range f.trailingComments {
+			lines = append(lines, commentText(comment))
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+	if len(blocks) == 0 {
+		return ""
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// commentText returns the text of a comment line without its comment
+// character and the space that follows it.
+func commentText(comment string) string {
+	return strings.TrimPrefix(comment[1:], " ")
+}
+
+// SectionComment returns the text of the comments above the first header for
+// the named section, without comment characters. Lines are separated by
+// newlines. SectionComment returns the empty string if the section has no
+// comments or does not exist. The global section has no header, so
+// SectionComment("") always returns the empty string.
+func (f *File) SectionComment(name string) string {
+	if f == nil || name == "" {
+		return ""
+	}
+	for _, s := range f.sections {
+		if s.name == name {
+			return joinComments(s.comments)
+		}
+	}
+	return ""
+}
+
+// KeyComment returns the text of the comments directly above the last
+// property with the given key in the given section, without comment
+// characters. Lines are separated by newlines. KeyComment returns the empty
+// string if the property has no comments or does not exist.
+func (f *File) KeyComment(section, key string) string {
+	prop := f.last(section, key)
+	if prop == nil {
+		return ""
+	}
+	return joinComments(prop.comments)
+}
+
+// SetSectionComment replaces the comments above the first header for the
+// named section with the given text. Each line of text is written as a
+// separate comment line starting with a semicolon. An empty text removes the
+// comments. If there is no section with the given name, SetSectionComment
+// creates an empty one at the end of the file. SetSectionComment will panic
+// if the name is empty or IsValidSection(name) reports false.
+func (f *File) SetSectionComment(name, text string) {
+	f.beforeChange()
+	if name == "" || !IsValidSection(name) {
+		panic("File.SetSectionComment invalid section: " + name)
+	}
+	for i := range f.sections {
+		if s := &f.sections[i]; s.name == name {
+			s.comments = splitComment(text)
+			return
+		}
+	}
+	f.sections = append(f.sections, section{
+		name:     name,
+		comments: splitComment(text),
+	})
+}
+
+// SetKeyComment replaces the comments directly above the last property with
+// the given key in the given section with the given text. Each line of text is
+// written as a separate comment line starting with a semicolon. An empty text
+// removes the comments. SetKeyComment reports whether the property exists; if
+// it does not, the file is not modified.
+func (f *File) SetKeyComment(section, key, text string) bool {
+	f.beforeChange()
+	prop := f.last(section, key)
+	if prop == nil {
+		return false
+	}
+	prop.comments = splitComment(text)
+	return true
+}
+
+// SetWithComment sets the value of the given key as by Set and replaces the
+// comments directly above the property with the given lines, as by
+// SetKeyComment. Each argument is written as one or more comment lines
+// starting with a semicolon. If no comment lines are given, the property's
+// existing comments are left as-is. SetWithComment will panic if
+// IsValidSection(section) or IsValidKey(key) reports false.
+func (f *File) SetWithComment(section, key, value string, comment ...string) {
+	if !IsValidSection(section) {
+		panic("File.SetWithComment invalid section: " + section)
+	}
+	if !IsValidKey(key) {
+		panic("File.SetWithComment invalid key: " + key)
+	}
+	f.Set(section, key, value)
+	if len(comment) > 0 {
+		f.SetKeyComment(section, key, strings.Join(comment, "\n"))
+	}
+}
+
+// joinComments returns the text of the given comment lines separated by
+// newlines.
+func joinComments(comments []string) string {
+	lines := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		lines = append(lines, commentText(comment))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitComment converts text into comment lines in the form stored by Parse.
+func splitComment(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	comments := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			comments = append(comments, ";")
+		} else {
+			comments = append(comments, "; "+line)
+		}
+	}
+	return comments
+}
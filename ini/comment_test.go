@@ -0,0 +1,266 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCommentTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		section string
+		key     string
+		want    map[string]string
+	}{
+		{
+			name:    "NoProperty",
+			source:  "; @owner: team-a\nfoo=bar\n",
+			section: "",
+			key:     "baz",
+			want:    nil,
+		},
+		{
+			name:    "NoComments",
+			source:  "foo=bar\n",
+			section: "",
+			key:     "foo",
+			want:    nil,
+		},
+		{
+			name:    "Single",
+			source:  "; @owner: team-a\nfoo=bar\n",
+			section: "",
+			key:     "foo",
+			want:    map[string]string{"owner": "team-a"},
+		},
+		{
+			name: "Multiple",
+			source: "[server]\n" +
+				"; The port to listen on.\n" +
+				"; @owner:   team-a  \n" +
+				"# @since: v1.2\n" +
+				"; @deprecated:\n" +
+				"port=8080\n",
+			section: "server",
+			key:     "port",
+			want: map[string]string{
+				"owner":      "team-a",
+				"since":      "v1.2",
+				"deprecated": "",
+			},
+		},
+		{
+			name: "NotTags",
+			source: "; Contact @owner: for details\n" +
+				"; @ owner: team-a\n" +
+				"; @owner team-a\n" +
+				"; @: team-a\n" +
+				"foo=bar\n",
+			section: "",
+			key:     "foo",
+			want:    nil,
+		},
+		{
+			name: "LastPropertyWins",
+			source: "; @owner: team-a\nfoo=bar\n" +
+				"; @owner: team-b\nfoo=baz\n",
+			section: "",
+			key:     "foo",
+			want:    map[string]string{"owner": "team-b"},
+		},
+		{
+			name:    "RepeatedTag",
+			source:  "; @owner: team-a\n; @owner: team-b\nfoo=bar\n",
+			section: "",
+			key:     "foo",
+			want:    map[string]string{"owner": "team-b"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(test.source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := f.CommentTags(test.section, test.key)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("f.CommentTags(%q, %q) (-want +got):\n%s", test.section, test.key, diff)
+			}
+		})
+	}
+}
+
+func TestDocumentation(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "Empty",
+			source: "",
+			want:   "",
+		},
+		{
+			name:   "NoComments",
+			source: "a=1\n[build]\ncmd=make\n",
+			want:   "",
+		},
+		{
+			name: "Full",
+			source: "; Verbosity of output.\n" +
+				"verbose=false\n" +
+				"\n" +
+				"; Build settings.\n" +
+				"; Used by the build command.\n" +
+				"[build]\n" +
+				"# The command to run.\n" +
+				"#\n" +
+				"# Runs in the project root.\n" +
+				"cmd=make\n" +
+				"tags=a\n" +
+				"\n" +
+				"[test]\n" +
+				";\n" +
+				"timeout=5m\n" +
+				"\n" +
+				"; Undocumented section.\n" +
+				"[empty]\n" +
+				"\n" +
+				"; End of file.\n",
+			want: "verbose: Verbosity of output.\n" +
+				"\n" +
+				"[build]\n" +
+				"Build settings.\n" +
+				"Used by the build command.\n" +
+				"\n" +
+				"cmd: The command to run.\n" +
+				"\n" +
+				"  Runs in the project root.\n" +
+				"\n" +
+				"[test]\n" +
+				"\n" +
+				"timeout:\n" +
+				"\n" +
+				"[empty]\n" +
+				"Undocumented section.\n" +
+				"\n" +
+				"End of file.\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(test.source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := f.Documentation()
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Documentation() (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCommentAccessors(t *testing.T) {
+	const source = "; About foo\n" +
+		";\n" +
+		"# More about foo\n" +
+		"foo=1\n" +
+		"\n" +
+		"; The server\n" +
+		"[server]\n" +
+		"host=a\n" +
+		"; Second host\n" +
+		"host=b\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.KeyComment("", "foo"), "About foo\n\nMore about foo"; got != want {
+		t.Errorf("f.KeyComment(\"\", \"foo\") = %q; want %q", got, want)
+	}
+	if got, want := f.KeyComment("server", "host"), "Second host"; got != want {
+		t.Errorf("f.KeyComment(\"server\", \"host\") = %q; want %q", got, want)
+	}
+	if got := f.KeyComment("server", "missing"); got != "" {
+		t.Errorf("f.KeyComment(\"server\", \"missing\") = %q; want \"\"", got)
+	}
+	if got, want := f.SectionComment("server"), "The server"; got != want {
+		t.Errorf("f.SectionComment(\"server\") = %q; want %q", got, want)
+	}
+	if got := f.SectionComment(""); got != "" {
+		t.Errorf("f.SectionComment(\"\") = %q; want \"\"", got)
+	}
+
+	f.SetSectionComment("server", "Servers\n\n  to contact  ")
+	f.SetSectionComment("new", "A new section")
+	if !f.SetKeyComment("server", "host", "") {
+		t.Error("f.SetKeyComment(\"server\", \"host\", \"\") = false; want true")
+	}
+	if !f.SetKeyComment("", "foo", "Just foo") {
+		t.Error("f.SetKeyComment(\"\", \"foo\", ...) = false; want true")
+	}
+	if f.SetKeyComment("server", "missing", "Nope") {
+		t.Error("f.SetKeyComment(\"server\", \"missing\", ...) = true; want false")
+	}
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "; Just foo\n" +
+		"foo=1\n" +
+		"\n" +
+		"; Servers\n" +
+		";\n" +
+		"; to contact\n" +
+		"[server]\n" +
+		"host=a\n" +
+		"host=b\n" +
+		"\n" +
+		"; A new section\n" +
+		"[new]\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalText after setting comments (-want +got):\n%s", diff)
+	}
+	if got, want := f.SectionComment("new"), "A new section"; got != want {
+		t.Errorf("f.SectionComment(\"new\") = %q; want %q", got, want)
+	}
+}
+
+func TestSetWithComment(t *testing.T) {
+	const source = "; Old comment\n" +
+		"foo=1\n" +
+		"; Kept\n" +
+		"bar=2\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SetWithComment("", "foo", "10", "New comment")
+	f.SetWithComment("", "bar", "20")
+	f.SetWithComment("server", "host", "example.com", "Host to contact,", "without a port.\nMust resolve.")
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "; New comment\n" +
+		"foo=10\n" +
+		"; Kept\n" +
+		"bar=20\n" +
+		"\n" +
+		"[server]\n" +
+		"; Host to contact,\n" +
+		"; without a port.\n" +
+		"; Must resolve.\n" +
+		"host=example.com\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decode stores the properties of f in the struct pointed to by v. Only fields
+// with an ini tag are populated. The tag gives the section name and key
+// separated by a comma:
+//
+//	type Config struct {
+//		Name    string        `ini:"user,name"`
+//		Timeout time.Duration `ini:"net,timeout"`
+//		Hosts   []string      `ini:"net,host"`
+//		Debug   bool          `ini:"debug"` // global section
+//	}
+//
+// A tag without a comma names a key in the global section. A tag of "-" is
+// ignored.
+//
+// Fields may be strings, booleans, integers, floating-point numbers,
+// time.Duration, or slices of any of those. A non-slice field is set from the
+// last value for its key, as with Get. A slice field is set to all the values
+// for its key, in the order they appear in the file, as with Find. Fields
+// whose key is absent from f are left unchanged. Booleans are parsed with
+// strconv.ParseBool and durations are parsed with time.ParseDuration.
+//
+// Decode returns an error if v is not a non-nil pointer to a struct, if a
+// tagged field has an unsupported type, or if a value cannot be converted to
+// its field's type. On error, some fields may have been set.
+func Decode(f *File, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode ini: %T is not a non-nil pointer to a struct", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		section, key, ok := parseFieldTag(field)
+		if !ok {
+			continue
+		}
+		if !isSupportedType(field.Type) {
+			return fmt.Errorf("decode ini: %s (field %s): unsupported type %v", propertyName(section, key), field.Name, field.Type)
+		}
+		values := f.Find(section, key)
+		if len(values) == 0 {
+			continue
+		}
+		if err := decodeField(rv.Field(i), values); err != nil {
+			return fmt.Errorf("decode ini: %s (field %s): %w", propertyName(section, key), field.Name, err)
+		}
+	}
+	return nil
+}
+
+// parseFieldTag returns the section and key named by a struct field's ini tag.
+// It returns false if the field has no tag or should be ignored.
+func parseFieldTag(field reflect.StructField) (section, key string, ok bool) {
+	tag, ok := field.Tag.Lookup("ini")
+	if !ok || tag == "-" || field.PkgPath != "" {
+		return "", "", false
+	}
+	if i := strings.IndexByte(tag, ','); i != -1 {
+		return tag[:i], tag[i+1:], true
+	}
+	return "", tag, true
+}
+
+// decodeField sets the field to the given values. values must not be empty.
+func decodeField(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := decodeValue(slice.Index(i), v); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	return decodeValue(field, values[len(values)-1])
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// isSupportedType reports whether Decode and Encode support fields of type t.
+func isSupportedType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func decodeValue(dst reflect.Value, v string) error {
+	if dst.Type() == durationType {
+		d, err := parseDuration(v)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(int64(d))
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(v)
+	case reflect.Bool:
+		b, err := parseBool(v)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(v, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(v, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(v, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(x)
+	default:
+		panic("unreachable")
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecode(t *testing.T) {
+	type config struct {
+		Name     string        `ini:"name"`
+		Debug    bool          `ini:"debug"`
+		Port     int           `ini:"server,port"`
+		Small    uint8         `ini:"server,small"`
+		Ratio    float64       `ini:"server,ratio"`
+		Timeout  time.Duration `ini:"server,timeout"`
+		Hosts    []string      `ini:"server,host"`
+		Weights  []int         `ini:"server,weight"`
+		Missing  string        `ini:"server,missing"`
+		Ignored  string        `ini:"-"`
+		Untagged string
+	}
+	const source = "name=app\n" +
+		"debug=true\n" +
+		"[server]\n" +
+		"port=80\n" +
+		"port=8080\n" +
+		"small=255\n" +
+		"ratio=0.5\n" +
+		"timeout=1m30s\n" +
+		"host=a.example.com\n" +
+		"host=b.example.com\n" +
+		"weight=1\n" +
+		"weight=2\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := config{
+		Missing:  "keep",
+		Ignored:  "keep",
+		Untagged: "keep",
+	}
+	if err := Decode(f, &got); err != nil {
+		t.Fatal("Decode:", err)
+	}
+	want := config{
+		Name:     "app",
+		Debug:    true,
+		Port:     8080,
+		Small:    255,
+		Ratio:    0.5,
+		Timeout:  90 * time.Second,
+		Hosts:    []string{"a.example.com", "b.example.com"},
+		Weights:  []int{1, 2},
+		Missing:  "keep",
+		Ignored:  "keep",
+		Untagged: "keep",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Decode (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	f, err := Parse(strings.NewReader("n=x\nsmall=256\n[s]\nlist=1\nlist=x\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{
+			name: "NotPointer",
+			v:    struct{}{},
+			want: "not a non-nil pointer to a struct",
+		},
+		{
+			name: "NilPointer",
+			v:    (*struct{})(nil),
+			want: "not a non-nil pointer to a struct",
+		},
+		{
+			name: "BadInt",
+			v: &struct {
+				N int `ini:"n"`
+			}{},
+			want: "n (field N)",
+		},
+		{
+			name: "Overflow",
+			v: &struct {
+				Small uint8 `ini:"small"`
+			}{},
+			want: "small (field Small)",
+		},
+		{
+			name: "BadSliceElement",
+			v: &struct {
+				List []int `ini:"s,list"`
+			}{},
+			want: "s.list (field List): [1]",
+		},
+		{
+			name: "UnsupportedType",
+			v: &struct {
+				M map[string]string `ini:"s,absent"`
+			}{},
+			want: "unsupported type",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Decode(f, test.v)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Decode(...) = %v; want error containing %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import "io"
+
+// ParseWithDefaults parses an INI file like Parse and then fills in any
+// missing properties from defaults. For each section and key that has
+// properties in defaults but not in the parsed file, every value of the key
+// in defaults is added to the last section with that name, creating a new
+// section at the end of the file if necessary. Properties present in the
+// parsed file, even with empty values, are left untouched.
+//
+// Filled-in properties behave like any other property for lookups, but
+// MarshalText omits them (and sections that only contain them) so that
+// serializing the file does not clutter it with defaults. Use
+// MarshalOptions.IncludeDefaults to write them. Setting a filled-in property
+// with Set causes it to be written like any other property.
+func ParseWithDefaults(r io.Reader, defaults *File, opts *ParseOptions) (*File, error) {
+	f, err := Parse(r, opts)
+	if err != nil {
+		return f, err
+	}
+	if defaults == nil {
+		return f, nil
+	}
+	type sectionKey struct {
+		section, key string
+	}
+	filled := make(map[sectionKey]bool)
+	for _, s := range defaults.sections {
+		for _, p := range s.properties {
+			k := sectionKey{s.name, p.key}
+			if !filled[k] && f.Has(s.name, p.key) {
+				continue
+			}
+			filled[k] = true
+			f.addDefault(s.name, p.key, p.value)
+		}
+	}
+	return f, nil
+}
+
+// addDefault appends a property filled in by ParseWithDefaults.
+func (f *File) addDefault(sectionName, key, value string) {
+	var addToSection *section
+	for i := len(f.sections) - 1; i >= 0; i-- {
+		if f.sections[i].name == sectionName {
+			addToSection = &f.sections[i]
+			break
+		}
+	}
+	if addToSection == nil {
+		f.sections = append(f.sections, section{
+			name:         sectionName,
+			fromDefaults: true,
+		})
+		addToSection = &f.sections[len(f.sections)-1]
+	}
+	addToSection.properties = append(addToSection.properties, property{
+		key:         key,
+		value:       value,
+		fromDefault: true,
+	})
+}
+
+// onlyDefaults reports whether the section was added by ParseWithDefaults
+// and contains nothing but properties filled in from defaults.
+func (s *section) onlyDefaults() bool {
+	if !s.fromDefaults || len(s.comments) > 0 {
+		return false
+	}
+	for _, p := range s.properties {
+		if !p.fromDefault {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseWithDefaults(t *testing.T) {
+	defaults, err := Parse(strings.NewReader("timeout=30\n"+
+		"verbose=false\n"+
+		"[server]\n"+
+		"host=localhost\n"+
+		"port=80\n"+
+		"port=8080\n"+
+		"[cache]\n"+
+		"size=64\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const source = "verbose=\n" +
+		"\n" +
+		"[server]\n" +
+		"host=example.com\n"
+	f, err := ParseWithDefaults(strings.NewReader(source), defaults, nil)
+	if err != nil {
+		t.Fatal("ParseWithDefaults:", err)
+	}
+
+	tests := []struct {
+		section string
+		key     string
+		want    []string
+	}{
+		{section: "", key: "timeout", want: []string{"30"}},
+		{section: "", key: "verbose", want: []string{""}},
+		{section: "server", key: "host", want: []string{"example.com"}},
+		{section: "server", key: "port", want: []string{"80", "8080"}},
+		{section: "cache", key: "size", want: []string{"64"}},
+	}
+	for _, test := range tests {
+		if diff := cmp.Diff(test.want, f.Find(test.section, test.key)); diff != "" {
+			t.Errorf("f.Find(%q, %q) (-want +got):\n%s", test.section, test.key, diff)
+		}
+	}
+
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal("MarshalText:", err)
+	}
+	if diff := cmp.Diff(source, string(got)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
+
+	got, err = f.MarshalTextWith(&MarshalOptions{IncludeDefaults: true})
+	if err != nil {
+		t.Fatal("MarshalTextWith:", err)
+	}
+	const wantWithDefaults = "verbose=\n" +
+		"timeout=30\n" +
+		"\n" +
+		"[server]\n" +
+		"host=example.com\n" +
+		"port=80\n" +
+		"port=8080\n" +
+		"\n" +
+		"[cache]\n" +
+		"size=64\n"
+	if diff := cmp.Diff(wantWithDefaults, string(got)); diff != "" {
+		t.Errorf("MarshalTextWith(IncludeDefaults) (-want +got):\n%s", diff)
+	}
+
+	t.Run("SetDefault", func(t *testing.T) {
+		f, err := ParseWithDefaults(strings.NewReader(source), defaults, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Set("cache", "size", "128")
+		got, err := f.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := source + "\n[cache]\nsize=128\n"
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("MarshalText after Set (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("NilDefaults", func(t *testing.T) {
+		f, err := ParseWithDefaults(strings.NewReader(source), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Has("", "timeout") {
+			t.Error("f.Has(\"\", \"timeout\") = true; want false")
+		}
+	})
+}
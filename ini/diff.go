@@ -0,0 +1,126 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import "strconv"
+
+// DiffKind is the kind of change described by a PropertyDiff.
+type DiffKind int
+
+// Kinds of changes.
+const (
+	// PropertyAdded indicates a key that is only present in the new file.
+	PropertyAdded DiffKind = 1 + iota
+	// PropertyRemoved indicates a key that is only present in the old file.
+	PropertyRemoved
+	// PropertyChanged indicates a key whose values differ between the files.
+	PropertyChanged
+)
+
+// String returns "added", "removed", or "changed".
+func (k DiffKind) String() string {
+	switch k {
+	case PropertyAdded:
+		return "added"
+	case PropertyRemoved:
+		return "removed"
+	case PropertyChanged:
+		return "changed"
+	default:
+		return "DiffKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// PropertyDiff describes a difference in the values of a key between two
+// files. See File.Diff for details.
+type PropertyDiff struct {
+	Kind    DiffKind
+	Section string
+	Key     string
+
+	// Old is the key's values in the old file, in the order they appear.
+	// It is empty if Kind is PropertyAdded.
+	Old []string
+	// New is the key's values in the new file, in the order they appear.
+	// It is empty if Kind is PropertyRemoved.
+	New []string
+}
+
+// Diff returns the differences between f (the old file) and other (the new
+// file). A key is reported as changed if it has different values or a
+// different number of values in the two files. Comments, formatting, and the
+// placement of properties under repeated section headers are ignored, as
+// with EqualFold. Removed and changed keys are listed first, in the order they
+// first appear in f, followed by added keys, in the order they first appear
+// in other. A nil file is treated as a file with no properties. Diff returns
+// nil if the files have the same properties.
+func (f *File) Diff(other *File) []PropertyDiff {
+	identity := func(s string) string { return s }
+	oldValues := f.foldedValues(identity)
+	newValues := other.foldedValues(identity)
+	var diffs []PropertyDiff
+	for _, k := range f.keyOrder() {
+		old, updated := oldValues[k], newValues[k]
+		switch {
+		case len(updated) == 0:
+			diffs = append(diffs, PropertyDiff{
+				Kind:    PropertyRemoved,
+				Section: k.section,
+				Key:     k.key,
+				Old:     old,
+			})
+		case !equalStrings(old, updated):
+			diffs = append(diffs, PropertyDiff{
+				Kind:    PropertyChanged,
+				Section: k.section,
+				Key:     k.key,
+				Old:     old,
+				New:     updated,
+			})
+		}
+	}
+	for _, k := range other.keyOrder() {
+		if len(oldValues[k]) == 0 {
+			diffs = append(diffs, PropertyDiff{
+				Kind:    PropertyAdded,
+				Section: k.section,
+				Key:     k.key,
+				New:     newValues[k],
+			})
+		}
+	}
+	return diffs
+}
+
+// keyOrder returns each section and key with properties in f, in the order
+// they first appear.
+func (f *File) keyOrder() []foldedKey {
+	if f == nil {
+		return nil
+	}
+	seen := make(map[foldedKey]bool)
+	var keys []foldedKey
+	for _, s := range f.sections {
+		for _, p := range s.properties {
+			k := foldedKey{section: s.name, key: p.key}
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiff(t *testing.T) {
+	const oldSource = "; Comment\n" +
+		"name=app\n" +
+		"removed=1\n" +
+		"[server]\n" +
+		"host=a\n" +
+		"host=b\n" +
+		"port=80\n"
+	const newSource = "name = \"app\"\n" +
+		"[server]\n" +
+		"host=a\n" +
+		"port=8080\n" +
+		"[client]\n" +
+		"retries=3\n" +
+		"[server]\n" +
+		"timeout=30s\n"
+	oldFile, err := Parse(strings.NewReader(oldSource), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newFile, err := Parse(strings.NewReader(newSource), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PropertyDiff{
+		{Kind: PropertyRemoved, Key: "removed", Old: []string{"1"}},
+		{Kind: PropertyChanged, Section: "server", Key: "host", Old: []string{"a", "b"}, New: []string{"a"}},
+		{Kind: PropertyChanged, Section: "server", Key: "port", Old: []string{"80"}, New: []string{"8080"}},
+		{Kind: PropertyAdded, Section: "client", Key: "retries", New: []string{"3"}},
+		{Kind: PropertyAdded, Section: "server", Key: "timeout", New: []string{"30s"}},
+	}
+	if diff := cmp.Diff(want, oldFile.Diff(newFile)); diff != "" {
+		t.Errorf("oldFile.Diff(newFile) (-want +got):\n%s", diff)
+	}
+	if got := oldFile.Diff(oldFile); len(got) != 0 {
+		t.Errorf("oldFile.Diff(oldFile) = %+v; want empty", got)
+	}
+
+	wantNil := []PropertyDiff{
+		{Kind: PropertyAdded, Key: "name", New: []string{"app"}},
+		{Kind: PropertyAdded, Key: "removed", New: []string{"1"}},
+		{Kind: PropertyAdded, Section: "server", Key: "host", New: []string{"a", "b"}},
+		{Kind: PropertyAdded, Section: "server", Key: "port", New: []string{"80"}},
+	}
+	if diff := cmp.Diff(wantNil, (*File)(nil).Diff(oldFile)); diff != "" {
+		t.Errorf("(*File)(nil).Diff(oldFile) (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiffKindString(t *testing.T) {
+	tests := []struct {
+		kind DiffKind
+		want string
+	}{
+		{PropertyAdded, "added"},
+		{PropertyRemoved, "removed"},
+		{PropertyChanged, "changed"},
+		{DiffKind(0), "DiffKind(0)"},
+	}
+	for _, test := range tests {
+		if got := test.kind.String(); got != test.want {
+			t.Errorf("DiffKind(%d).String() = %q; want %q", int(test.kind), got, test.want)
+		}
+	}
+}
@@ -20,17 +20,34 @@ value written on a single line, separated by an equals sign ('='):
 
 	key=value
 
+A property's value may be empty ("key="). Such a property is still defined:
+it is distinct from a key that is not present at all.
+
+If ParseOptions.AllowBareKeys is set, a line containing only a key ("key")
+is a property with an empty value.
+
+If ParseOptions.AllowLineContinuation is set, a property line ending in a
+backslash ('\') continues onto the next line.
+
 Keys are not allowed to contain semicolons (';'), contain equals signs ('='),
 or start with a square bracket ('[' or ']'). Values may be surrounded by double
 quotes ('"') to express values that begin or end with whitespace or to use
 C-style escape sequences. Supported escape sequences:
 
-	\n    U+000A line feed or newline
-	\r    U+000D carriage return
-	\t    U+0009 horizontal tab
-	\\    U+005C backslash
-	\"    U+0022 double quote
-	\xFF  hex escape
+	\n          U+000A line feed or newline
+	\r          U+000D carriage return
+	\t          U+0009 horizontal tab
+	\\          U+005C backslash
+	\"          U+0022 double quote
+	\xFF        hex escape (a single byte)
+	\uFFFF      Unicode code point (4 hex digits)
+	\UFFFFFFFF  Unicode code point (8 hex digits)
+
+If ParseOptions.AllowSingleQuotes is set, values may also be surrounded by
+single quotes ('\''), which do not process escape sequences.
+
+ParseOptions.Systemd accepts the syntax of systemd unit files, in which
+values are never quoted.
 
 Properties may be grouped into sections. A section is started by writing its
 name in square brackets ('[' and ']') on its own line and ends at the next
@@ -47,7 +64,9 @@ Whitespace (characters with the Unicode White Space property) at the
 beginning or end of lines, around section names, around property keys, and
 around property values are ignored. If the first non-whitespace character in
 a line is a semicolon (';') or a hash ('#'), then the line is treated as a
-comment. Inline comments are not supported.
+comment. Inline comments after a property's value are only recognized if
+ParseOptions.AllowInlineComments is set. Whitespace inside a key is
+significant unless ParseOptions.CollapseKeyWhitespace is set.
 
 Repeated names
 
@@ -0,0 +1,147 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Encode stores the tagged fields of the struct pointed to by v (or the struct
+// v itself) in f, editing f in place. It is the inverse of Decode and uses the
+// same ini tags and field types.
+//
+// A non-slice field is stored as if by Set, unless the property already has
+// the field's value, in which case it is left untouched. A slice field
+// replaces the values of existing properties with its key in order, appending
+// any additional values at the end of the section and deleting any leftover
+// properties. Comments and the positions of existing properties are
+// preserved. A nil or empty slice deletes all properties with its key.
+//
+// Values are formatted so that Decode reads them back: booleans as "true" or
+// "false", numbers in base 10, and durations with time.Duration.String.
+//
+// Encode returns an error and does not modify f if v is not a struct or a
+// non-nil pointer to a struct, if a tagged field has an unsupported type, or if
+// a tag names an invalid section or key.
+func Encode(f *File, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("encode ini: %T is not a struct or a non-nil pointer to a struct", v)
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		section, key, ok := parseFieldTag(field)
+		if !ok {
+			continue
+		}
+		if !isSupportedType(field.Type) {
+			return fmt.Errorf("encode ini: %s (field %s): unsupported type %v", propertyName(section, key), field.Name, field.Type)
+		}
+		if err := validateName(section, key); err != nil {
+			return fmt.Errorf("encode ini: field %s: %w", field.Name, err)
+		}
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		section, key, ok := parseFieldTag(rt.Field(i))
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+		if fv.Kind() != reflect.Slice {
+			value := encodeValue(fv)
+			if prop := f.last(section, key); prop == nil || prop.value != value || prop.fromDefault {
+				f.Set(section, key, value)
+			}
+			continue
+		}
+		values := make([]string, fv.Len())
+		for j := range values {
+			values[j] = encodeValue(fv.Index(j))
+		}
+		f.replaceAll(section, key, values)
+	}
+	return nil
+}
+
+// replaceAll sets the values of the properties with the given key in the given
+// section to values in order, appending or deleting properties as needed.
+// Properties whose value does not change are left untouched.
+func (f *File) replaceAll(sectionName, key string, values []string) {
+	f.beforeChange()
+	if len(values) == 0 {
+		f.Delete(sectionName, key)
+		return
+	}
+	n := 0
+	for i := range f.sections {
+		s := &f.sections[i]
+		if s.name != sectionName {
+			continue
+		}
+		for j := range s.properties {
+			prop := &s.properties[j]
+			if prop.key != key || n >= len(values) {
+				continue
+			}
+			if prop.value != values[n] || prop.fromDefault {
+				prop.setValue(values[n])
+				prop.fromDefault = false
+			}
+			n++
+		}
+	}
+	if n < len(values) {
+		f.Add(sectionName, key, values[n:])
+		return
+	}
+	found := 0
+	for i := range f.sections {
+		s := &f.sections[i]
+		if s.name != sectionName {
+			continue
+		}
+		kept := s.properties[:0]
+		for _, prop := range s.properties {
+			if prop.key == key {
+				found++
+				if found > len(values) {
+					continue
+				}
+			}
+			kept = append(kept, prop)
+		}
+		for j := len(kept); j < len(s.properties); j++ {
+			// Zero out for garbage collection.
+			s.properties[j] = property{}
+		}
+		s.properties = kept
+	}
+}
+
+func encodeValue(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	default:
+		panic("unreachable")
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEncode(t *testing.T) {
+	type config struct {
+		Name    string        `ini:"name"`
+		Debug   bool          `ini:"debug"`
+		Port    int           `ini:"server,port"`
+		Ratio   float64       `ini:"server,ratio"`
+		Timeout time.Duration `ini:"server,timeout"`
+		Hosts   []string      `ini:"server,host"`
+		Weights []int         `ini:"server,weight"`
+		Tags    []string      `ini:"server,tag"`
+		Ignored string        `ini:"-"`
+	}
+	tests := []struct {
+		name   string
+		source string
+		v      config
+		want   string
+	}{
+		{
+			name:   "Empty",
+			source: "",
+			v: config{
+				Name:    "app",
+				Port:    80,
+				Ratio:   0.5,
+				Timeout: 90 * time.Second,
+				Hosts:   []string{"a", "b"},
+				Ignored: "x",
+			},
+			want: "name=app\n" +
+				"debug=false\n" +
+				"\n" +
+				"[server]\n" +
+				"port=80\n" +
+				"ratio=0.5\n" +
+				"timeout=1m30s\n" +
+				"host=a\n" +
+				"host=b\n",
+		},
+		{
+			name: "PreserveLayout",
+			source: "; leading\n" +
+				"name = \"app\"\n" +
+				"debug=false\n" +
+				"\n" +
+				"[server]\n" +
+				"; the host\n" +
+				"host=a\n" +
+				"port=80\n" +
+				"host=b\n" +
+				"host=c\n" +
+				"ratio=0.5\n" +
+				"timeout=1m30s\n" +
+				"weight=1\n" +
+				"tag=x\n",
+			v: config{
+				Name:    "app",
+				Debug:   true,
+				Port:    8080,
+				Ratio:   0.5,
+				Timeout: 90 * time.Second,
+				Hosts:   []string{"a", "z"},
+				Weights: []int{1, 2, 3},
+			},
+			want: "; leading\n" +
+				"name=app\n" +
+				"debug=true\n" +
+				"\n" +
+				"[server]\n" +
+				"; the host\n" +
+				"host=a\n" +
+				"port=8080\n" +
+				"host=z\n" +
+				"ratio=0.5\n" +
+				"timeout=1m30s\n" +
+				"weight=1\n" +
+				"weight=2\n" +
+				"weight=3\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(test.source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Encode(f, &test.v); err != nil {
+				t.Fatal("Encode:", err)
+			}
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalText() after Encode (-want +got):\n%s", diff)
+			}
+
+			var decoded config
+			if err := Decode(f, &decoded); err != nil {
+				t.Fatal("Decode:", err)
+			}
+			want := test.v
+			want.Ignored = ""
+			if len(want.Tags) == 0 {
+				want.Tags = nil
+			}
+			if diff := cmp.Diff(want, decoded); diff != "" {
+				t.Errorf("Decode after Encode (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{
+			name: "NotStruct",
+			v:    42,
+			want: "not a struct",
+		},
+		{
+			name: "UnsupportedType",
+			v: &struct {
+				Name string            `ini:"name"`
+				M    map[string]string `ini:"m"`
+			}{Name: "x"},
+			want: "unsupported type",
+		},
+		{
+			name: "InvalidKey",
+			v: &struct {
+				Name string `ini:"name"`
+				Bad  string `ini:"s,a=b"`
+			}{Name: "x"},
+			want: "field Bad",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := new(File)
+			err := Encode(f, test.v)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Encode(...) = %v; want error containing %q", err, test.want)
+			}
+			if got := f.Sections(); len(got) > 0 {
+				t.Errorf("Encode modified the file; sections = %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvOverlay returns a File with a property for each environment variable
+// whose name starts with prefix. It can be placed at the front of a FileSet
+// so that the environment takes precedence over configuration files:
+//
+//	fset = append(ini.FileSet{ini.EnvOverlay("APP_")}, fset...)
+//
+// The prefix is removed from each name, and the rest is split at its first
+// underscore into a section name and a key, both converted to lower case.
+// For example, with the prefix "APP_", APP_SERVER_MAX_CONNS sets the key
+// "max_conns" in the section "server". Names without another underscore
+// (like APP_DEBUG) set keys in the global section, as do names with an
+// underscore directly after the prefix (like APP__LOG_LEVEL, which sets the
+// key "log_level"). Variables that would produce an invalid section name or
+// key are ignored.
+//
+// The returned File is a snapshot of the environment at the time of the call.
+func EnvOverlay(prefix string) *File {
+	return envOverlay(prefix, os.Environ())
+}
+
+func envOverlay(prefix string, environ []string) *File {
+	environ = append([]string(nil), environ...)
+	sort.Strings(environ)
+	f := new(File)
+	for _, kv := range environ {
+		eq := strings.IndexByte(kv, '=')
+		if eq == -1 || !strings.HasPrefix(kv[:eq], prefix) {
+			continue
+		}
+		name, value := strings.ToLower(kv[len(prefix):eq]), kv[eq+1:]
+		section, key := "", name
+		if i := strings.IndexByte(name, '_'); i != -1 {
+			section, key = name[:i], name[i+1:]
+		}
+		if key == "" || validateName(section, key) != nil {
+			continue
+		}
+		f.Set(section, key, value)
+	}
+	return f
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEnvOverlay(t *testing.T) {
+	environ := []string{
+		"HOME=/home/me",
+		"APP_SERVER_PORT=9090",
+		"APP_SERVER_MAX_CONNS=10",
+		"APP_DEBUG=1",
+		"APP__LOG_LEVEL=info",
+		"APP_EMPTY_=x",
+		"APP_=x",
+		"APPLE=red",
+	}
+	overlay := envOverlay("APP_", environ)
+	want := map[string]map[string][]string{
+		"": {
+			"debug":     {"1"},
+			"log_level": {"info"},
+		},
+		"server": {
+			"port":      {"9090"},
+			"max_conns": {"10"},
+		},
+	}
+	if diff := cmp.Diff(want, overlay.Map()); diff != "" {
+		t.Errorf("envOverlay(...).Map() (-want +got):\n%s", diff)
+	}
+
+	f, err := Parse(strings.NewReader("[server]\nport=8080\nhost=localhost\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := FileSet{overlay, f}
+	if got, want := fset.Get("server", "port"), "9090"; got != want {
+		t.Errorf("fset.Get(\"server\", \"port\") = %q; want %q", got, want)
+	}
+	if got, want := fset.Get("server", "host"), "localhost"; got != want {
+		t.Errorf("fset.Get(\"server\", \"host\") = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+// Equal reports whether a and b have the same properties: the same values in
+// the same order for each section and key. Comments, formatting, and the
+// placement of properties under repeated section headers are ignored. It is
+// equivalent to a.EqualFold(b, nil).
+func Equal(a, b *File) bool {
+	return a.EqualFold(b, nil)
+}
+
+// EqualFold reports whether f and other have the same properties after
+// applying fold to every section name and key. For each folded section and
+// key, the files must have the same values in the same order, so keys whose
+// names differ only in ways removed by fold (such as letter case, with
+// strings.ToLower) are treated as the same key. Values are always compared
+// exactly. Comments, formatting, and the placement of properties under
+// repeated section headers are ignored. A nil fold compares names exactly.
+// A nil file is equal to a file with no properties.
+func (f *File) EqualFold(other *File, fold func(string) string) bool {
+	if fold == nil {
+		fold = func(s string) string { return s }
+	}
+	a := f.foldedValues(fold)
+	b := other.foldedValues(fold)
+	if len(a) != len(b) {
+		return false
+	}
+	for k, va := range a {
+		vb, ok := b[k]
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if va[i] != vb[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type foldedKey struct {
+	section string
+	key     string
+}
+
+// foldedValues returns the values of every property in f keyed by its folded
+// section name and key, in the order they appear in the file.
+func (f *File) foldedValues(fold func(string) string) map[foldedKey][]string {
+	m := make(map[foldedKey][]string)
+	if f == nil {
+		return m
+	}
+	for _, s := range f.sections {
+		name := fold(s.name)
+		for _, p := range s.properties {
+			k := foldedKey{section: name, key: fold(p.key)}
+			m[k] = append(m[k], p.value)
+		}
+	}
+	return m
+}
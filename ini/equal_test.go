@@ -0,0 +1,116 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEqualFold(t *testing.T) {
+	tests := []struct {
+		name      string
+		a         string
+		b         string
+		wantExact bool
+		wantFold  bool
+	}{
+		{
+			name:      "Identical",
+			a:         "top=1\n[build]\ncmd=make\n",
+			b:         "top=1\n[build]\ncmd=make\n",
+			wantExact: true,
+			wantFold:  true,
+		},
+		{
+			name:      "Formatting",
+			a:         "; Comment\ntop = 1\n[build]\ncmd=\"make\"\n",
+			b:         "top=1\n\n[ build ]\ncmd=make\n",
+			wantExact: true,
+			wantFold:  true,
+		},
+		{
+			name:      "KeyCase",
+			a:         "[build]\nCmd=make\n",
+			b:         "[build]\ncmd=make\n",
+			wantExact: false,
+			wantFold:  true,
+		},
+		{
+			name:      "SectionCase",
+			a:         "[Build]\ncmd=make\n[BUILD]\ntags=a\n",
+			b:         "[build]\ncmd=make\ntags=a\n",
+			wantExact: false,
+			wantFold:  true,
+		},
+		{
+			name:      "ValueCase",
+			a:         "[build]\ncmd=Make\n",
+			b:         "[build]\ncmd=make\n",
+			wantExact: false,
+			wantFold:  false,
+		},
+		{
+			name:      "FoldedCollision",
+			a:         "[build]\ncmd=a\nCMD=b\n",
+			b:         "[build]\ncmd=a\n",
+			wantExact: false,
+			wantFold:  false,
+		},
+		{
+			name:      "ValueOrder",
+			a:         "[build]\ntags=a\ntags=b\n",
+			b:         "[build]\ntags=b\ntags=a\n",
+			wantExact: false,
+			wantFold:  false,
+		},
+		{
+			name:      "Missing",
+			a:         "[build]\ncmd=make\n",
+			b:         "",
+			wantExact: false,
+			wantFold:  false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := Parse(strings.NewReader(test.a), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := Parse(strings.NewReader(test.b), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Equal(a, b); got != test.wantExact {
+				t.Errorf("Equal(a, b) = %t; want %t", got, test.wantExact)
+			}
+			if got := a.EqualFold(b, nil); got != test.wantExact {
+				t.Errorf("a.EqualFold(b, nil) = %t; want %t", got, test.wantExact)
+			}
+			if got := b.EqualFold(a, nil); got != test.wantExact {
+				t.Errorf("b.EqualFold(a, nil) = %t; want %t", got, test.wantExact)
+			}
+			if got := a.EqualFold(b, strings.ToLower); got != test.wantFold {
+				t.Errorf("a.EqualFold(b, strings.ToLower) = %t; want %t", got, test.wantFold)
+			}
+			if got := b.EqualFold(a, strings.ToLower); got != test.wantFold {
+				t.Errorf("b.EqualFold(a, strings.ToLower) = %t; want %t", got, test.wantFold)
+			}
+		})
+	}
+
+	t.Run("Nil", func(t *testing.T) {
+		empty, err := Parse(strings.NewReader("; Just a comment\n"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !(*File)(nil).EqualFold(empty, nil) {
+			t.Error("(*File)(nil).EqualFold(empty, nil) = false; want true")
+		}
+		if !empty.EqualFold(nil, nil) {
+			t.Error("empty.EqualFold(nil, nil) = false; want true")
+		}
+	})
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import "strings"
+
+// expandEnv replaces "${NAME}" and "$NAME" in s with the result of calling
+// getenv with NAME and replaces "$$" with a single "$". NAME must be an ASCII
+// letter or underscore followed by ASCII letters, digits, or underscores. A
+// dollar sign that does not start one of these forms is left unchanged.
+func expandEnv(s string, getenv func(string) string) string {
+	i := strings.IndexByte(s, '$')
+	if i == -1 {
+		return s
+	}
+	sb := new(strings.Builder)
+	sb.Grow(len(s))
+	for i != -1 {
+		sb.WriteString(s[:i])
+		s = s[i+1:]
+		switch {
+		case strings.HasPrefix(s, "$"):
+			sb.WriteByte('$')
+			s = s[1:]
+		case strings.HasPrefix(s, "{"):
+			end := strings.IndexByte(s, '}')
+			if end == -1 || !isEnvName(s[1:end]) {
+				sb.WriteByte('$')
+				break
+			}
+			sb.WriteString(getenv(s[1:end]))
+			s = s[end+1:]
+		default:
+			n := envNameLen(s)
+			if n == 0 {
+				sb.WriteByte('$')
+				break
+			}
+			sb.WriteString(getenv(s[:n]))
+			s = s[n:]
+		}
+		i = strings.IndexByte(s, '$')
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
+
+// envNameLen returns the length of the environment variable name at the
+// beginning of s or 0 if s does not start with a name.
+func envNameLen(s string) int {
+	for i := 0; i < len(s); i++ {
+		if !isEnvName(s[:i+1]) {
+			return i
+		}
+	}
+	return len(s)
+}
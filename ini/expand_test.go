@@ -0,0 +1,95 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{
+		"HOME": "/home/me",
+		"A_1":  "x",
+	}
+	getenv := func(name string) string { return env[name] }
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"", ""},
+		{"plain", "plain"},
+		{"$HOME/bin", "/home/me/bin"},
+		{"${HOME}bin", "/home/mebin"},
+		{"$A_1$A_1", "xx"},
+		{"$A_1-y", "x-y"},
+		{"$MISSING|", "|"},
+		{"${MISSING}", ""},
+		{"$$HOME", "$HOME"},
+		{"$$$HOME", "$/home/me"},
+		{"cost: 5$", "cost: 5$"},
+		{"$1", "$1"},
+		{"${HOME", "${HOME"},
+		{"${}", "${}"},
+		{"${not valid}", "${not valid}"},
+	}
+	for _, test := range tests {
+		if got := expandEnv(test.s, getenv); got != test.want {
+			t.Errorf("expandEnv(%q) = %q; want %q", test.s, got, test.want)
+		}
+	}
+}
+
+func TestParseExpandEnv(t *testing.T) {
+	const name = "INI_TEST_EXPAND_ENV"
+	old, hadOld := os.LookupEnv(name)
+	if err := os.Setenv(name, "world"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if hadOld {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	}()
+
+	const source = "greeting = hello ${" + name + "}\n" +
+		"quoted = \"$" + name + "\\t$$\"\n"
+	f, err := Parse(strings.NewReader(source), &ParseOptions{ExpandEnv: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.Get("", "greeting"), "hello world"; got != want {
+		t.Errorf("f.Get(\"\", \"greeting\") = %q; want %q", got, want)
+	}
+	if got, want := f.Get("", "quoted"), "world\t$"; got != want {
+		t.Errorf("f.Get(\"\", \"quoted\") = %q; want %q", got, want)
+	}
+
+	// Marshaling must not cause the values to be expanded again.
+	f.Set("", "literal", "$"+name)
+	text, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := Parse(strings.NewReader(string(text)), &ParseOptions{ExpandEnv: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"greeting", "quoted", "literal"} {
+		if got, want := reparsed.Get("", key), f.Get("", key); got != want {
+			t.Errorf("after round trip, f.Get(\"\", %q) = %q; want %q", key, got, want)
+		}
+	}
+
+	f, err = Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.Get("", "greeting"), "hello ${"+name+"}"; got != want {
+		t.Errorf("without ExpandEnv, f.Get(\"\", \"greeting\") = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"flag"
+	"fmt"
+)
+
+// BindFlags sets each flag in fs that was not set on the command line from
+// the property in the given section whose key is the flag's name. Flags that
+// were set on the command line take precedence over the file, so BindFlags
+// should be called after fs.Parse. If the key has multiple values, the flag's
+// Set method is called once for each value in order, so repeatable flags
+// receive every value and other flags receive the last one. Flags without a
+// corresponding property are left unchanged. A nil file sets no flags.
+//
+// BindFlags returns an error if a flag rejects a value.
+func BindFlags(f *File, section string, fs *flag.FlagSet) error {
+	if f == nil {
+		return nil
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) {
+		set[fl.Name] = true
+	})
+	var err error
+	fs.VisitAll(func(fl *flag.Flag) {
+		if err != nil || set[fl.Name] {
+			return
+		}
+		for _, v := range f.Find(section, fl.Name) {
+			if setErr := fs.Set(fl.Name, v); setErr != nil {
+				err = fmt.Errorf("bind ini flags: [%s] %s: %w", section, fl.Name, setErr)
+				return
+			}
+		}
+	})
+	return err
+}
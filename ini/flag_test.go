@@ -0,0 +1,79 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type stringsFlag []string
+
+func (sf *stringsFlag) String() string {
+	return strings.Join(*sf, ",")
+}
+
+func (sf *stringsFlag) Set(v string) error {
+	*sf = append(*sf, v)
+	return nil
+}
+
+func TestBindFlags(t *testing.T) {
+	const source = "[app]\n" +
+		"name=file\n" +
+		"port=8080\n" +
+		"verbose=true\n" +
+		"tag=a\n" +
+		"tag=b\n" +
+		"unknown=x\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	name := fs.String("name", "default", "")
+	port := fs.Int("port", 80, "")
+	verbose := fs.Bool("verbose", false, "")
+	timeout := fs.Duration("timeout", 0, "")
+	var tags stringsFlag
+	fs.Var(&tags, "tag", "")
+	if err := fs.Parse([]string{"-name=cli"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := BindFlags(f, "app", fs); err != nil {
+		t.Fatal("BindFlags:", err)
+	}
+	if got, want := *name, "cli"; got != want {
+		t.Errorf("name = %q; want %q", got, want)
+	}
+	if got, want := *port, 8080; got != want {
+		t.Errorf("port = %d; want %d", got, want)
+	}
+	if !*verbose {
+		t.Error("verbose = false; want true")
+	}
+	if *timeout != 0 {
+		t.Errorf("timeout = %v; want 0", *timeout)
+	}
+	if diff := cmp.Diff(stringsFlag{"a", "b"}, tags); diff != "" {
+		t.Errorf("tags (-want +got):\n%s", diff)
+	}
+
+	t.Run("BadValue", func(t *testing.T) {
+		f, err := Parse(strings.NewReader("port=http\n"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fs := flag.NewFlagSet("app", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		fs.Int("port", 80, "")
+		if err := BindFlags(f, "", fs); err == nil {
+			t.Error("BindFlags did not return an error")
+		}
+	})
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+//go:build go1.16
+// +build go1.16
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// ParseFS parses the files at the given paths in fsys as INI and returns a
+// FileSet. It behaves like ParseFiles, but reads files from fsys, so default
+// configuration embedded with go:embed can be combined with files on disk:
+//
+//	embedded, err := ini.ParseFS(defaultsFS, nil, "defaults.ini")
+//	...
+//	fset := append(onDisk, embedded...)
+//
+// Paths are slash-separated as required by fs.FS.
+func ParseFS(fsys fs.FS, opts *ParseOptions, paths ...string) (FileSet, error) {
+	fset := make(FileSet, 0, len(paths))
+	for _, p := range paths {
+		f, err := fsys.Open(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			fset = append(fset, nil)
+			continue
+		}
+		if err != nil {
+			return fset, fmt.Errorf("parse ini files: %w", err)
+		}
+		parsed, err := Parse(f, opts)
+		f.Close() // Close errors irrelevant.
+		if err != nil {
+			return fset, fmt.Errorf("parse ini files: %s: %w", p, err)
+		}
+		fset = append(fset, parsed)
+	}
+	return fset, nil
+}
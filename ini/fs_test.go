@@ -0,0 +1,43 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+//go:build go1.16
+// +build go1.16
+
+package ini
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"local.ini":         {Data: []byte("name=local\n")},
+		"defaults/base.ini": {Data: []byte("name=base\ncolor=blue\n")},
+		"bad.ini":           {Data: []byte("[bad\n")},
+	}
+	fset, err := ParseFS(fsys, nil, "local.ini", "missing.ini", "defaults/base.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fset) != 3 {
+		t.Fatalf("len(fset) = %d; want 3", len(fset))
+	}
+	if fset[1] != nil {
+		t.Errorf("fset[1] = %v; want <nil> for missing file", fset[1])
+	}
+	if got, want := fset.Get("", "name"), "local"; got != want {
+		t.Errorf("fset.Get(\"\", \"name\") = %q; want %q", got, want)
+	}
+	if got, want := fset.Get("", "color"), "blue"; got != want {
+		t.Errorf("fset.Get(\"\", \"color\") = %q; want %q", got, want)
+	}
+
+	_, err = ParseFS(fsys, nil, "local.ini", "bad.ini")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("ParseFS with bad file error = %v; want *ParseError", err)
+	}
+}
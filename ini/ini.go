@@ -6,9 +6,12 @@ package ini
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -17,20 +20,147 @@ import (
 // A File is a collection of properties. The zero value is an empty file.
 // Files can be read by multiple concurrent goroutines.
 type File struct {
+	leadingLines     []string
 	sections         []section
 	trailingComments []string
+	directives       []Directive
+	defaults         map[string]Section
+
+	// inlineComments is true if the file was parsed with
+	// ParseOptions.AllowInlineComments.
+	inlineComments bool
+
+	// singleQuotes is true if the file was parsed with
+	// ParseOptions.AllowSingleQuotes.
+	singleQuotes bool
+
+	// lineContinuation is true if the file was parsed with
+	// ParseOptions.AllowLineContinuation.
+	lineContinuation bool
+
+	// expandEnv is true if the file was parsed with ParseOptions.ExpandEnv.
+	expandEnv bool
+
+	// systemd is true if the file was parsed with ParseOptions.Systemd.
+	systemd bool
+
+	// defaultSection is the value of ParseOptions.DefaultSection.
+	defaultSection string
+
+	// trailingBlankLines is the number of blank lines before trailingComments
+	// in the source. It is only valid if hasBlankLines is true, which it is if
+	// the file was parsed with ParseOptions.PreserveBlankLines.
+	trailingBlankLines int
+	hasBlankLines      bool
+
+	// path is the file's location on disk, set by ParseFiles or SetFilename.
+	path string
+	// saved is true if the file's contents matched the file at path when it
+	// was last read from or written to path. See FileSet.SaveModified.
+	saved bool
+	// savedSum is the SHA-256 hash of the output of MarshalText when the file
+	// was last read from or written to path. It is only valid if hasSavedSum
+	// is true. ParseFiles does not compute it: beforeChange computes it when
+	// the file is first changed, so files that are only read are never
+	// marshaled.
+	savedSum    [sha256.Size]byte
+	hasSavedSum bool
 }
 
 type section struct {
 	name       string
 	comments   []string
 	properties []property
+
+	// fromDefaults is true if the section was added by ParseWithDefaults.
+	fromDefaults bool
+
+	// header is the section's header line as it appeared in the source,
+	// recorded when parsing with ParseOptions.PreserveFormatting. If empty,
+	// the header is written as "[name]".
+	header string
+
+	// line is the 1-based line number of the header in the source, or zero if
+	// the section was not read by Parse.
+	line int
+
+	// blankLines is the number of blank lines before the header and its
+	// comments in the source, recorded when parsing with
+	// ParseOptions.PreserveBlankLines. It is only valid if hasBlankLines is
+	// true.
+	blankLines    int
+	hasBlankLines bool
 }
 
 type property struct {
 	comments []string
 	key      string
 	value    string
+
+	// inlineComment is the comment that followed the value on the same line,
+	// including its leading ';' or '#'. It is empty if there is none.
+	inlineComment string
+
+	// continuation holds the source lines of a value that was continued
+	// across multiple lines (see ParseOptions.AllowLineContinuation). The
+	// first element is the text after the '=' on the first line. It is nil if
+	// the value was on a single line or has been changed since parsing.
+	continuation []string
+
+	// indent and delim are the whitespace before the key and the text
+	// between the key and the value (including the '=') as they appeared in
+	// the source, recorded when parsing with ParseOptions.PreserveFormatting.
+	// An empty delim is written as "=".
+	indent string
+	delim  string
+
+	// verbatim is the value's source text, recorded when parsing with
+	// ParseOptions.PreserveFormatting. It is only valid if hasVerbatim is true.
+	verbatim    string
+	hasVerbatim bool
+
+	// export is true if the property was prefixed with "export" in the
+	// source. See ParseOptions.AllowExport.
+	export bool
+
+	// bare is true if the property was written without an '=' in the source.
+	// See ParseOptions.AllowBareKeys.
+	bare bool
+
+	// line is the 1-based line number of the property in the source, or zero
+	// if the property was not read by Parse. For a value continued across
+	// multiple lines, it is the number of the first line.
+	line int
+
+	// blankLines is the number of blank lines before the property and its
+	// comments in the source, recorded when parsing with
+	// ParseOptions.PreserveBlankLines. It is only valid if hasBlankLines is
+	// true.
+	blankLines    int
+	hasBlankLines bool
+
+	// raw is the value's text as it appeared in the source.
+	// It is only valid if hasRaw is true.
+	raw    string
+	hasRaw bool
+
+	// quote is how the value should be quoted when the file is marshaled.
+	quote QuoteStyle
+
+	// fromDefault is true if the property was added by ParseWithDefaults and
+	// has not been set since.
+	fromDefault bool
+}
+
+// setValue changes the property's value, discarding any information about its
+// source text.
+func (prop *property) setValue(v string) {
+	prop.value = v
+	prop.raw = ""
+	prop.hasRaw = false
+	prop.continuation = nil
+	prop.verbatim = ""
+	prop.hasVerbatim = false
 }
 
 // ParseOptions holds optional parameters for Parse.
@@ -44,6 +174,276 @@ type ParseOptions struct {
 	// This can be used to make keys case-insensitive, for instance.
 	// If nil, no transformations are made.
 	NormalizeKey func(section, key string) string
+
+	// CollapseKeyWhitespace causes Parse to replace each run of whitespace
+	// inside a key with a single space, so "foo\tbar" and "foo  bar" are both
+	// read as "foo bar". By default, whitespace inside a key is kept verbatim.
+	// Collapsing happens before NormalizeKey is called.
+	CollapseKeyWhitespace bool
+
+	// KeepRawValue causes Parse to record the text of each value as it appeared
+	// in the source (after the '=' with surrounding whitespace removed, but
+	// with any quotes and escape sequences intact). The recorded text can be
+	// retrieved with File.RawValue.
+	KeepRawValue bool
+
+	// DirectivePrefix is a string (like ";!") that marks a line as a directive.
+	// If DirectivePrefix is not empty, any line that begins with it (ignoring
+	// leading whitespace) is recorded as a Directive instead of being
+	// interpreted, and can be retrieved with File.Directives. Directive lines
+	// are kept in place and written verbatim by MarshalText.
+	DirectivePrefix string
+
+	// PreserveLeadingLines is the number of lines at the beginning of the
+	// source that Parse stores verbatim without interpreting them, such as a
+	// shebang ("#!/usr/bin/env app") or a generator banner. MarshalText writes
+	// the stored lines unchanged at the top of its output.
+	PreserveLeadingLines int
+
+	// RejectTabs causes Parse to return a *ParseError for any line whose
+	// leading whitespace contains a tab character. By default, tabs are
+	// treated like any other whitespace.
+	RejectTabs bool
+
+	// AllowLineContinuation causes Parse to join a property line that ends
+	// with an odd number of backslashes ('\') with the following line. The
+	// final backslash is removed and leading whitespace on the following line
+	// is dropped, so "key = a \" followed by "    b" has the value "a b".
+	// A continued value may span any number of lines. MarshalText writes
+	// continued values across the same lines as long as the value has not
+	// been changed. A value that ends in a backslash is quoted by MarshalText
+	// so that it is not read back as continued. By default, a trailing
+	// backslash is part of the value, as required for .env files.
+	AllowLineContinuation bool
+
+	// AllowInlineComments causes Parse to treat a semicolon (';') or hash
+	// ('#') that starts a property's value or follows whitespace in the value
+	// as the start of a comment that runs to the end of the line, as in
+	// "key=value ; comment". The comment is kept with the property and written
+	// after its value by MarshalText. A quoted value may be followed by an
+	// inline comment. By default, such text is part of the value. Section
+	// headers may not have inline comments.
+	AllowInlineComments bool
+
+	// PreserveFormatting causes Parse to record the indentation of each
+	// property, the whitespace around its '=', the text of its value
+	// (including any quotes), and the spacing of each section header as they
+	// appear in the source. MarshalText reproduces the recorded formatting, so
+	// "  foo = bar" is not rewritten as "foo=bar". A value's text is only
+	// reproduced until the value is changed. By default, MarshalText writes
+	// every property and section header in a single canonical form.
+	PreserveFormatting bool
+
+	// PreserveBlankLines causes Parse to record the number of blank lines
+	// before each section header and property (or before the comments above
+	// it) and before the comments at the end of the file. MarshalText writes
+	// the recorded number of blank lines in place of its usual spacing, so
+	// editing a file does not change its vertical whitespace. Blank lines
+	// between two comment lines and at the end of the file are not preserved.
+	PreserveBlankLines bool
+
+	// ExpandEnv causes Parse to replace references to environment variables
+	// in values with the variables' values from the process environment.
+	// A reference is written as "${NAME}" or "$NAME", where NAME is an ASCII
+	// letter or underscore followed by ASCII letters, digits, or underscores.
+	// Undefined variables are replaced with the empty string. "$$" is
+	// replaced with a single "$", and any other dollar sign is kept as-is.
+	// Expansion happens after quotes and escape sequences are processed, and
+	// the expanded value is what MarshalText writes, with each "$" written as
+	// "$$" so that the value is not expanded again when the file is parsed.
+	ExpandEnv bool
+
+	// AllowSingleQuotes causes Parse to accept values surrounded by single
+	// quotes ('\''), as in "key='literal value'". Single-quoted values are
+	// taken literally: escape sequences are not processed and, if ExpandEnv is
+	// set, variables are not expanded. A single-quoted value cannot contain a
+	// single quote. When a file parsed with this option is marshaled, values
+	// that contain double quotes are written in single quotes when possible.
+	// By default, single quotes are part of the value.
+	AllowSingleQuotes bool
+
+	// AllowExport causes Parse to accept properties prefixed with the word
+	// "export" and whitespace, as in "export KEY=value", so that files that
+	// can be sourced by a POSIX shell can be parsed. The prefix is not part of
+	// the key. MarshalText writes the prefix for properties that had it, so
+	// such files round-trip, and quotes their values if they contain
+	// whitespace. A key named "export" (as in "export=1") is not
+	// affected.
+	AllowExport bool
+
+	// AllowBareKeys causes Parse to treat a line with only a key and no '='
+	// (like "verbose") as a property with an empty value instead of an
+	// error. MarshalText writes such properties back without the '=' as long
+	// as their value is empty.
+	AllowBareKeys bool
+
+	// Systemd causes Parse to accept the syntax of systemd unit files. It
+	// implies AllowLineContinuation, except that the backslash at the end of
+	// each continued line is replaced with a space, as systemd does. Quotes
+	// and backslashes in values are not processed, so values are taken
+	// exactly as written (after removing surrounding whitespace), and
+	// AllowSingleQuotes has no effect. In the returned File, an empty
+	// assignment ("key=") resets the list of values for a key: Find and
+	// related methods only return the values after the last empty assignment
+	// in the file. MarshalText writes values exactly as they are, and returns
+	// an error for values that systemd would not read back identically.
+	Systemd bool
+
+	// DefaultSection names a section whose properties are inherited by every
+	// other named section, like the "[DEFAULT]" section of Python's
+	// configparser. If a section has no properties for a key, Get and Find
+	// return the default section's values for the key after checking the
+	// section's own defaults (see File.SetSectionDefaults). As with section
+	// defaults, inherited values are not properties of the section: they do
+	// not affect Has, Section, or FileSet lookups. The global section does not
+	// inherit from the default section. By default, no section is inherited.
+	DefaultSection string
+}
+
+// quoteSyntax specifies how quotes in property values are interpreted.
+type quoteSyntax int
+
+const (
+	// doubleQuotes accepts values surrounded by double quotes that may
+	// contain escape sequences. This is the default.
+	doubleQuotes quoteSyntax = iota
+	// singleAndDoubleQuotes also accepts literal single-quoted values. See
+	// ParseOptions.AllowSingleQuotes.
+	singleAndDoubleQuotes
+	// noQuotes takes values literally. See ParseOptions.Systemd.
+	noQuotes
+)
+
+// quoteSyntax returns the quote syntax specified by the options.
+func (opts *ParseOptions) quoteSyntax() quoteSyntax {
+	switch {
+	case opts == nil:
+		return doubleQuotes
+	case opts.Systemd:
+		return noQuotes
+	case opts.AllowSingleQuotes:
+		return singleAndDoubleQuotes
+	default:
+		return doubleQuotes
+	}
+}
+
+// A Directive is a line recognized by ParseOptions.DirectivePrefix.
+type Directive struct {
+	// Line is the 1-based line number of the directive in the source.
+	Line int
+	// Text is the directive's text after the prefix, with surrounding
+	// whitespace removed.
+	Text string
+}
+
+// ParseError is the error returned by Parse for a malformed line.
+type ParseError struct {
+	// Line is the 1-based line number of the error in the source.
+	Line int
+	// Column is the 1-based byte offset of the error within the line,
+	// or 0 if unknown.
+	Column int
+	// Kind classifies the problem for programs that handle errors.
+	Kind ParseErrorKind
+	// Text is the text of the malformed line, without its line terminator.
+	Text string
+	// Err describes the problem with the line.
+	Err error
+}
+
+// ParseErrorKind is a machine-readable classification of a ParseError.
+type ParseErrorKind int
+
+// Kinds of parse errors.
+const (
+	// UnspecifiedParseError is the zero ParseErrorKind. Parse does not return
+	// errors of this kind.
+	UnspecifiedParseError ParseErrorKind = iota
+	// TabIndentation is returned for a line indented with a tab when
+	// ParseOptions.RejectTabs is set.
+	TabIndentation
+	// UnclosedSection is returned for a section header without a closing
+	// bracket.
+	UnclosedSection
+	// EmptySectionName is returned for a section header with no name.
+	EmptySectionName
+	// BracketInSectionName is returned for a section name that contains a
+	// square bracket.
+	BracketInSectionName
+	// MissingEquals is returned for a line that is not a comment or section
+	// header and does not contain an equals sign.
+	MissingEquals
+	// InvalidKey is returned for a property whose key is rejected by
+	// IsValidKey.
+	InvalidKey
+	// UnterminatedString is returned for a quoted value without a closing
+	// quote.
+	UnterminatedString
+	// TrailingCharacters is returned for a quoted value that is followed by
+	// other text.
+	TrailingCharacters
+	// InvalidEscape is returned for an unknown or incomplete escape sequence
+	// in a quoted value.
+	InvalidEscape
+)
+
+var parseErrorKindNames = [...]string{
+	UnspecifiedParseError: "unspecified",
+	TabIndentation:        "tab indentation",
+	UnclosedSection:       "unclosed section",
+	EmptySectionName:      "empty section name",
+	BracketInSectionName:  "bracket in section name",
+	MissingEquals:         "missing equals",
+	InvalidKey:            "invalid key",
+	UnterminatedString:    "unterminated string",
+	TrailingCharacters:    "trailing characters",
+	InvalidEscape:         "invalid escape",
+}
+
+// String returns a short description of the kind, like "invalid key".
+func (k ParseErrorKind) String() string {
+	if k < 0 || int(k) >= len(parseErrorKindNames) {
+		return "ParseErrorKind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return parseErrorKindNames[k]
+}
+
+// syntaxError is an error with a ParseErrorKind, used to construct a
+// ParseError.
+type syntaxError struct {
+	kind ParseErrorKind
+	msg  string
+}
+
+func (e *syntaxError) Error() string {
+	return e.msg
+}
+
+// newParseError returns a *ParseError for the given line. If err is a
+// *syntaxError, its kind is used.
+func newParseError(lineno int, line []byte, column int, err error) *ParseError {
+	e := &ParseError{
+		Line:   lineno,
+		Column: column,
+		Text:   string(line),
+		Err:    err,
+	}
+	var synErr *syntaxError
+	if errors.As(err, &synErr) {
+		e.Kind = synErr.kind
+	}
+	return e
+}
+
+// Error returns the error message, including the line number.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse ini file: line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns e.Err.
+func (e *ParseError) Unwrap() error {
+	return e.Err
 }
 
 // Parse parses an INI file. Nil options are treated identically as passing the
@@ -59,53 +459,397 @@ func Parse(r io.Reader, opts *ParseOptions) (*File, error) {
 		},
 	}
 	lineno := 1
+	if opts != nil {
+		for ; lineno <= opts.PreserveLeadingLines && s.Scan(); lineno++ {
+			f.leadingLines = append(f.leadingLines, s.Text())
+		}
+	}
 	var comments []string
+	preserveBlankLines := opts != nil && opts.PreserveBlankLines
+	blankLines := 0 // before comments
+	lineOpts := opts
+	if lineOpts == nil {
+		lineOpts = new(ParseOptions)
+	}
 	for ; s.Scan(); lineno++ {
-		line, err := cleanLine(s.Bytes())
+		l, err := parseLine(s, &lineno, lineOpts, f.sections[len(f.sections)-1].name)
 		if err != nil {
-			return f, fmt.Errorf("parse ini file: line %d: %w", lineno, err)
-		}
-		if line == "" {
-			continue
+			return f, err
 		}
-		switch line[0] {
-		case ';', '#':
-			comments = append(comments, line)
-		case '[':
-			name := line[1 : len(line)-1]
-			if opts != nil && opts.NormalizeSection != nil {
-				name = opts.NormalizeSection(name)
+		switch l.kind {
+		case blankLine:
+			if len(comments) == 0 {
+				blankLines++
 			}
-			f.sections = append(f.sections, section{
-				name:     name,
-				comments: comments,
+		case commentLine:
+			comments = append(comments, l.text)
+		case directiveLine:
+			// Store the directive as a comment so it keeps its position.
+			comments = append(comments, l.text)
+			f.directives = append(f.directives, Directive{
+				Line: lineno,
+				Text: strings.TrimSpace(l.text[len(opts.DirectivePrefix):]),
 			})
-			comments = nil
-		default:
-			currSection := &f.sections[len(f.sections)-1]
-			i := strings.IndexByte(line, '=')
-			key := line[:i]
-			if !IsValidKey(key) {
-				return f, fmt.Errorf("parse ini file: line %d: invalid key %q", lineno, key)
+		case sectionLine:
+			sect := section{
+				name:     l.name,
+				comments: comments,
+				header:   l.header,
+				line:     lineno,
 			}
-			if opts != nil && opts.NormalizeKey != nil {
-				key = opts.NormalizeKey(currSection.name, key)
+			if preserveBlankLines {
+				sect.blankLines = blankLines
+				sect.hasBlankLines = true
 			}
-			currSection.properties = append(currSection.properties, property{
-				comments: comments,
-				key:      key,
-				value:    unquote(line[i+1:]),
-			})
+			f.sections = append(f.sections, sect)
 			comments = nil
+			blankLines = 0
+		case propertyLine:
+			prop := l.prop
+			prop.comments = comments
+			if preserveBlankLines {
+				prop.blankLines = blankLines
+				prop.hasBlankLines = true
+			}
+			currSection := &f.sections[len(f.sections)-1]
+			currSection.properties = append(currSection.properties, prop)
+			comments = nil
+			blankLines = 0
 		}
 	}
 	if err := s.Err(); err != nil {
 		return f, fmt.Errorf("parse ini file: line %d: %w", lineno, err)
 	}
 	f.trailingComments = comments
+	if preserveBlankLines && len(comments) > 0 {
+		f.trailingBlankLines = blankLines
+	}
+	f.hasBlankLines = preserveBlankLines
+	f.inlineComments = opts != nil && opts.AllowInlineComments
+	f.lineContinuation = opts != nil && opts.AllowLineContinuation
+	f.expandEnv = opts != nil && opts.ExpandEnv
+	f.singleQuotes = opts.quoteSyntax() == singleAndDoubleQuotes
+	f.systemd = opts != nil && opts.Systemd
+	if opts != nil {
+		f.defaultSection = opts.DefaultSection
+	}
 	return f, nil
 }
 
+// lineKind is the kind of a line parsed by parseLine.
+type lineKind int
+
+const (
+	blankLine lineKind = iota
+	commentLine
+	directiveLine
+	sectionLine
+	propertyLine
+)
+
+// parsedLine is a logical line of an INI file returned by parseLine.
+type parsedLine struct {
+	kind lineKind
+
+	// text is the trimmed line for a comment or directive.
+	text string
+
+	// name and header are the section name and, if
+	// ParseOptions.PreserveFormatting is set, the header line for a section.
+	name   string
+	header string
+
+	// prop is the property for a property line. Its comments are not set.
+	prop property
+}
+
+// parseLine parses the scanner's current line, which is line *lineno of the
+// input, as a line of an INI file in the given section. If the line is a
+// continued property line, parseLine advances the scanner and *lineno past
+// the lines that continue it. Both Parse and Decoder use parseLine, so they
+// accept exactly the same syntax and report the same errors.
+func parseLine(s *bufio.Scanner, lineno *int, opts *ParseOptions, sectionName string) (parsedLine, error) {
+	if opts.RejectTabs && hasLeadingTab(s.Bytes()) {
+		return parsedLine{}, newParseError(*lineno, s.Bytes(), bytes.IndexByte(s.Bytes(), '\t')+1,
+			&syntaxError{TabIndentation, "tab in indentation"})
+	}
+	if opts.DirectivePrefix != "" {
+		line := string(bytes.TrimSpace(s.Bytes()))
+		if strings.HasPrefix(line, opts.DirectivePrefix) {
+			return parsedLine{kind: directiveLine, text: line}, nil
+		}
+	}
+	rawLine, inlineComment := s.Bytes(), ""
+	startLine := *lineno
+	var continuation []string
+	if (opts.AllowLineContinuation || opts.Systemd) && isPropertyLine(rawLine) && hasContinuation(rawLine) {
+		rawLine, continuation = joinContinuedLines(s, lineno, opts.Systemd)
+	}
+	if opts.AllowInlineComments {
+		rawLine, inlineComment = splitInlineComment(rawLine, opts.quoteSyntax() == singleAndDoubleQuotes)
+	}
+	export := false
+	if opts.AllowExport {
+		rawLine, export = stripExport(rawLine)
+	}
+	bare := false
+	if opts.AllowBareKeys {
+		rawLine, bare = addBareEquals(rawLine)
+	}
+	line, err := cleanLine(rawLine, opts.quoteSyntax())
+	if err != nil {
+		return parsedLine{}, newParseError(*lineno, s.Bytes(), contentColumn(s.Bytes()), err)
+	}
+	if line == "" {
+		return parsedLine{kind: blankLine}, nil
+	}
+	switch line[0] {
+	case ';', '#':
+		return parsedLine{kind: commentLine, text: line}, nil
+	case '[':
+		l := parsedLine{kind: sectionLine, name: line[1 : len(line)-1]}
+		if opts.NormalizeSection != nil {
+			l.name = opts.NormalizeSection(l.name)
+		}
+		if opts.PreserveFormatting {
+			l.header = string(bytes.TrimRightFunc(rawLine, unicode.IsSpace))
+		}
+		return l, nil
+	default:
+		i := strings.IndexByte(line, '=')
+		key := line[:i]
+		if !IsValidKey(key) {
+			return parsedLine{}, newParseError(*lineno, s.Bytes(), contentColumn(s.Bytes()),
+				&syntaxError{InvalidKey, fmt.Sprintf("invalid key %q", key)})
+		}
+		if opts.CollapseKeyWhitespace {
+			key = collapseSpace(key)
+		}
+		if opts.NormalizeKey != nil {
+			key = opts.NormalizeKey(sectionName, key)
+		}
+		prop := property{
+			key:           key,
+			value:         parseValue(line[i+1:], opts.quoteSyntax(), opts.ExpandEnv),
+			inlineComment: inlineComment,
+			continuation:  continuation,
+			export:        export,
+			bare:          bare,
+			line:          startLine,
+		}
+		if opts.KeepRawValue {
+			prop.raw = line[i+1:]
+			prop.hasRaw = true
+		}
+		if opts.PreserveFormatting {
+			prop.indent, prop.delim = propertyFormat(rawLine)
+			prop.verbatim = line[i+1:]
+			prop.hasVerbatim = true
+		}
+		return parsedLine{kind: propertyLine, prop: prop}, nil
+	}
+}
+
+// propertyFormat returns the whitespace before the key in a property line and
+// the text between the key and the value.
+func propertyFormat(line []byte) (indent, delim string) {
+	trimmed := bytes.TrimLeftFunc(line, unicode.IsSpace)
+	indent = string(line[:len(line)-len(trimmed)])
+	trimmed = bytes.TrimRightFunc(trimmed, unicode.IsSpace)
+	eq := bytes.IndexByte(trimmed, '=')
+	keyEnd := len(bytes.TrimRightFunc(trimmed[:eq], unicode.IsSpace))
+	valueStart := len(trimmed) - len(bytes.TrimLeftFunc(trimmed[eq+1:], unicode.IsSpace))
+	return indent, string(trimmed[keyEnd:valueStart])
+}
+
+// stripExport removes an "export" prefix from a property line, keeping any
+// indentation. See ParseOptions.AllowExport.
+func stripExport(line []byte) (_ []byte, ok bool) {
+	const prefix = "export"
+	trimmed := bytes.TrimLeftFunc(line, unicode.IsSpace)
+	if !bytes.HasPrefix(trimmed, []byte(prefix)) {
+		return line, false
+	}
+	rest := trimmed[len(prefix):]
+	key := bytes.TrimLeftFunc(rest, unicode.IsSpace)
+	if len(key) == len(rest) || len(key) == 0 || key[0] == '=' {
+		return line, false
+	}
+	indent := line[:len(line)-len(trimmed)]
+	stripped := make([]byte, 0, len(indent)+len(key))
+	stripped = append(stripped, indent...)
+	stripped = append(stripped, key...)
+	return stripped, true
+}
+
+// addBareEquals appends an '=' to a property line that does not have one.
+// See ParseOptions.AllowBareKeys.
+func addBareEquals(line []byte) (_ []byte, ok bool) {
+	if !isPropertyLine(line) || bytes.IndexByte(line, '=') != -1 {
+		return line, false
+	}
+	line = bytes.TrimRightFunc(line, unicode.IsSpace)
+	withEquals := make([]byte, 0, len(line)+1)
+	withEquals = append(withEquals, line...)
+	withEquals = append(withEquals, '=')
+	return withEquals, true
+}
+
+// isPropertyLine reports whether line is neither blank, a comment, nor a
+// section header.
+func isPropertyLine(line []byte) bool {
+	line = bytes.TrimLeftFunc(line, unicode.IsSpace)
+	return len(line) > 0 && line[0] != ';' && line[0] != '#' && line[0] != '['
+}
+
+// hasContinuation reports whether line ends with an odd number of
+// backslashes, ignoring trailing whitespace.
+func hasContinuation(line []byte) bool {
+	line = bytes.TrimRightFunc(line, unicode.IsSpace)
+	n := len(line) - len(bytes.TrimRight(line, "\\"))
+	return n%2 == 1
+}
+
+// joinContinuedLines joins the scanner's current line with the lines that
+// continue it, advancing the scanner and *lineno past them. If space is true,
+// each removed backslash is replaced with a space. It returns the joined line
+// and the source lines to preserve for marshaling.
+func joinContinuedLines(s *bufio.Scanner, lineno *int, space bool) (joined []byte, continuation []string) {
+	for {
+		line := bytes.TrimRightFunc(s.Bytes(), unicode.IsSpace)
+		continuation = append(continuation, string(line))
+		if len(continuation) > 1 {
+			line = bytes.TrimLeftFunc(line, unicode.IsSpace)
+		}
+		if !hasContinuation(line) {
+			joined = append(joined, line...)
+			break
+		}
+		joined = append(joined, line[:len(line)-1]...)
+		if space {
+			joined = append(joined, ' ')
+		}
+		if !s.Scan() {
+			break
+		}
+		*lineno++
+	}
+	// Only keep the text after the '=' from the first line.
+	i := strings.IndexByte(continuation[0], '=')
+	if i == -1 {
+		return joined, nil
+	}
+	continuation[0] = strings.TrimLeftFunc(continuation[0][i+1:], unicode.IsSpace)
+	return joined, continuation
+}
+
+// splitInlineComment splits a property line into the part before an inline
+// comment and the normalized comment. See ParseOptions.AllowInlineComments.
+// Lines that are not properties are returned unchanged.
+func splitInlineComment(line []byte, singleQuotes bool) (_ []byte, comment string) {
+	trimmed := bytes.TrimLeftFunc(line, unicode.IsSpace)
+	if len(trimmed) == 0 || trimmed[0] == ';' || trimmed[0] == '#' || trimmed[0] == '[' {
+		return line, ""
+	}
+	// Bare keys (see ParseOptions.AllowBareKeys) have no '=', so the comment
+	// may start anywhere after the key.
+	start := len(line) - len(trimmed)
+	if eq := bytes.IndexByte(line, '='); eq != -1 {
+		start = eq + 1
+	}
+	for start < len(line) && isSpaceByte(line[start]) {
+		start++
+	}
+	i := start
+	if i < len(line) && line[i] == '"' {
+		// Skip over the quoted string.
+		for i++; i < len(line) && line[i] != '"'; i++ {
+			if line[i] == '\\' {
+				i++
+			}
+		}
+		i++
+	} else if singleQuotes && i < len(line) && line[i] == '\'' {
+		// Skip over the single-quoted string.
+		if end := bytes.IndexByte(line[i+1:], '\''); end != -1 {
+			i += end + 2
+		} else {
+			i = len(line)
+		}
+	}
+	for ; i < len(line); i++ {
+		if (line[i] == ';' || line[i] == '#') && (i == start || isSpaceByte(line[i-1])) {
+			text := bytes.TrimSpace(line[i+1:])
+			comment := string(line[i])
+			if len(text) > 0 {
+				comment += " " + string(text)
+			}
+			return line[:i], comment
+		}
+	}
+	return line, ""
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\v' || c == '\f' || c == '\r'
+}
+
+// contentColumn returns the 1-based column of the first non-whitespace
+// character in line.
+func contentColumn(line []byte) int {
+	return len(line) - len(bytes.TrimLeftFunc(line, unicode.IsSpace)) + 1
+}
+
+// hasLeadingTab reports whether the whitespace at the beginning of line
+// contains a tab.
+func hasLeadingTab(line []byte) bool {
+	for _, c := range line {
+		switch c {
+		case '\t':
+			return true
+		case ' ', '\v', '\f', '\r':
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// collapseSpace replaces each run of whitespace in s with a single space.
+func collapseSpace(s string) string {
+	sb := new(strings.Builder)
+	sb.Grow(len(s))
+	inSpace := false
+	for _, c := range s {
+		if unicode.IsSpace(c) {
+			if !inSpace {
+				sb.WriteByte(' ')
+			}
+			inSpace = true
+			continue
+		}
+		sb.WriteRune(c)
+		inSpace = false
+	}
+	return sb.String()
+}
+
+// parseValue returns the value of a property from its source text, as
+// returned by cleanLine.
+func parseValue(raw string, quotes quoteSyntax, expand bool) string {
+	if quotes == singleAndDoubleQuotes && strings.HasPrefix(raw, "'") {
+		return raw[1 : len(raw)-1]
+	}
+	v := raw
+	if quotes != noQuotes {
+		v = unquote(raw)
+	}
+	if expand {
+		v = expandEnv(v, os.Getenv)
+	}
+	return v
+}
+
 func unquote(v string) string {
 	if !strings.HasPrefix(v, `"`) {
 		return v
@@ -129,6 +873,14 @@ func unquote(v string) string {
 		case 'x':
 			sb.WriteByte(fromHex(v[i+1])<<4 | fromHex(v[i+2]))
 			i += 2
+		case 'u', 'U':
+			n := 4
+			if v[i] == 'U' {
+				n = 8
+			}
+			r, _ := parseHexRune([]byte(v[i+1 : i+1+n]))
+			sb.WriteRune(r)
+			i += n
 		case '"', '\\':
 			sb.WriteByte(v[i])
 		default:
@@ -138,7 +890,9 @@ func unquote(v string) string {
 	return sb.String()
 }
 
-func cleanLine(line []byte) (string, error) {
+// cleanLine normalizes a line and checks its syntax, including the syntax of
+// any quoted value.
+func cleanLine(line []byte, quotes quoteSyntax) (string, error) {
 	line = bytes.TrimSpace(line)
 	if len(line) == 0 {
 		return "", nil
@@ -157,29 +911,38 @@ func cleanLine(line []byte) (string, error) {
 	if line[0] == '[' {
 		// Section name
 		if line[len(line)-1] != ']' {
-			return "", errors.New("missing section closing bracket")
+			return "", &syntaxError{UnclosedSection, "missing section closing bracket"}
 		}
 		name := bytes.TrimSpace(line[1 : len(line)-1])
 		if len(name) == 0 {
-			return "", errors.New("section name missing")
+			return "", &syntaxError{EmptySectionName, "section name missing"}
 		}
 		if bytes.ContainsAny(name, "[]") {
-			return "", errors.New("unexpected brackets in section name")
+			return "", &syntaxError{BracketInSectionName, "unexpected brackets in section name"}
 		}
 		return "[" + string(name) + "]", nil
 	}
 	// Property
 	i := bytes.IndexByte(line, '=')
 	if i == -1 {
-		return "", errors.New("could not find '='")
+		return "", &syntaxError{MissingEquals, "could not find '='"}
 	}
 	k := bytes.TrimRightFunc(line[:i], unicode.IsSpace)
 	v := bytes.TrimLeftFunc(line[i+1:], unicode.IsSpace)
-	if bytes.HasPrefix(v, []byte{'"'}) {
+	if quotes != noQuotes && bytes.HasPrefix(v, []byte{'"'}) {
 		if err := validateQuotedString(v); err != nil {
 			return "", err
 		}
 	}
+	if quotes == singleAndDoubleQuotes && bytes.HasPrefix(v, []byte{'\''}) {
+		end := bytes.IndexByte(v[1:], '\'')
+		if end == -1 {
+			return "", &syntaxError{UnterminatedString, "unterminated string"}
+		}
+		if end+2 != len(v) {
+			return "", &syntaxError{TrailingCharacters, "trailing characters after string"}
+		}
+	}
 	sb := new(strings.Builder)
 	sb.Grow(len(k) + 1 + len(v))
 	sb.Write(k)
@@ -190,37 +953,49 @@ func cleanLine(line []byte) (string, error) {
 
 func validateQuotedString(v []byte) error {
 	if len(v) < 2 {
-		return errors.New("unterminated string")
+		return &syntaxError{UnterminatedString, "unterminated string"}
 	}
 	endsInQuote := bytes.HasSuffix(v, []byte{'"'})
 	v = v[1 : len(v)-1]
 	for i := 0; i < len(v); i++ {
 		if v[i] == '"' {
-			return errors.New("trailing characters after string")
+			return &syntaxError{TrailingCharacters, "trailing characters after string"}
 		}
 		if v[i] != '\\' {
 			continue
 		}
 		if i+1 >= len(v) {
-			return errors.New("unexpected end of string")
+			return &syntaxError{InvalidEscape, "unexpected end of string"}
 		}
 		switch v[i+1] {
 		case 'n', 'r', 't', '\\', '"':
 			i++
 		case 'x':
 			if i+3 >= len(v) {
-				return errors.New("unexpected end of string")
+				return &syntaxError{InvalidEscape, "unexpected end of string"}
 			}
 			if !isHexDigit(v[i+2]) || !isHexDigit(v[i+3]) {
-				return fmt.Errorf("bad hex escape %s", v[i:i+4])
+				return &syntaxError{InvalidEscape, fmt.Sprintf("bad hex escape %s", v[i:i+4])}
 			}
 			i += 3
+		case 'u', 'U':
+			n := 4
+			if v[i+1] == 'U' {
+				n = 8
+			}
+			if i+2+n > len(v) {
+				return &syntaxError{InvalidEscape, "unexpected end of string"}
+			}
+			if _, ok := parseHexRune(v[i+2 : i+2+n]); !ok {
+				return &syntaxError{InvalidEscape, fmt.Sprintf("bad unicode escape %s", v[i:i+2+n])}
+			}
+			i += 1 + n
 		default:
-			return fmt.Errorf("unknown escape %q", v[i+1])
+			return &syntaxError{InvalidEscape, fmt.Sprintf("unknown escape %q", v[i+1])}
 		}
 	}
 	if !endsInQuote {
-		return errors.New("unterminated string")
+		return &syntaxError{UnterminatedString, "unterminated string"}
 	}
 	return nil
 }
@@ -231,6 +1006,19 @@ func isHexDigit(c byte) bool {
 		'A' <= c && c <= 'F'
 }
 
+// parseHexRune parses the hex digits of a \u or \U escape. It reports
+// whether the digits are valid and name a Unicode scalar value.
+func parseHexRune(digits []byte) (rune, bool) {
+	var r rune
+	for _, c := range digits {
+		if !isHexDigit(c) {
+			return 0, false
+		}
+		r = r<<4 | rune(fromHex(c))
+	}
+	return r, utf8.ValidRune(r)
+}
+
 func fromHex(c byte) byte {
 	switch {
 	case '0' <= c && c <= '9':
@@ -244,19 +1032,142 @@ func fromHex(c byte) byte {
 	}
 }
 
+// Directives returns the directives found by Parse in source order. See
+// ParseOptions.DirectivePrefix for details.
+func (f *File) Directives() []Directive {
+	if f == nil || len(f.directives) == 0 {
+		return nil
+	}
+	return append([]Directive(nil), f.directives...)
+}
+
 // Get returns the last value associated with the given key in the given
 // section. Passing an empty section name searches for properties outside
 // any section. If there are no values associated with the key, Get returns
-// the empty string.
+// the empty string. Use Has to distinguish an absent key from a key with an
+// empty value.
+//
+// If the section has no properties with the key, Get returns the last value
+// for the key from the section's defaults, if any, and then from the default
+// section. See SetSectionDefaults and ParseOptions.DefaultSection.
 func (f *File) Get(section, key string) string {
-	v, _ := f.get(section, key)
+	v, _ := f.Lookup(section, key)
 	return v
 }
 
-func (f *File) get(section, key string) (_ string, ok bool) {
+// Lookup is like Get, but also reports whether a value was found. Unlike Get,
+// Lookup distinguishes a key with an empty value, for which it returns
+// ("", true), from an absent key, for which it returns ("", false).
+func (f *File) Lookup(section, key string) (value string, ok bool) {
 	if f == nil {
 		return "", false
 	}
+	if v, ok := f.get(section, key); ok {
+		return v, true
+	}
+	if values := f.defaults[section][key]; len(values) > 0 {
+		return values[len(values)-1], true
+	}
+	if f.inheritsDefaultSection(section) {
+		return f.get(f.defaultSection, key)
+	}
+	return "", false
+}
+
+// GetDefault is like Get, but returns def if no value is found for the key.
+// A key with an empty value is found, so GetDefault returns the empty string
+// for it. See GetIntDefault and related methods for typed values.
+func (f *File) GetDefault(section, key, def string) string {
+	if v, ok := f.Lookup(section, key); ok {
+		return v
+	}
+	return def
+}
+
+// inheritsDefaultSection reports whether the named section inherits the
+// properties of f's default section. See ParseOptions.DefaultSection.
+func (f *File) inheritsDefaultSection(section string) bool {
+	return f.defaultSection != "" && section != "" && section != f.defaultSection
+}
+
+// SetSectionDefaults sets the default values returned by Get and Find for
+// keys that have no properties in the named section. Defaults replace any
+// previously set for the section; passing a nil or empty Section removes them.
+// Defaults are not properties: they are not written by MarshalText, do not
+// affect Sections, Section, or Has, and are not consulted by FileSet methods.
+func (f *File) SetSectionDefaults(name string, defaults Section) {
+	if len(defaults) == 0 {
+		delete(f.defaults, name)
+		return
+	}
+	if f.defaults == nil {
+		f.defaults = make(map[string]Section)
+	}
+	copied := make(Section, len(defaults))
+	for k, v := range defaults {
+		copied[k] = append([]string(nil), v...)
+	}
+	f.defaults[name] = copied
+}
+
+// GetWithFallback returns the last value associated with the given key in the
+// given section. If the section has no such property, GetWithFallback tries
+// each of the fallback sections in order and returns the value from the first
+// one that has the key. A property with an empty value counts as found and
+// stops the search. If no section has the key, GetWithFallback returns the
+// empty string.
+func (f *File) GetWithFallback(section, key string, fallbackSections ...string) string {
+	if v, ok := f.get(section, key); ok {
+		return v
+	}
+	for _, fallback := range fallbackSections {
+		if v, ok := f.get(fallback, key); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// Has reports whether the given section has at least one property with the
+// given key, even if its value is empty. Passing an empty section name
+// searches for properties outside any section.
+func (f *File) Has(section, key string) bool {
+	return f.last(section, key) != nil
+}
+
+// GetTrimmed is like Get, but removes leading and trailing whitespace from
+// the section name and key before matching. This is useful for names that come
+// from user input.
+func (f *File) GetTrimmed(section, key string) string {
+	return f.Get(strings.TrimSpace(section), strings.TrimSpace(key))
+}
+
+// HasTrimmed is like Has, but removes leading and trailing whitespace from
+// the section name and key before matching.
+func (f *File) HasTrimmed(section, key string) bool {
+	return f.Has(strings.TrimSpace(section), strings.TrimSpace(key))
+}
+
+// SetTrimmed is like Set, but removes leading and trailing whitespace from
+// the section name and key first. The value is not trimmed.
+func (f *File) SetTrimmed(section, key, value string) {
+	f.Set(strings.TrimSpace(section), strings.TrimSpace(key), value)
+}
+
+func (f *File) get(section, key string) (_ string, ok bool) {
+	prop := f.last(section, key)
+	if prop == nil {
+		return "", false
+	}
+	return prop.value, true
+}
+
+// last returns the last property with the given key in the given section or
+// nil if there is no such property.
+func (f *File) last(section, key string) *property {
+	if f == nil {
+		return nil
+	}
 	for i := len(f.sections) - 1; i >= 0; i-- {
 		currSection := &f.sections[i]
 		if currSection.name != section {
@@ -265,17 +1176,47 @@ func (f *File) get(section, key string) (_ string, ok bool) {
 		for j := len(currSection.properties) - 1; j >= 0; j-- {
 			currProperty := &currSection.properties[j]
 			if currProperty.key == key {
-				return currProperty.value, true
+				return currProperty
 			}
 		}
 	}
-	return "", false
+	return nil
+}
+
+// RawValue returns the source text of the last value associated with the
+// given key in the given section, as recorded by Parse with
+// ParseOptions.KeepRawValue set. Unlike Get, the text includes any quotes and
+// escape sequences from the source. RawValue returns false if there is no
+// such property or its source text was not recorded, such as when the value
+// was changed after parsing.
+func (f *File) RawValue(section, key string) (string, bool) {
+	prop := f.last(section, key)
+	if prop == nil || !prop.hasRaw {
+		return "", false
+	}
+	return prop.raw, true
 }
 
 // Find returns all the values associated with the given key in the given
 // section. Passing an empty section name searches for properties outside
-// any section.
+// any section. If the section has no properties with the key, Find returns
+// the section's default values for the key, if any, or else the default
+// section's values. See SetSectionDefaults and ParseOptions.DefaultSection.
 func (f *File) Find(section, key string) []string {
+	if f == nil {
+		return nil
+	}
+	values := f.find(section, key)
+	if len(values) == 0 {
+		values = append(values, f.defaults[section][key]...)
+	}
+	if len(values) == 0 && f.inheritsDefaultSection(section) {
+		values = f.find(f.defaultSection, key)
+	}
+	return values
+}
+
+func (f *File) find(section, key string) []string {
 	if f == nil {
 		return nil
 	}
@@ -285,14 +1226,94 @@ func (f *File) Find(section, key string) []string {
 			continue
 		}
 		for _, p := range s.properties {
-			if p.key == key {
-				values = append(values, p.value)
+			if p.key != key {
+				continue
+			}
+			if f.systemd && p.value == "" {
+				// Empty assignments reset the list. See ParseOptions.Systemd.
+				values = values[:0]
+				continue
 			}
+			values = append(values, p.value)
 		}
 	}
 	return values
 }
 
+// Reduce combines all the values associated with the given key in the given
+// section by calling fn with the accumulated result and each value in turn,
+// starting with init. Values are visited in document order, including values
+// in repeated sections. If there are no values, Reduce returns init.
+func (f *File) Reduce(section, key string, init string, fn func(acc, value string) string) string {
+	acc := init
+	for _, v := range f.Find(section, key) {
+		acc = fn(acc, v)
+	}
+	return acc
+}
+
+// MissingFrom returns the properties in f whose section and key have no
+// properties in other, in the order they appear in f. Values are not
+// compared: a key present in both files with different values is not
+// reported. If a missing key has multiple values in f, each is returned.
+func (f *File) MissingFrom(other *File) []Property {
+	if f == nil {
+		return nil
+	}
+	var missing []Property
+	for _, s := range f.sections {
+		for _, prop := range s.properties {
+			if !other.Has(s.name, prop.key) {
+				missing = append(missing, Property{
+					Section: s.name,
+					Key:     prop.key,
+					Value:   prop.value,
+				})
+			}
+		}
+	}
+	return missing
+}
+
+// FindAcrossSections returns every property with the given key in any
+// section, including the global section, in the order they appear in the file.
+func (f *File) FindAcrossSections(key string) []Property {
+	if f == nil {
+		return nil
+	}
+	var props []Property
+	for _, s := range f.sections {
+		for _, prop := range s.properties {
+			if prop.key == key {
+				props = append(props, Property{
+					Section: s.name,
+					Key:     prop.key,
+					Value:   prop.value,
+				})
+			}
+		}
+	}
+	return props
+}
+
+// Walk calls fn for each property in the file in the order they appear,
+// including properties with duplicate keys. Properties in repeated sections
+// with the same name are visited where each section appears in the file, so
+// the section names passed to fn may not be contiguous. If fn returns false,
+// Walk stops. fn must not modify the file.
+func (f *File) Walk(fn func(section, key, value string) bool) {
+	if f == nil {
+		return
+	}
+	for _, s := range f.sections {
+		for _, prop := range s.properties {
+			if !fn(s.name, prop.key, prop.value) {
+				return
+			}
+		}
+	}
+}
+
 // Sections returns the names of sections in a file that have properties set.
 // This will include the empty string if there are properties set outside
 // a section.
@@ -309,6 +1330,54 @@ func (f *File) Sections() map[string]struct{} {
 	return names
 }
 
+// FileStats is a summary of a File's contents returned by File.Stats.
+type FileStats struct {
+	// Sections is the number of distinct section names that have properties
+	// set, including the global section if it has properties.
+	Sections int
+	// Properties is the total number of properties, counting each value of a
+	// repeated key separately.
+	Properties int
+	// MultiValuedKeys is the number of distinct section and key pairs that
+	// have more than one value.
+	MultiValuedKeys int
+	// Comments is the number of comment lines, including any directives.
+	Comments int
+}
+
+// Stats returns counts of the file's contents. A nil file returns the zero
+// value.
+func (f *File) Stats() FileStats {
+	if f == nil {
+		return FileStats{}
+	}
+	type sectionKey struct {
+		section string
+		key     string
+	}
+	var stats FileStats
+	names := make(map[string]struct{})
+	valueCounts := make(map[sectionKey]int)
+	for _, s := range f.sections {
+		stats.Comments += len(s.comments)
+		if len(s.properties) > 0 {
+			names[s.name] = struct{}{}
+		}
+		for _, prop := range s.properties {
+			stats.Comments += len(prop.comments)
+			stats.Properties++
+			k := sectionKey{s.name, prop.key}
+			valueCounts[k]++
+			if valueCounts[k] == 2 {
+				stats.MultiValuedKeys++
+			}
+		}
+	}
+	stats.Comments += len(f.trailingComments)
+	stats.Sections = len(names)
+	return stats
+}
+
 // HasSections reports whether f has any sections with properties set other than
 // the unnamed global section.
 func (f *File) HasSections() bool {
@@ -345,6 +1414,182 @@ func (f *File) Section(name string) Section {
 	return result
 }
 
+// SectionInOrder returns a copy of the properties in the named section in the
+// order they appear in the file, including repeated keys. Properties under
+// repeated headers for the same section are concatenated.
+// SectionInOrder("") returns the global section.
+func (f *File) SectionInOrder(name string) OrderedSection {
+	if f == nil {
+		return nil
+	}
+	var result OrderedSection
+	for _, s := range f.sections {
+		if s.name != name {
+			continue
+		}
+		for _, prop := range s.properties {
+			result = append(result, KeyValue{Key: prop.key, Value: prop.value})
+		}
+	}
+	return result
+}
+
+// Keys returns the distinct keys in the named section in the order they first
+// appear in the file. Properties under repeated headers for the same section
+// are included. Keys("") returns the keys of the global section.
+func (f *File) Keys(section string) []string {
+	if f == nil {
+		return nil
+	}
+	var keys []string
+	seen := make(map[string]struct{})
+	for _, s := range f.sections {
+		if s.name != section {
+			continue
+		}
+		for _, prop := range s.properties {
+			if _, dup := seen[prop.key]; dup {
+				continue
+			}
+			seen[prop.key] = struct{}{}
+			keys = append(keys, prop.key)
+		}
+	}
+	return keys
+}
+
+// AllKeys returns the key of every property in the named section in the order
+// they appear in the file, so a key with multiple values appears multiple
+// times. AllKeys("") returns the keys of the global section.
+func (f *File) AllKeys(section string) []string {
+	if f == nil {
+		return nil
+	}
+	var keys []string
+	for _, s := range f.sections {
+		if s.name != section {
+			continue
+		}
+		for _, prop := range s.properties {
+			keys = append(keys, prop.key)
+		}
+	}
+	return keys
+}
+
+// SplitBySection returns a new File for each section name in f that has
+// properties set, keyed by section name. Each File contains only the
+// properties of its section, in order, with their comments. Multiple sections
+// with the same name are merged into a single section. The global section's
+// properties, if any, are returned under the empty string.
+//
+// If keepHeader is true, each File places its properties under a section
+// header with the same name, preceded by the comments that appeared above
+// the first header. Otherwise, the properties are placed in the File's global
+// section and the header's comments are kept above the first property.
+// Comments above the headers of repeated sections are moved above the
+// first property that follows them. Trailing comments at the end of f are
+// not copied into any File. Each File is marshaled with the same syntax as f,
+// such as the quoting rules of the options f was parsed with.
+func (f *File) SplitBySection(keepHeader bool) map[string]*File {
+	if f == nil {
+		return nil
+	}
+	files := make(map[string]*File)
+	pending := make(map[string][]string)
+	for _, s := range f.sections {
+		if len(s.properties) == 0 {
+			pending[s.name] = append(pending[s.name], s.comments...)
+			continue
+		}
+		split := files[s.name]
+		if split == nil {
+			split = f.emptyCopy()
+			files[s.name] = split
+			if keepHeader && s.name != "" {
+				split.sections = []section{
+					{name: ""},
+					{name: s.name, comments: copyStrings(s.comments)},
+				}
+			} else {
+				split.sections = []section{
+					{name: "", comments: copyStrings(s.comments)},
+				}
+			}
+		} else {
+			pending[s.name] = append(pending[s.name], s.comments...)
+		}
+		dst := &split.sections[len(split.sections)-1]
+		for _, prop := range s.properties {
+			prop.comments = append(pending[s.name], prop.comments...)
+			delete(pending, s.name)
+			dst.properties = append(dst.properties, prop)
+		}
+	}
+	return files
+}
+
+// Clone returns a deep copy of f. Changes to the copy do not affect f and vice
+// versa. Clone returns nil if f is nil.
+func (f *File) Clone() *File {
+	if f == nil {
+		return nil
+	}
+	clone := f.emptyCopy()
+	clone.leadingLines = copyStrings(f.leadingLines)
+	clone.trailingComments = copyStrings(f.trailingComments)
+	clone.trailingBlankLines = f.trailingBlankLines
+	clone.path = f.path
+	clone.saved = f.saved
+	clone.savedSum = f.savedSum
+	clone.hasSavedSum = f.hasSavedSum
+	if len(f.directives) > 0 {
+		clone.directives = append([]Directive(nil), f.directives...)
+	}
+	if len(f.sections) > 0 {
+		clone.sections = make([]section, len(f.sections))
+		for i, s := range f.sections {
+			s.comments = copyStrings(s.comments)
+			props := s.properties
+			s.properties = nil
+			if len(props) > 0 {
+				s.properties = make([]property, len(props))
+				for j, prop := range props {
+					prop.comments = copyStrings(prop.comments)
+					prop.continuation = copyStrings(prop.continuation)
+					s.properties[j] = prop
+				}
+			}
+			clone.sections[i] = s
+		}
+	}
+	for name, defaults := range f.defaults {
+		clone.SetSectionDefaults(name, defaults)
+	}
+	return clone
+}
+
+// emptyCopy returns a new File without any contents that is marshaled with
+// the same syntax as f.
+func (f *File) emptyCopy() *File {
+	return &File{
+		inlineComments:   f.inlineComments,
+		singleQuotes:     f.singleQuotes,
+		lineContinuation: f.lineContinuation,
+		expandEnv:        f.expandEnv,
+		systemd:          f.systemd,
+		defaultSection:   f.defaultSection,
+		hasBlankLines:    f.hasBlankLines,
+	}
+}
+
+func copyStrings(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return append([]string(nil), s...)
+}
+
 // Set sets the property to the given value. If the section name is empty, the
 // property is set outside any section. Set will panic if
 // IsValidSection(sectionName) or IsValidKey(key) report false.
@@ -354,7 +1599,12 @@ func (f *File) Section(name string) Section {
 // earlier in the file will be removed. Otherwise, the property will be appended
 // to the appropriate section, creating a section at the end of the file if
 // necessary.
+//
+// Setting a property to the empty string keeps the property defined (it is
+// written as "key=" by MarshalText and Has reports true for it). Use Delete to
+// remove a property entirely.
 func (f *File) Set(sectionName, key, value string) {
+	f.beforeChange()
 	if !IsValidSection(sectionName) {
 		panic("File.Set invalid section: " + sectionName)
 	}
@@ -383,7 +1633,8 @@ func (f *File) Set(sectionName, key, value string) {
 				currSection.properties[len(currSection.properties)-1] = property{}
 				currSection.properties = currSection.properties[:len(currSection.properties)-1]
 			} else {
-				prop.value = value
+				prop.setValue(value)
+				prop.fromDefault = false
 				wrote = true
 			}
 		}
@@ -410,10 +1661,75 @@ func (f *File) Set(sectionName, key, value string) {
 	})
 }
 
+// RewriteValues calls fn for each property in the file in order and replaces
+// the property's value with fn's return value. Comments and the order of
+// properties are preserved. A common use is redacting secrets before logging
+// a configuration.
+func (f *File) RewriteValues(fn func(section, key, value string) string) {
+	f.beforeChange()
+	for i := range f.sections {
+		s := &f.sections[i]
+		for j := range s.properties {
+			prop := &s.properties[j]
+			if v := fn(s.name, prop.key, prop.value); v != prop.value {
+				prop.setValue(v)
+			}
+		}
+	}
+}
+
+// TrySet is like Set, but returns an *InvalidNameError instead of panicking if
+// the section name or key is not valid.
+func (f *File) TrySet(sectionName, key, value string) error {
+	if err := validateName(sectionName, key); err != nil {
+		return err
+	}
+	f.Set(sectionName, key, value)
+	return nil
+}
+
+// TryAdd is like Add, but returns an *InvalidNameError instead of panicking if
+// the section name or key is not valid.
+func (f *File) TryAdd(sectionName, key string, values []string) error {
+	if err := validateName(sectionName, key); err != nil {
+		return err
+	}
+	f.Add(sectionName, key, values)
+	return nil
+}
+
+// ApplyFragment parses r as a list of properties without section headers and
+// sets each of them in the named section of f as if by Set, so a key in the
+// fragment replaces any existing values for that key. If a key appears more
+// than once in the fragment, the last value wins. Comments in the fragment are
+// discarded. If the fragment contains a section header or cannot be parsed,
+// ApplyFragment returns an error and f is not modified.
+func (f *File) ApplyFragment(section string, r io.Reader, opts *ParseOptions) error {
+	if !IsValidSection(section) {
+		return &InvalidNameError{Section: section}
+	}
+	frag, err := Parse(r, opts)
+	if err != nil {
+		return fmt.Errorf("apply ini fragment: %w", err)
+	}
+	for _, s := range frag.sections {
+		if s.name != "" {
+			return fmt.Errorf("apply ini fragment: section header [%s] not allowed", s.name)
+		}
+	}
+	for _, s := range frag.sections {
+		for _, p := range s.properties {
+			f.Set(section, p.key, p.value)
+		}
+	}
+	return nil
+}
+
 // Delete deletes any property with the given key in sections with the
 // given name. If this causes any sections that do not have comments attached to
 // become empty, then those sections will be removed.
 func (f *File) Delete(sectionName, key string) {
+	f.beforeChange()
 	sectionCount := 0
 	for i := range f.sections {
 		s := &f.sections[i]
@@ -457,6 +1773,7 @@ func (f *File) Delete(sectionName, key string) {
 // If there is no section with the given name, one will be created at the end of
 // the file.
 func (f *File) Add(sectionName, key string, values []string) {
+	f.beforeChange()
 	if !IsValidSection(sectionName) {
 		panic("File.Add invalid section: " + sectionName)
 	}
@@ -495,90 +1812,6 @@ func (f *File) Add(sectionName, key string, values []string) {
 	}
 }
 
-// MarshalText serializes the file in INI format, including comments from the
-// original file.
-func (f *File) MarshalText() ([]byte, error) {
-	if f == nil {
-		return nil, nil
-	}
-	var buf []byte
-	for _, s := range f.sections {
-		if s.name != "" && len(buf) > 0 {
-			buf = append(buf, '\n')
-		}
-		for _, comment := range s.comments {
-			buf = append(buf, comment...)
-			buf = append(buf, '\n')
-		}
-		if s.name != "" {
-			buf = append(buf, '[')
-			buf = append(buf, s.name...)
-			buf = append(buf, "]\n"...)
-		}
-		for _, prop := range s.properties {
-			for _, comment := range prop.comments {
-				buf = append(buf, comment...)
-				buf = append(buf, '\n')
-			}
-			buf = append(buf, prop.key...)
-			buf = append(buf, '=')
-			if shouldQuoteValue(prop.value) {
-				buf = appendQuotedString(buf, prop.value)
-			} else {
-				buf = append(buf, prop.value...)
-			}
-			buf = append(buf, '\n')
-		}
-	}
-	if len(f.trailingComments) > 0 && len(buf) > 0 {
-		buf = append(buf, '\n')
-	}
-	for _, comment := range f.trailingComments {
-		buf = append(buf, comment...)
-		buf = append(buf, '\n')
-	}
-	return buf, nil
-}
-
-func appendQuotedString(dst []byte, v string) []byte {
-	dst = append(dst, '"')
-	for i := 0; i < len(v); i++ {
-		switch c := v[i]; {
-		case c == '\n':
-			dst = append(dst, '\\', 'n')
-		case c == '\r':
-			dst = append(dst, '\\', 'r')
-		case c == '\t':
-			dst = append(dst, '\\', 't')
-		case c == '\\':
-			dst = append(dst, '\\', '\\')
-		case c == '"':
-			dst = append(dst, '\\', '"')
-		case c < ' ' || c == del:
-			const hexDigits = "0123456789abcdef"
-			dst = append(dst, '\\', 'x', hexDigits[c>>4], hexDigits[c&0xf])
-		default:
-			dst = append(dst, c)
-		}
-	}
-	dst = append(dst, '"')
-	return dst
-}
-
-const del = '\x7f'
-
-func shouldQuoteValue(v string) bool {
-	if strings.TrimSpace(v) != v {
-		return true
-	}
-	for _, c := range v {
-		if c == '"' || (c < ' ' || c == del) {
-			return true
-		}
-	}
-	return false
-}
-
 // UnmarshalText parses the INI data with default options, replacing any
 // properties or sections in f.
 func (f *File) UnmarshalText(data []byte) error {
@@ -603,6 +1836,76 @@ func (sect Section) Get(key string) string {
 	return values[len(values)-1]
 }
 
+// An OrderedSection is a list of a section's properties in file order.
+type OrderedSection []KeyValue
+
+// A KeyValue is a single property in an OrderedSection.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// Get returns the last value associated with the given key. If there are no
+// values associated with the key, Get returns the empty string.
+func (sect OrderedSection) Get(key string) string {
+	for i := len(sect) - 1; i >= 0; i-- {
+		if sect[i].Key == key {
+			return sect[i].Value
+		}
+	}
+	return ""
+}
+
+// Section returns the properties as a Section.
+func (sect OrderedSection) Section() Section {
+	if len(sect) == 0 {
+		return nil
+	}
+	result := make(Section)
+	for _, kv := range sect {
+		result[kv.Key] = append(result[kv.Key], kv.Value)
+	}
+	return result
+}
+
+// A Property is a single value in a File along with the name of its section
+// and its key.
+type Property struct {
+	Section string
+	Key     string
+	Value   string
+}
+
+// InvalidNameError is the error returned when a section name or key is
+// rejected by IsValidSection or IsValidKey.
+type InvalidNameError struct {
+	Section string
+	Key     string
+
+	// IsKey is true if Key is invalid and false if Section is invalid.
+	IsKey bool
+}
+
+// Error returns a message describing the invalid name.
+func (e *InvalidNameError) Error() string {
+	if e.IsKey {
+		return fmt.Sprintf("invalid ini key %q", e.Key)
+	}
+	return fmt.Sprintf("invalid ini section name %q", e.Section)
+}
+
+// validateName returns an *InvalidNameError if the section name or key are
+// not valid.
+func validateName(sectionName, key string) error {
+	if !IsValidSection(sectionName) {
+		return &InvalidNameError{Section: sectionName, Key: key}
+	}
+	if !IsValidKey(key) {
+		return &InvalidNameError{Section: sectionName, Key: key, IsKey: true}
+	}
+	return nil
+}
+
 // IsValidSection reports whether a string can be used as a section name in
 // an INI file.
 func IsValidSection(name string) bool {
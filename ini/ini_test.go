@@ -5,6 +5,8 @@ package ini
 
 import (
 	"encoding"
+	"errors"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -26,6 +28,9 @@ func TestNil(t *testing.T) {
 	if got := f.Find("foo", "bar"); len(got) > 0 {
 		t.Errorf("Find(...) = %q; want empty", got)
 	}
+	if f.Has("foo", "bar") {
+		t.Error("Has(...) = true; want false")
+	}
 	if got := f.Sections(); len(got) > 0 {
 		t.Errorf("Sections(...) = %q; want empty", got)
 	}
@@ -35,6 +40,12 @@ func TestNil(t *testing.T) {
 	if got := f.Section("foo"); len(got) > 0 {
 		t.Errorf("Section(...) = %q; want empty", got)
 	}
+	if got := f.CommentTags("foo", "bar"); len(got) > 0 {
+		t.Errorf("CommentTags(...) = %q; want empty", got)
+	}
+	if got := f.Stats(); got != (FileStats{}) {
+		t.Errorf("Stats() = %+v; want zero", got)
+	}
 	if got, err := f.MarshalText(); err != nil {
 		t.Errorf("MarshalText(): %v", err)
 	} else if len(got) > 0 {
@@ -114,6 +125,17 @@ func TestParse(t *testing.T) {
 			},
 			canonical: "FOO=bar\n",
 		},
+		{
+			name:   "EmptyValue",
+			source: "FOO=\nBAR=\"\"\n",
+			want: map[string]Section{
+				"": {
+					"FOO": {""},
+					"BAR": {""},
+				},
+			},
+			canonical: "FOO=\nBAR=\n",
+		},
 		{
 			name:    "SemicolonKey",
 			source:  "FOO;Bar=bar\n",
@@ -302,6 +324,43 @@ func TestParse(t *testing.T) {
 			canonical:   "[foo]\nBAR=baz\n",
 			hasSections: true,
 		},
+		{
+			name:   "KeyInnerWhitespace",
+			source: "foo\t bar=baz\n",
+			want: map[string]Section{
+				"": {
+					"foo\t bar": {"baz"},
+				},
+			},
+			canonical: "foo\t bar=baz\n",
+		},
+		{
+			name:   "CollapseKeyWhitespace/Tab",
+			source: "foo\tbar=baz\n",
+			options: &ParseOptions{
+				CollapseKeyWhitespace: true,
+			},
+			want: map[string]Section{
+				"": {
+					"foo bar": {"baz"},
+				},
+			},
+			canonical: "foo bar=baz\n",
+		},
+		{
+			name:   "CollapseKeyWhitespace/MultipleSpaces",
+			source: "[sect]\nfoo  \t  bar = baz\n",
+			options: &ParseOptions{
+				CollapseKeyWhitespace: true,
+			},
+			want: map[string]Section{
+				"sect": {
+					"foo bar": {"baz"},
+				},
+			},
+			canonical:   "[sect]\nfoo bar=baz\n",
+			hasSections: true,
+		},
 		{
 			name:   "InnerQuote",
 			source: `foo=bar"baz`,
@@ -383,6 +442,36 @@ func TestParse(t *testing.T) {
 			source:  `foo="\xgG"` + "\n",
 			wantErr: true,
 		},
+		{
+			name:   "Quoted/UnicodeEscapes",
+			source: `foo="caf\u00e9 \U0001F600"` + "\n",
+			want: map[string]Section{
+				"": {
+					"foo": {"caf\u00e9 \U0001F600"},
+				},
+			},
+			canonical: "foo=caf\u00e9 \U0001F600\n",
+		},
+		{
+			name:    "Quoted/ShortUnicodeEscape",
+			source:  `foo="\u00e"` + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "Quoted/BadUnicodeDigits",
+			source:  `foo="\u00eg"` + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "Quoted/SurrogateEscape",
+			source:  `foo="\ud800"` + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "Quoted/OutOfRangeEscape",
+			source:  `foo="\U00110000"` + "\n",
+			wantErr: true,
+		},
 		{
 			name:    "Quoted/TripleQuote",
 			source:  `foo="""` + "\n",
@@ -454,6 +543,7 @@ func TestAccess(t *testing.T) {
 		key      string
 		wantGet  string
 		wantFind []string
+		wantHas  bool
 	}{
 		{
 			name:     "Global",
@@ -462,6 +552,7 @@ func TestAccess(t *testing.T) {
 			key:      "FOO",
 			wantGet:  "bar",
 			wantFind: []string{"bar"},
+			wantHas:  true,
 		},
 		{
 			name:     "GlobalDoesNotExist",
@@ -478,6 +569,7 @@ func TestAccess(t *testing.T) {
 			key:      "FOO",
 			wantGet:  "baz",
 			wantFind: []string{"bar", "baz"},
+			wantHas:  true,
 		},
 		{
 			name:     "Section",
@@ -486,6 +578,7 @@ func TestAccess(t *testing.T) {
 			key:      "bar",
 			wantGet:  "baz",
 			wantFind: []string{"baz"},
+			wantHas:  true,
 		},
 		{
 			name: "FirstSection",
@@ -499,6 +592,16 @@ func TestAccess(t *testing.T) {
 			key:      "bar",
 			wantGet:  "baz",
 			wantFind: []string{"baz"},
+			wantHas:  true,
+		},
+		{
+			name:     "EmptyValue",
+			source:   "FOO=\n",
+			section:  "",
+			key:      "FOO",
+			wantGet:  "",
+			wantFind: []string{""},
+			wantHas:  true,
 		},
 	}
 	t.Run("Get", func(t *testing.T) {
@@ -514,6 +617,9 @@ func TestAccess(t *testing.T) {
 				if got := f.Section(test.section).Get(test.key); got != test.wantGet {
 					t.Errorf("f.Section(%q).Get(%q) = %q; want %q", test.section, test.key, got, test.wantGet)
 				}
+				if got := f.Has(test.section, test.key); got != test.wantHas {
+					t.Errorf("f.Has(%q, %q) = %t; want %t", test.section, test.key, got, test.wantHas)
+				}
 			})
 		}
 	})
@@ -537,173 +643,247 @@ func TestAccess(t *testing.T) {
 	})
 }
 
-func TestSet(t *testing.T) {
+func TestRejectTabs(t *testing.T) {
 	tests := []struct {
-		name    string
-		source  string
-		section string
-		key     string
-		value   string
-		want    string
+		name     string
+		source   string
+		wantLine int // 0 for no error
 	}{
 		{
-			name:    "AddToEmpty",
-			section: "",
-			key:     "foo",
-			value:   "bar",
-			want:    "foo=bar\n",
-		},
-		{
-			name:    "AddSectionToEmpty",
-			section: "foo",
-			key:     "bar",
-			value:   "baz",
-			want:    "[foo]\nbar=baz\n",
+			name:   "NoIndentation",
+			source: "[foo]\nbar=baz\tquux\n",
 		},
 		{
-			name:    "Overwrite",
-			source:  "foo=bar\n",
-			section: "",
-			key:     "foo",
-			value:   "xyzzy",
-			want:    "foo=xyzzy\n",
+			name:   "Spaces",
+			source: "  [foo]\n    bar=baz\n  ; comment\n",
 		},
 		{
-			name:    "DeletePrevious",
-			source:  "; Comment 1\nfoo=bar\n; Comment 2\nfoo=baz\n",
-			section: "",
-			key:     "foo",
-			value:   "quux",
-			want:    "; Comment 2\nfoo=quux\n",
+			name:     "TabIndentedProperty",
+			source:   "[foo]\nbar=1\n\tbaz=2\n",
+			wantLine: 3,
 		},
 		{
-			name:    "AddToExistingSection",
-			source:  "foo=bar\n",
-			section: "",
-			key:     "baz",
-			value:   "quux",
-			want:    "foo=bar\nbaz=quux\n",
+			name:     "TabAfterSpaces",
+			source:   "[foo]\n  \tbar=1\n",
+			wantLine: 2,
 		},
 		{
-			name:    "AddGlobalSection",
-			source:  "[foo]\nbar=baz\n",
-			section: "",
-			key:     "global",
-			value:   "world",
-			want:    "global=world\n\n[foo]\nbar=baz\n",
+			name:     "TabIndentedComment",
+			source:   "\t# comment\nfoo=1\n",
+			wantLine: 1,
 		},
 		{
-			name:    "AddNewSection",
-			source:  "[foo]\nbar=baz\n",
-			section: "python",
-			key:     "spam",
-			value:   "eggs",
-			want:    "[foo]\nbar=baz\n\n[python]\nspam=eggs\n",
+			name:     "TabOnlyLine",
+			source:   "foo=1\n\t\nbar=2\n",
+			wantLine: 2,
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			f := new(File)
-			if test.source != "" {
-				var err error
-				f, err = Parse(strings.NewReader(test.source), nil)
+			_, err := Parse(strings.NewReader(test.source), &ParseOptions{RejectTabs: true})
+			if test.wantLine == 0 {
 				if err != nil {
-					t.Fatal(err)
+					t.Error("Parse:", err)
 				}
+				return
 			}
-			f.Set(test.section, test.key, test.value)
-			got, err := f.MarshalText()
-			if err != nil {
-				t.Fatal(err)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Parse error = %v; want *ParseError", err)
 			}
-			if diff := cmp.Diff(test.want, string(got)); diff != "" {
-				t.Errorf("MarshalText (-want +got):\n%s", diff)
+			if parseErr.Line != test.wantLine {
+				t.Errorf("Parse error line = %d; want %d", parseErr.Line, test.wantLine)
+			}
+
+			// Tabs are permitted by default.
+			if _, err := Parse(strings.NewReader(test.source), nil); err != nil {
+				t.Error("Parse without RejectTabs:", err)
 			}
 		})
 	}
 }
 
-func TestAdd(t *testing.T) {
+func TestParseError(t *testing.T) {
+	_, err := Parse(strings.NewReader("foo=1\n[bar\n"), nil)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Parse error = %v; want *ParseError", err)
+	}
+	if parseErr.Line != 2 {
+		t.Errorf("Parse error line = %d; want 2", parseErr.Line)
+	}
+	const want = "parse ini file: line 2: missing section closing bracket"
+	if got := err.Error(); got != want {
+		t.Errorf("err.Error() = %q; want %q", got, want)
+	}
+}
+
+func TestParseErrorKind(t *testing.T) {
 	tests := []struct {
-		name    string
-		source  string
-		section string
-		key     string
-		values  []string
-		want    string
+		source string
+		opts   *ParseOptions
+		line   int
+		column int
+		kind   ParseErrorKind
+		text   string
+	}{
+		{source: "a=1\n\tb=2\n", opts: &ParseOptions{RejectTabs: true}, line: 2, column: 1, kind: TabIndentation, text: "\tb=2"},
+		{source: "  [bar\n", line: 1, column: 3, kind: UnclosedSection, text: "  [bar"},
+		{source: "[ ]\n", line: 1, column: 1, kind: EmptySectionName, text: "[ ]"},
+		{source: "[a[b]\n", line: 1, column: 1, kind: BracketInSectionName, text: "[a[b]"},
+		{source: "bork\n", line: 1, column: 1, kind: MissingEquals, text: "bork"},
+		{source: "a;b=1\n", line: 1, column: 1, kind: InvalidKey, text: "a;b=1"},
+		{source: "a=\"x\n", line: 1, column: 1, kind: UnterminatedString, text: "a=\"x"},
+		{source: "a=\"x\" y\n", line: 1, column: 1, kind: TrailingCharacters, text: "a=\"x\" y"},
+		{source: "a=\"\\q\"\n", line: 1, column: 1, kind: InvalidEscape, text: "a=\"\\q\""},
+		{source: "a=\"\\xZZ\"\n", line: 1, column: 1, kind: InvalidEscape, text: "a=\"\\xZZ\""},
+	}
+	for _, test := range tests {
+		_, err := Parse(strings.NewReader(test.source), test.opts)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Parse(%q) error = %v; want *ParseError", test.source, err)
+			continue
+		}
+		if parseErr.Line != test.line || parseErr.Column != test.column || parseErr.Kind != test.kind || parseErr.Text != test.text {
+			t.Errorf("Parse(%q) error = {Line: %d, Column: %d, Kind: %v, Text: %q}; want {Line: %d, Column: %d, Kind: %v, Text: %q}",
+				test.source, parseErr.Line, parseErr.Column, parseErr.Kind, parseErr.Text,
+				test.line, test.column, test.kind, test.text)
+		}
+
+		d := NewDecoder(strings.NewReader(test.source), test.opts)
+		for err = nil; err == nil; _, err = d.Token() {
+		}
+		if !errors.As(err, &parseErr) || parseErr.Kind != test.kind {
+			t.Errorf("Decoder.Token() for %q error = %v; want *ParseError of kind %v", test.source, err, test.kind)
+		}
+	}
+}
+
+func TestParseErrorKindString(t *testing.T) {
+	if got, want := InvalidKey.String(), "invalid key"; got != want {
+		t.Errorf("InvalidKey.String() = %q; want %q", got, want)
+	}
+	if got, want := ParseErrorKind(100).String(), "ParseErrorKind(100)"; got != want {
+		t.Errorf("ParseErrorKind(100).String() = %q; want %q", got, want)
+	}
+}
+
+func TestDirectives(t *testing.T) {
+	const source = ";!include base.ini\n" +
+		"; A regular comment\n" +
+		"foo=bar\n" +
+		"\n" +
+		"  ;!if  prod  \n" +
+		"[server]\n" +
+		"host=example.com\n" +
+		";!endif\n"
+	const canonical = ";!include base.ini\n" +
+		"; A regular comment\n" +
+		"foo=bar\n" +
+		"\n" +
+		";!if  prod\n" +
+		"[server]\n" +
+		"host=example.com\n" +
+		"\n" +
+		";!endif\n"
+	f, err := Parse(strings.NewReader(source), &ParseOptions{DirectivePrefix: ";!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Directive{
+		{Line: 1, Text: "include base.ini"},
+		{Line: 5, Text: "if  prod"},
+		{Line: 8, Text: "endif"},
+	}
+	if diff := cmp.Diff(want, f.Directives()); diff != "" {
+		t.Errorf("f.Directives() (-want +got):\n%s", diff)
+	}
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(canonical, string(got)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
+
+	t.Run("NonCommentPrefix", func(t *testing.T) {
+		const source = "%include base.ini\nfoo=bar\n"
+		f, err := Parse(strings.NewReader(source), &ParseOptions{DirectivePrefix: "%"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Directive{{Line: 1, Text: "include base.ini"}}
+		if diff := cmp.Diff(want, f.Directives()); diff != "" {
+			t.Errorf("f.Directives() (-want +got):\n%s", diff)
+		}
+		got, err := f.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(source, string(got)); diff != "" {
+			t.Errorf("MarshalText (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		f, err := Parse(strings.NewReader(";!include base.ini\n"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.Directives(); len(got) > 0 {
+			t.Errorf("f.Directives() = %+v; want empty", got)
+		}
+		text, err := f.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(text), "; !include base.ini\n"; got != want {
+			t.Errorf("MarshalText = %q; want %q", got, want)
+		}
+	})
+}
+
+func TestPreserveLeadingLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		lines  int
+		want   string
 	}{
 		{
-			name:    "AddNothing",
-			section: "",
-			key:     "foo",
-			values:  []string{},
-			want:    "",
-		},
-		{
-			name:    "AddToEmpty",
-			section: "",
-			key:     "foo",
-			values:  []string{"bar"},
-			want:    "foo=bar\n",
-		},
-		{
-			name:    "AddMultiple",
-			section: "",
-			key:     "foo",
-			values:  []string{"bar", "baz"},
-			want:    "foo=bar\nfoo=baz\n",
-		},
-		{
-			name:    "AddSectionToEmpty",
-			section: "foo",
-			key:     "bar",
-			values:  []string{"baz"},
-			want:    "[foo]\nbar=baz\n",
-		},
-		{
-			name:    "RetainPrevious",
-			source:  "; Comment 1\nfoo=bar\n; Comment 2\nfoo=baz\n",
-			section: "",
-			key:     "foo",
-			values:  []string{"quux"},
-			want:    "; Comment 1\nfoo=bar\n; Comment 2\nfoo=baz\nfoo=quux\n",
+			name:   "Shebang",
+			source: "#!/usr/bin/env app\n[server]\nhost = example.com\n",
+			lines:  1,
+			want:   "#!/usr/bin/env app\n[server]\nhost=example.com\n",
 		},
 		{
-			name:    "AddToExistingSection",
-			source:  "foo=bar\n",
-			section: "",
-			key:     "baz",
-			values:  []string{"quux"},
-			want:    "foo=bar\nbaz=quux\n",
+			name:   "NotINI",
+			source: "GENERATED FILE -- DO NOT EDIT\n  [[banner]]  \nfoo=bar\n",
+			lines:  2,
+			want:   "GENERATED FILE -- DO NOT EDIT\n  [[banner]]  \nfoo=bar\n",
 		},
 		{
-			name:    "AddGlobalSection",
-			source:  "[foo]\nbar=baz\n",
-			section: "",
-			key:     "global",
-			values:  []string{"world"},
-			want:    "global=world\n\n[foo]\nbar=baz\n",
+			name:   "OnlyLeadingLines",
+			source: "#!/usr/bin/env app\n",
+			lines:  3,
+			want:   "#!/usr/bin/env app\n",
 		},
 		{
-			name:    "AddNewSection",
-			source:  "[foo]\nbar=baz\n",
-			section: "python",
-			key:     "spam",
-			values:  []string{"eggs"},
-			want:    "[foo]\nbar=baz\n\n[python]\nspam=eggs\n",
+			name:   "TrailingComments",
+			source: "#!/usr/bin/env app\n; Comment\n",
+			lines:  1,
+			want:   "#!/usr/bin/env app\n; Comment\n",
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			f := new(File)
-			if test.source != "" {
-				var err error
-				f, err = Parse(strings.NewReader(test.source), nil)
-				if err != nil {
-					t.Fatal(err)
-				}
+			f, err := Parse(strings.NewReader(test.source), &ParseOptions{
+				PreserveLeadingLines: test.lines,
+			})
+			if err != nil {
+				t.Fatal(err)
 			}
-			f.Add(test.section, test.key, test.values)
 			got, err := f.MarshalText()
 			if err != nil {
 				t.Fatal(err)
@@ -713,6 +893,1782 @@ func TestAdd(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("LineNumbers", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("#!/usr/bin/env app\nfoo=bar\nbork\n"), &ParseOptions{
+			PreserveLeadingLines: 1,
+		})
+		if err == nil || !strings.Contains(err.Error(), "line 3") {
+			t.Errorf("Parse(...) error = %v; want error mentioning line 3", err)
+		}
+	})
+}
+
+func TestAllowExport(t *testing.T) {
+	const source = "export FOO=bar\n" +
+		"  export\tBAZ = \"qux quux\"\n" +
+		"PLAIN=1\n" +
+		"export=2\n" +
+		"exported=3\n"
+	f, err := Parse(strings.NewReader(source), &ParseOptions{AllowExport: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Section{
+		"FOO":      {"bar"},
+		"BAZ":      {"qux quux"},
+		"PLAIN":    {"1"},
+		"export":   {"2"},
+		"exported": {"3"},
+	}
+	if diff := cmp.Diff(want, f.Section("")); diff != "" {
+		t.Errorf("f.Section(\"\") (-want +got):\n%s", diff)
+	}
+
+	f.Set("", "FOO", "new")
+	f.Set("", "NEW", "x")
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantText = "export FOO=new\n" +
+		"export BAZ=\"qux quux\"\n" +
+		"PLAIN=1\n" +
+		"export=2\n" +
+		"exported=3\n" +
+		"NEW=x\n"
+	if diff := cmp.Diff(wantText, string(got)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		f, err := Parse(strings.NewReader("export FOO=bar\n"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := f.Get("", "export FOO"), "bar"; got != want {
+			t.Errorf("f.Get(\"\", \"export FOO\") = %q; want %q", got, want)
+		}
+	})
+}
+
+func TestBareKeys(t *testing.T) {
+	const source = "[ui]\n" +
+		"verbose\n" +
+		"  debug ; enabled for now\n" +
+		"username = alice\n"
+	f, err := Parse(strings.NewReader(source), &ParseOptions{
+		AllowBareKeys:       true,
+		AllowInlineComments: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Section{
+		"verbose":  {""},
+		"debug":    {""},
+		"username": {"alice"},
+	}
+	if diff := cmp.Diff(want, f.Section("ui")); diff != "" {
+		t.Errorf("f.Section(\"ui\") (-want +got):\n%s", diff)
+	}
+
+	f.Set("ui", "verbose", "true")
+	f.Set("ui", "quiet", "")
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantText = "[ui]\n" +
+		"verbose=true\n" +
+		"debug ; enabled for now\n" +
+		"username=alice\n" +
+		"quiet=\n"
+	if diff := cmp.Diff(wantText, string(got)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("verbose\n"), nil)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Kind != MissingEquals {
+			t.Errorf("Parse(\"verbose\\n\") error = %v; want MissingEquals", err)
+		}
+	})
+}
+
+func TestSystemd(t *testing.T) {
+	const source = "# Example unit\n" +
+		"[Service]\n" +
+		"ExecStart=/bin/sh -c \"echo \\$HOME\"\\\n" +
+		"    --verbose\n" +
+		"Environment=\"A=1\" \"B=2\"\n" +
+		"ExecStartPre=/bin/one\n" +
+		"ExecStartPre=\n" +
+		"ExecStartPre=/bin/two\n" +
+		"ExecStartPre=/bin/three\n"
+	f, err := Parse(strings.NewReader(source), &ParseOptions{Systemd: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.Get("Service", "ExecStart"), `/bin/sh -c "echo \$HOME" --verbose`; got != want {
+		t.Errorf("f.Get(\"Service\", \"ExecStart\") = %q; want %q", got, want)
+	}
+	if got, want := f.Get("Service", "Environment"), `"A=1" "B=2"`; got != want {
+		t.Errorf("f.Get(\"Service\", \"Environment\") = %q; want %q", got, want)
+	}
+	if diff := cmp.Diff([]string{"/bin/two", "/bin/three"}, f.Find("Service", "ExecStartPre")); diff != "" {
+		t.Errorf("f.Find(\"Service\", \"ExecStartPre\") (-want +got):\n%s", diff)
+	}
+
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(source, string(got)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
+
+	f.Set("Service", "Environment", `"C=3"`)
+	got, err = f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "\nEnvironment=\"C=3\"\n") {
+		t.Errorf("MarshalText after Set = %q; want to contain %q", got, "Environment=\"C=3\"")
+	}
+
+	f.Set("Service", "Environment", "a\nb")
+	if _, err := f.MarshalText(); err == nil {
+		t.Error("MarshalText with multi-line value did not return an error")
+	}
+
+	f.Set("Service", "Environment", `C:\dir\`)
+	if _, err := f.MarshalText(); err == nil {
+		t.Error("MarshalText with trailing backslash did not return an error")
+	}
+
+	// An even number of trailing backslashes does not continue the line.
+	const evenBackslashes = `C:\dir\\`
+	f.Set("Service", "Environment", evenBackslashes)
+	got, err = f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := Parse(strings.NewReader(string(got)), &ParseOptions{Systemd: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := reparsed.Get("Service", "Environment"), evenBackslashes; got != want {
+		t.Errorf("after round trip, Get(\"Service\", \"Environment\") = %q; want %q", got, want)
+	}
+	if got, want := reparsed.Get("Service", "ExecStartPre"), "/bin/three"; got != want {
+		t.Errorf("after round trip, Get(\"Service\", \"ExecStartPre\") = %q; want %q", got, want)
+	}
+}
+
+func TestSingleQuotes(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		opts      ParseOptions
+		wantValue string
+		want      string
+	}{
+		{
+			name:      "Literal",
+			source:    "key = 'a \\n $HOME'\n",
+			opts:      ParseOptions{ExpandEnv: true},
+			wantValue: "a \\n $HOME",
+			want:      "key=a \\n $$HOME\n",
+		},
+		{
+			name:      "Whitespace",
+			source:    "key = '  x  '\n",
+			wantValue: "  x  ",
+			want:      "key=\"  x  \"\n",
+		},
+		{
+			name:      "DoubleQuotes",
+			source:    "key = 'say \"hi\"'\n",
+			wantValue: "say \"hi\"",
+			want:      "key='say \"hi\"'\n",
+		},
+		{
+			name:      "Empty",
+			source:    "key = ''\n",
+			wantValue: "",
+			want:      "key=\n",
+		},
+		{
+			name:      "InlineComment",
+			source:    "key = 'a ; b' ; comment\n",
+			opts:      ParseOptions{AllowInlineComments: true},
+			wantValue: "a ; b",
+			want:      "key=\"a ; b\" ; comment\n",
+		},
+		{
+			name:      "LeadingQuoteInValue",
+			source:    "key = \"'x\"\n",
+			wantValue: "'x",
+			want:      "key=\"'x\"\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := test.opts
+			opts.AllowSingleQuotes = true
+			f, err := Parse(strings.NewReader(test.source), &opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Get("", "key"); got != test.wantValue {
+				t.Errorf("f.Get(\"\", \"key\") = %q; want %q", got, test.wantValue)
+			}
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalText (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		f, err := Parse(strings.NewReader("key = 'x'\n"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := f.Get("", "key"), "'x'"; got != want {
+			t.Errorf("f.Get(\"\", \"key\") = %q; want %q", got, want)
+		}
+	})
+
+	errorTests := []struct {
+		source string
+		kind   ParseErrorKind
+	}{
+		{"key = 'x\n", UnterminatedString},
+		{"key = '\n", UnterminatedString},
+		{"key = 'x'y'\n", TrailingCharacters},
+		{"key = 'x' y\n", TrailingCharacters},
+	}
+	for _, test := range errorTests {
+		_, err := Parse(strings.NewReader(test.source), &ParseOptions{AllowSingleQuotes: true})
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Kind != test.kind {
+			t.Errorf("Parse(%q) error = %v; want *ParseError of kind %v", test.source, err, test.kind)
+		}
+	}
+}
+
+func TestPreserveFormatting(t *testing.T) {
+	const source = "name = \"app\"\n" +
+		"  debug\t=true\n" +
+		"empty =\n" +
+		"\n" +
+		"[ server ]\n" +
+		"host   =   a.example.com\n" +
+		"port= 80\n"
+	f, err := Parse(strings.NewReader(source), &ParseOptions{
+		PreserveFormatting: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(source, string(got)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
+
+	f.Set("server", "port", "8080")
+	f.Set("", "name", " app ")
+	f.Set("server", "timeout", "30s")
+	got, err = f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "name = \" app \"\n" +
+		"  debug\t=true\n" +
+		"empty =\n" +
+		"\n" +
+		"[ server ]\n" +
+		"host   =   a.example.com\n" +
+		"port= 8080\n" +
+		"timeout=30s\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalText after Set (-want +got):\n%s", diff)
+	}
+
+	f.NormalizeQuoting(AlwaysQuoting)
+	got, err = f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "host   =   \"a.example.com\"\n") {
+		t.Errorf("MarshalText after NormalizeQuoting = %q; want host value quoted", got)
+	}
+}
+
+func TestLineContinuation(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		key       string
+		wantValue string
+		want      string
+	}{
+		{
+			name:      "TwoLines",
+			source:    "key = a \\\n    b\nnext = c\n",
+			key:       "key",
+			wantValue: "a b",
+			want:      "key=a \\\n    b\nnext=c\n",
+		},
+		{
+			name:      "ManyLines",
+			source:    "key = one,\\\n  two,\\   \n  three\n",
+			key:       "key",
+			wantValue: "one,two,three",
+			want:      "key=one,\\\n  two,\\\n  three\n",
+		},
+		{
+			name:      "EscapedBackslash",
+			source:    "key = C:\\\\\nnext = c\n",
+			key:       "key",
+			wantValue: "C:\\\\",
+			want:      "key=C:\\\\\nnext=c\n",
+		},
+		{
+			name:      "ContinuedCommentLike",
+			source:    "key = a\\\n; b\n",
+			key:       "key",
+			wantValue: "a; b",
+			want:      "key=a\\\n; b\n",
+		},
+		{
+			name:      "EndOfFile",
+			source:    "key = a\\",
+			key:       "key",
+			wantValue: "a",
+			want:      "key=a\\\n",
+		},
+		{
+			name:      "Quoted",
+			source:    "key = \"a \\\n  b\"\n",
+			key:       "key",
+			wantValue: "a b",
+			want:      "key=\"a \\\n  b\"\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(test.source), &ParseOptions{
+				AllowLineContinuation: true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Get("", test.key); got != test.wantValue {
+				t.Errorf("f.Get(\"\", %q) = %q; want %q", test.key, got, test.wantValue)
+			}
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalText (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		f, err := Parse(strings.NewReader("key = a\\\nb = c\n"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := f.Get("", "key"), "a\\"; got != want {
+			t.Errorf("f.Get(\"\", \"key\") = %q; want %q", got, want)
+		}
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		f, err := Parse(strings.NewReader("key = a \\\n  b\n"), &ParseOptions{
+			AllowLineContinuation: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Set("", "key", "c")
+		got, err := f.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff("key=c\n", string(got)); diff != "" {
+			t.Errorf("MarshalText after Set (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("TrailingBackslash", func(t *testing.T) {
+		opts := &ParseOptions{AllowLineContinuation: true}
+		f, err := Parse(strings.NewReader("0=\"\\\\\"\n"), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Set("", "a", `x\`)
+		f.Set("", "b", "2")
+		got, err := f.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		const want = "0=\"\\\\\"\na=\"x\\\\\"\nb=2\n"
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("MarshalText (-want +got):\n%s", diff)
+		}
+		f2, err := Parse(strings.NewReader(string(got)), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"0", "a", "b"} {
+			if got, want := f2.Get("", key), f.Get("", key); got != want {
+				t.Errorf("after round trip, f.Get(\"\", %q) = %q; want %q", key, got, want)
+			}
+		}
+	})
+
+	t.Run("LineNumbers", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("key = a\\\n  b\nbork\n"), &ParseOptions{
+			AllowLineContinuation: true,
+		})
+		if err == nil || !strings.Contains(err.Error(), "line 3") {
+			t.Errorf("Parse(...) error = %v; want error mentioning line 3", err)
+		}
+	})
+}
+
+func TestInlineComments(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		section   string
+		key       string
+		wantValue string
+		want      string
+	}{
+		{
+			name:      "Semicolon",
+			source:    "key = value ; comment\n",
+			key:       "key",
+			wantValue: "value",
+			want:      "key=value ; comment\n",
+		},
+		{
+			name:      "Hash",
+			source:    "[core]\nbare = true  #   comment  \n",
+			section:   "core",
+			key:       "bare",
+			wantValue: "true",
+			want:      "[core]\nbare=true # comment\n",
+		},
+		{
+			name:      "NoSpaceBeforeMarker",
+			source:    "url = http://example.com/#frag;x\n",
+			key:       "url",
+			wantValue: "http://example.com/#frag;x",
+			want:      "url=http://example.com/#frag;x\n",
+		},
+		{
+			name:      "EmptyValue",
+			source:    "key = ; nothing\n",
+			key:       "key",
+			wantValue: "",
+			want:      "key= ; nothing\n",
+		},
+		{
+			name:      "EmptyComment",
+			source:    "key = value ;\n",
+			key:       "key",
+			wantValue: "value",
+			want:      "key=value ;\n",
+		},
+		{
+			name:      "Quoted",
+			source:    "key = \"a ; b\" ; comment\n",
+			key:       "key",
+			wantValue: "a ; b",
+			want:      "key=\"a ; b\" ; comment\n",
+		},
+		{
+			name:      "QuotedEscape",
+			source:    "key = \"a\\\" ;b\"\n",
+			key:       "key",
+			wantValue: "a\" ;b",
+			want:      "key=\"a\\\" ;b\"\n",
+		},
+		{
+			name:      "CommentLine",
+			source:    "; full line ; comment\nkey=value\n",
+			key:       "key",
+			wantValue: "value",
+			want:      "; full line ; comment\nkey=value\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(test.source), &ParseOptions{
+				AllowInlineComments: true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Get(test.section, test.key); got != test.wantValue {
+				t.Errorf("f.Get(%q, %q) = %q; want %q", test.section, test.key, got, test.wantValue)
+			}
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalText (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		f, err := Parse(strings.NewReader("key = value ; comment\n"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := f.Get("", "key"), "value ; comment"; got != want {
+			t.Errorf("f.Get(\"\", \"key\") = %q; want %q", got, want)
+		}
+	})
+
+	t.Run("SetKeepsComment", func(t *testing.T) {
+		f, err := Parse(strings.NewReader("key = value ; comment\n"), &ParseOptions{
+			AllowInlineComments: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Set("", "key", "x ;y")
+		got, err := f.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		const want = "key=\"x ;y\" ; comment\n"
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("MarshalText after Set (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestGetWithFallback(t *testing.T) {
+	const source = "global=g\n" +
+		"[default]\n" +
+		"host=localhost\n" +
+		"port=8080\n" +
+		"debug=true\n" +
+		"[env:prod]\n" +
+		"host=example.com\n" +
+		"debug=\n"
+	tests := []struct {
+		section   string
+		key       string
+		fallbacks []string
+		want      string
+	}{
+		{section: "env:prod", key: "host", fallbacks: []string{"default"}, want: "example.com"},
+		{section: "env:prod", key: "port", fallbacks: []string{"default"}, want: "8080"},
+		{section: "env:prod", key: "debug", fallbacks: []string{"default"}, want: ""},
+		{section: "env:prod", key: "port", fallbacks: nil, want: ""},
+		{section: "env:prod", key: "global", fallbacks: []string{"default", ""}, want: "g"},
+		{section: "env:dev", key: "host", fallbacks: []string{"env:prod", "default"}, want: "example.com"},
+		{section: "env:prod", key: "missing", fallbacks: []string{"default"}, want: ""},
+	}
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		got := f.GetWithFallback(test.section, test.key, test.fallbacks...)
+		if got != test.want {
+			t.Errorf("f.GetWithFallback(%q, %q, %q...) = %q; want %q", test.section, test.key, test.fallbacks, got, test.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	const source = "global=g\n" +
+		"[server]\n" +
+		"host=localhost\n" +
+		"debug=\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SetSectionDefaults("server", Section{"port": {"8080"}})
+	tests := []struct {
+		section string
+		key     string
+		want    string
+		wantOK  bool
+	}{
+		{section: "", key: "global", want: "g", wantOK: true},
+		{section: "server", key: "host", want: "localhost", wantOK: true},
+		{section: "server", key: "debug", want: "", wantOK: true},
+		{section: "server", key: "port", want: "8080", wantOK: true},
+		{section: "server", key: "missing", want: "", wantOK: false},
+		{section: "missing", key: "host", want: "", wantOK: false},
+	}
+	for _, test := range tests {
+		got, ok := f.Lookup(test.section, test.key)
+		if got != test.want || ok != test.wantOK {
+			t.Errorf("f.Lookup(%q, %q) = %q, %t; want %q, %t", test.section, test.key, got, ok, test.want, test.wantOK)
+		}
+		wantDefault := test.want
+		if !test.wantOK {
+			wantDefault = "def"
+		}
+		if got := f.GetDefault(test.section, test.key, "def"); got != wantDefault {
+			t.Errorf("f.GetDefault(%q, %q, \"def\") = %q; want %q", test.section, test.key, got, wantDefault)
+		}
+	}
+}
+
+func TestTrimmed(t *testing.T) {
+	f, err := Parse(strings.NewReader("top=1\n[foo]\nbar=baz\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		section string
+		key     string
+		want    string
+		wantHas bool
+	}{
+		{section: "foo", key: "bar", want: "baz", wantHas: true},
+		{section: " foo ", key: " bar ", want: "baz", wantHas: true},
+		{section: "\tfoo", key: "bar\n", want: "baz", wantHas: true},
+		{section: "  ", key: " top", want: "1", wantHas: true},
+		{section: "foo", key: " missing ", want: "", wantHas: false},
+	}
+	for _, test := range tests {
+		if got := f.GetTrimmed(test.section, test.key); got != test.want {
+			t.Errorf("f.GetTrimmed(%q, %q) = %q; want %q", test.section, test.key, got, test.want)
+		}
+		if got := f.HasTrimmed(test.section, test.key); got != test.wantHas {
+			t.Errorf("f.HasTrimmed(%q, %q) = %t; want %t", test.section, test.key, got, test.wantHas)
+		}
+	}
+	if got := f.Get(" foo ", " bar "); got != "" {
+		t.Errorf("f.Get(\" foo \", \" bar \") = %q; want \"\"", got)
+	}
+
+	f.SetTrimmed(" foo ", " bar ", " new ")
+	f.SetTrimmed(" new ", "key ", "x")
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "top=1\n\n[foo]\nbar=\" new \"\n\n[new]\nkey=x\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("after SetTrimmed (-want +got):\n%s", diff)
+	}
+}
+
+func TestSectionDefaults(t *testing.T) {
+	const source = "[server]\n" +
+		"host=example.com\n" +
+		"[other]\n" +
+		"port=1\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SetSectionDefaults("server", Section{
+		"host": {"localhost"},
+		"port": {"80", "8080"},
+	})
+
+	tests := []struct {
+		section  string
+		key      string
+		wantGet  string
+		wantFind []string
+	}{
+		{section: "server", key: "host", wantGet: "example.com", wantFind: []string{"example.com"}},
+		{section: "server", key: "port", wantGet: "8080", wantFind: []string{"80", "8080"}},
+		{section: "server", key: "missing", wantGet: "", wantFind: nil},
+		{section: "other", key: "host", wantGet: "", wantFind: nil},
+		{section: "other", key: "port", wantGet: "1", wantFind: []string{"1"}},
+	}
+	for _, test := range tests {
+		if got := f.Get(test.section, test.key); got != test.wantGet {
+			t.Errorf("f.Get(%q, %q) = %q; want %q", test.section, test.key, got, test.wantGet)
+		}
+		got := f.Find(test.section, test.key)
+		if diff := cmp.Diff(test.wantFind, got, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("f.Find(%q, %q) (-want +got):\n%s", test.section, test.key, diff)
+		}
+	}
+	if f.Has("server", "port") {
+		t.Error("f.Has(\"server\", \"port\") = true; want false")
+	}
+	if got, want := (FileSet{f}).Get("server", "port"), ""; got != want {
+		t.Errorf("FileSet{f}.Get(\"server\", \"port\") = %q; want %q", got, want)
+	}
+
+	text, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "[server]\nhost=example.com\n\n[other]\nport=1\n"
+	if diff := cmp.Diff(want, string(text)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
+
+	t.Run("SectionWithoutProperties", func(t *testing.T) {
+		f := new(File)
+		f.SetSectionDefaults("server", Section{"host": {"localhost"}})
+		if got, want := f.Get("server", "host"), "localhost"; got != want {
+			t.Errorf("f.Get(\"server\", \"host\") = %q; want %q", got, want)
+		}
+		if got := f.Sections(); len(got) > 0 {
+			t.Errorf("f.Sections() = %v; want empty", got)
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		f := new(File)
+		f.SetSectionDefaults("server", Section{"host": {"localhost"}})
+		f.SetSectionDefaults("server", nil)
+		if got := f.Get("server", "host"); got != "" {
+			t.Errorf("f.Get(\"server\", \"host\") = %q; want empty", got)
+		}
+	})
+}
+
+func TestDefaultSection(t *testing.T) {
+	const source = "top=1\n" +
+		"[DEFAULT]\n" +
+		"user=root\n" +
+		"port=22\n" +
+		"tags=a\n" +
+		"tags=b\n" +
+		"[web]\n" +
+		"port=80\n" +
+		"[db]\n"
+	f, err := Parse(strings.NewReader(source), &ParseOptions{DefaultSection: "DEFAULT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		section  string
+		key      string
+		wantGet  string
+		wantFind []string
+	}{
+		{"web", "port", "80", []string{"80"}},
+		{"web", "user", "root", []string{"root"}},
+		{"db", "tags", "b", []string{"a", "b"}},
+		{"missing", "port", "22", []string{"22"}},
+		{"DEFAULT", "port", "22", []string{"22"}},
+		{"", "port", "", nil},
+		{"web", "nope", "", nil},
+	}
+	for _, test := range tests {
+		if got := f.Get(test.section, test.key); got != test.wantGet {
+			t.Errorf("f.Get(%q, %q) = %q; want %q", test.section, test.key, got, test.wantGet)
+		}
+		if diff := cmp.Diff(test.wantFind, f.Find(test.section, test.key), cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("f.Find(%q, %q) (-want +got):\n%s", test.section, test.key, diff)
+		}
+	}
+	if f.Has("web", "user") {
+		t.Error("f.Has(\"web\", \"user\") = true; want false")
+	}
+	if got, err := f.GetInt("db", "port"); err != nil || got != 22 {
+		t.Errorf("f.GetInt(\"db\", \"port\") = %d, %v; want 22, <nil>", got, err)
+	}
+
+	f.SetSectionDefaults("web", Section{"user": {"www"}})
+	if got, want := f.Get("web", "user"), "www"; got != want {
+		t.Errorf("after SetSectionDefaults, f.Get(\"web\", \"user\") = %q; want %q", got, want)
+	}
+}
+
+func TestRawValue(t *testing.T) {
+	const source = "plain = hello world \n" +
+		"quoted = \"  tab\\there  \"\n" +
+		"empty =\n" +
+		"[sect]\n" +
+		"hex=\"\\x41\"\n"
+	tests := []struct {
+		section string
+		key     string
+		wantGet string
+		wantRaw string
+		wantOK  bool
+	}{
+		{section: "", key: "plain", wantGet: "hello world", wantRaw: "hello world", wantOK: true},
+		{section: "", key: "quoted", wantGet: "  tab\there  ", wantRaw: `"  tab\there  "`, wantOK: true},
+		{section: "", key: "empty", wantGet: "", wantRaw: "", wantOK: true},
+		{section: "sect", key: "hex", wantGet: "A", wantRaw: `"\x41"`, wantOK: true},
+		{section: "", key: "missing", wantOK: false},
+	}
+
+	f, err := Parse(strings.NewReader(source), &ParseOptions{KeepRawValue: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		if got := f.Get(test.section, test.key); got != test.wantGet {
+			t.Errorf("f.Get(%q, %q) = %q; want %q", test.section, test.key, got, test.wantGet)
+		}
+		if got, ok := f.RawValue(test.section, test.key); got != test.wantRaw || ok != test.wantOK {
+			t.Errorf("f.RawValue(%q, %q) = %q, %t; want %q, %t", test.section, test.key, got, ok, test.wantRaw, test.wantOK)
+		}
+	}
+
+	t.Run("NotRecorded", func(t *testing.T) {
+		f, err := Parse(strings.NewReader(source), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := f.RawValue("", "plain"); ok {
+			t.Errorf("f.RawValue(\"\", \"plain\") = %q, true; want _, false", got)
+		}
+	})
+
+	t.Run("ClearedBySet", func(t *testing.T) {
+		f, err := Parse(strings.NewReader(source), &ParseOptions{KeepRawValue: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Set("", "quoted", "new")
+		if got, ok := f.RawValue("", "quoted"); ok {
+			t.Errorf("after Set, f.RawValue(\"\", \"quoted\") = %q, true; want _, false", got)
+		}
+	})
+}
+
+func TestReduce(t *testing.T) {
+	const source = "[build]\n" +
+		"flag=-v\n" +
+		"jobs=2\n" +
+		"[other]\n" +
+		"flag=-x\n" +
+		"[build]\n" +
+		"flag=-race\n" +
+		"jobs=3\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Concat", func(t *testing.T) {
+		got := f.Reduce("build", "flag", "go test", func(acc, value string) string {
+			return acc + " " + value
+		})
+		if want := "go test -v -race"; got != want {
+			t.Errorf("f.Reduce(\"build\", \"flag\", ...) = %q; want %q", got, want)
+		}
+	})
+
+	t.Run("Sum", func(t *testing.T) {
+		got := f.Reduce("build", "jobs", "0", func(acc, value string) string {
+			a, _ := strconv.Atoi(acc)
+			v, _ := strconv.Atoi(value)
+			return strconv.Itoa(a + v)
+		})
+		if want := "5"; got != want {
+			t.Errorf("f.Reduce(\"build\", \"jobs\", ...) = %q; want %q", got, want)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		got := f.Reduce("build", "missing", "init", func(acc, value string) string {
+			t.Errorf("fn called with %q, %q", acc, value)
+			return acc
+		})
+		if want := "init"; got != want {
+			t.Errorf("f.Reduce(\"build\", \"missing\", ...) = %q; want %q", got, want)
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		got := (*File)(nil).Reduce("build", "flag", "init", func(acc, value string) string {
+			return acc + value
+		})
+		if want := "init"; got != want {
+			t.Errorf("(*File)(nil).Reduce(...) = %q; want %q", got, want)
+		}
+	})
+}
+
+func TestMissingFrom(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want []Property
+	}{
+		{
+			name: "Same",
+			old:  "a=1\n[foo]\nb=2\n",
+			new:  "a=1\n[foo]\nb=2\n",
+			want: nil,
+		},
+		{
+			name: "ValueChanged",
+			old:  "a=1\n",
+			new:  "a=2\n",
+			want: nil,
+		},
+		{
+			name: "DroppedKeys",
+			old: "a=1\nz=26\n" +
+				"[foo]\nb=2\nc=3\n" +
+				"[bar]\nd=4\n" +
+				"[foo]\ne=5\n",
+			new: "a=1\n" +
+				"[foo]\nc=3\n" +
+				"[bar]\nd=4\n",
+			want: []Property{
+				{Section: "", Key: "z", Value: "26"},
+				{Section: "foo", Key: "b", Value: "2"},
+				{Section: "foo", Key: "e", Value: "5"},
+			},
+		},
+		{
+			name: "MovedToOtherSection",
+			old:  "[foo]\nb=2\n",
+			new:  "[bar]\nb=2\n",
+			want: []Property{
+				{Section: "foo", Key: "b", Value: "2"},
+			},
+		},
+		{
+			name: "MultipleValues",
+			old:  "[foo]\nb=1\nb=2\n",
+			new:  "",
+			want: []Property{
+				{Section: "foo", Key: "b", Value: "1"},
+				{Section: "foo", Key: "b", Value: "2"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oldFile, err := Parse(strings.NewReader(test.old), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			newFile, err := Parse(strings.NewReader(test.new), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := oldFile.MissingFrom(newFile)
+			if diff := cmp.Diff(test.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("oldFile.MissingFrom(newFile) (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFindAcrossSections(t *testing.T) {
+	const source = "email=root@example.com\n" +
+		"[user:alice]\n" +
+		"name=Alice\n" +
+		"email=alice@example.com\n" +
+		"[user:bob]\n" +
+		"name=Bob\n" +
+		"[user:carol]\n" +
+		"email=carol@example.com\n" +
+		"email=carol@example.org\n" +
+		"[user:alice]\n" +
+		"email=alice@example.org\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Property{
+		{Section: "", Key: "email", Value: "root@example.com"},
+		{Section: "user:alice", Key: "email", Value: "alice@example.com"},
+		{Section: "user:carol", Key: "email", Value: "carol@example.com"},
+		{Section: "user:carol", Key: "email", Value: "carol@example.org"},
+		{Section: "user:alice", Key: "email", Value: "alice@example.org"},
+	}
+	if diff := cmp.Diff(want, f.FindAcrossSections("email")); diff != "" {
+		t.Errorf("f.FindAcrossSections(\"email\") (-want +got):\n%s", diff)
+	}
+	if got := f.FindAcrossSections("phone"); len(got) > 0 {
+		t.Errorf("f.FindAcrossSections(\"phone\") = %v; want empty", got)
+	}
+	if got := (*File)(nil).FindAcrossSections("email"); got != nil {
+		t.Errorf("(*File)(nil).FindAcrossSections(\"email\") = %v; want nil", got)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	const source = "a=1\n" +
+		"[s1]\n" +
+		"b=2\n" +
+		"b=3\n" +
+		"[s2]\n" +
+		"c=4\n" +
+		"[s1]\n" +
+		"d=5\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Property
+	f.Walk(func(section, key, value string) bool {
+		got = append(got, Property{Section: section, Key: key, Value: value})
+		return true
+	})
+	want := []Property{
+		{Section: "", Key: "a", Value: "1"},
+		{Section: "s1", Key: "b", Value: "2"},
+		{Section: "s1", Key: "b", Value: "3"},
+		{Section: "s2", Key: "c", Value: "4"},
+		{Section: "s1", Key: "d", Value: "5"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Walk visited (-want +got):\n%s", diff)
+	}
+
+	t.Run("Stop", func(t *testing.T) {
+		n := 0
+		f.Walk(func(section, key, value string) bool {
+			n++
+			return n < 3
+		})
+		if n != 3 {
+			t.Errorf("Walk called fn %d times after returning false on call 3", n)
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		(*File)(nil).Walk(func(section, key, value string) bool {
+			t.Error("fn called for nil File")
+			return true
+		})
+	})
+}
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   FileStats
+	}{
+		{
+			name:   "Empty",
+			source: "",
+			want:   FileStats{},
+		},
+		{
+			name:   "OnlyComments",
+			source: "; foo\n# bar\n",
+			want:   FileStats{Comments: 2},
+		},
+		{
+			name: "RepeatedSectionsAndKeys",
+			source: "; Global\n" +
+				"a=1\n" +
+				"a=2\n" +
+				"; Section\n" +
+				"[foo]\n" +
+				"b=1\n" +
+				"[bar]\n" +
+				"; Property\n" +
+				"b=1\n" +
+				"[foo]\n" +
+				"b=2\n" +
+				"b=3\n" +
+				"c=1\n" +
+				"[empty]\n" +
+				"; Trailing\n",
+			want: FileStats{
+				Sections:        3,
+				Properties:      7,
+				MultiValuedKeys: 2,
+				Comments:        4,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(test.source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Stats(); got != test.want {
+				t.Errorf("f.Stats() = %+v; want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSectionInOrder(t *testing.T) {
+	const source = "top=1\n" +
+		"[server]\n" +
+		"host=b\n" +
+		"port=80\n" +
+		"host=a\n" +
+		"[other]\n" +
+		"x=y\n" +
+		"[server]\n" +
+		"host=c\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := f.SectionInOrder("server")
+	want := OrderedSection{
+		{Key: "host", Value: "b"},
+		{Key: "port", Value: "80"},
+		{Key: "host", Value: "a"},
+		{Key: "host", Value: "c"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("f.SectionInOrder(\"server\") (-want +got):\n%s", diff)
+	}
+	if got, want := got.Get("host"), "c"; got != want {
+		t.Errorf("f.SectionInOrder(\"server\").Get(\"host\") = %q; want %q", got, want)
+	}
+	if diff := cmp.Diff(f.Section("server"), got.Section()); diff != "" {
+		t.Errorf("f.SectionInOrder(\"server\").Section() (-f.Section(\"server\") +got):\n%s", diff)
+	}
+	if got := f.SectionInOrder("missing"); got != nil {
+		t.Errorf("f.SectionInOrder(\"missing\") = %v; want <nil>", got)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	const source = "top=1\n" +
+		"[server]\n" +
+		"port=80\n" +
+		"host=b\n" +
+		"port=81\n" +
+		"[other]\n" +
+		"x=y\n" +
+		"[server]\n" +
+		"name=c\n" +
+		"host=a\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		section string
+		keys    []string
+		allKeys []string
+	}{
+		{
+			section: "",
+			keys:    []string{"top"},
+			allKeys: []string{"top"},
+		},
+		{
+			section: "server",
+			keys:    []string{"port", "host", "name"},
+			allKeys: []string{"port", "host", "port", "name", "host"},
+		},
+		{
+			section: "missing",
+			keys:    nil,
+			allKeys: nil,
+		},
+	}
+	for _, test := range tests {
+		if diff := cmp.Diff(test.keys, f.Keys(test.section)); diff != "" {
+			t.Errorf("f.Keys(%q) (-want +got):\n%s", test.section, diff)
+		}
+		if diff := cmp.Diff(test.allKeys, f.AllKeys(test.section)); diff != "" {
+			t.Errorf("f.AllKeys(%q) (-want +got):\n%s", test.section, diff)
+		}
+	}
+}
+
+func TestSplitBySection(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		keepHeader bool
+		want       map[string]string
+	}{
+		{
+			name:   "Empty",
+			source: "",
+			want:   map[string]string{},
+		},
+		{
+			name: "GlobalAndSections",
+			source: "; Global comment\n" +
+				"global=1\n" +
+				"; Section comment\n" +
+				"[foo]\n" +
+				"; Property comment\n" +
+				"bar=baz\n" +
+				"[python]\n" +
+				"spam=eggs\n",
+			want: map[string]string{
+				"":       "; Global comment\nglobal=1\n",
+				"foo":    "; Section comment\n; Property comment\nbar=baz\n",
+				"python": "spam=eggs\n",
+			},
+		},
+		{
+			name: "KeepHeader",
+			source: "global=1\n" +
+				"; Section comment\n" +
+				"[foo]\n" +
+				"; Property comment\n" +
+				"bar=baz\n",
+			keepHeader: true,
+			want: map[string]string{
+				"":    "global=1\n",
+				"foo": "; Section comment\n[foo]\n; Property comment\nbar=baz\n",
+			},
+		},
+		{
+			name: "RepeatedSections",
+			source: "[foo]\n" +
+				"a=1\n" +
+				"[bar]\n" +
+				"b=2\n" +
+				"; Second foo\n" +
+				"[foo]\n" +
+				"c=3\n",
+			keepHeader: true,
+			want: map[string]string{
+				"foo": "[foo]\na=1\n; Second foo\nc=3\n",
+				"bar": "[bar]\nb=2\n",
+			},
+		},
+		{
+			name:   "SkipsEmptySections",
+			source: "[empty]\n[foo]\na=1\n; Trailing\n",
+			want: map[string]string{
+				"foo": "a=1\n",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(test.source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for name, split := range f.SplitBySection(test.keepHeader) {
+				text, err := split.MarshalText()
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[name] = string(text)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("split files (-want +got):\n%s", diff)
+			}
+
+			// Splitting must not modify the original.
+			text, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := Parse(strings.NewReader(test.source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantText, err := want.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(string(wantText), string(text)); diff != "" {
+				t.Errorf("original modified (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("Syntax", func(t *testing.T) {
+		const source = "[s]\n" +
+			"key = \"a ; b\"\n" +
+			"cost = $$5\n"
+		opts := &ParseOptions{AllowInlineComments: true, ExpandEnv: true}
+		f, err := Parse(strings.NewReader(source), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := f.SplitBySection(true)["s"].MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		const want = "[s]\n" +
+			"key=\"a ; b\"\n" +
+			"cost=$$5\n"
+		if diff := cmp.Diff(want, string(text)); diff != "" {
+			t.Errorf("split file (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestClone(t *testing.T) {
+	const source = "; Global\n" +
+		"foo=1\n" +
+		"\n" +
+		"; Servers\n" +
+		"[server]\n" +
+		"; First\n" +
+		"host=a\n" +
+		"host=b\n" +
+		"\n" +
+		"; Trailing\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SetSectionDefaults("server", Section{"port": {"80"}})
+	clone := f.Clone()
+
+	clone.Set("", "foo", "2")
+	clone.Add("server", "host", []string{"c"})
+	clone.SetKeyComment("server", "host", "Changed")
+	clone.SetSectionComment("server", "Changed")
+	clone.SetSectionDefaults("server", Section{"port": {"8080"}})
+	clone.Set("new", "x", "y")
+
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(source, string(got)); diff != "" {
+		t.Errorf("original after modifying clone (-want +got):\n%s", diff)
+	}
+	if got, want := f.Get("server", "port"), "80"; got != want {
+		t.Errorf("f.Get(\"server\", \"port\") = %q; want %q", got, want)
+	}
+	if diff := cmp.Diff([]string{"a", "b", "c"}, clone.Find("server", "host")); diff != "" {
+		t.Errorf("clone.Find(\"server\", \"host\") (-want +got):\n%s", diff)
+	}
+
+	if got := (*File)(nil).Clone(); got != nil {
+		t.Errorf("(*File)(nil).Clone() = %v; want <nil>", got)
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		section string
+		key     string
+		value   string
+		want    string
+	}{
+		{
+			name:    "AddToEmpty",
+			section: "",
+			key:     "foo",
+			value:   "bar",
+			want:    "foo=bar\n",
+		},
+		{
+			name:    "AddSectionToEmpty",
+			section: "foo",
+			key:     "bar",
+			value:   "baz",
+			want:    "[foo]\nbar=baz\n",
+		},
+		{
+			name:    "Overwrite",
+			source:  "foo=bar\n",
+			section: "",
+			key:     "foo",
+			value:   "xyzzy",
+			want:    "foo=xyzzy\n",
+		},
+		{
+			name:    "DeletePrevious",
+			source:  "; Comment 1\nfoo=bar\n; Comment 2\nfoo=baz\n",
+			section: "",
+			key:     "foo",
+			value:   "quux",
+			want:    "; Comment 2\nfoo=quux\n",
+		},
+		{
+			name:    "AddToExistingSection",
+			source:  "foo=bar\n",
+			section: "",
+			key:     "baz",
+			value:   "quux",
+			want:    "foo=bar\nbaz=quux\n",
+		},
+		{
+			name:    "EmptyValue",
+			source:  "foo=bar\n",
+			section: "",
+			key:     "foo",
+			value:   "",
+			want:    "foo=\n",
+		},
+		{
+			name:    "AddGlobalSection",
+			source:  "[foo]\nbar=baz\n",
+			section: "",
+			key:     "global",
+			value:   "world",
+			want:    "global=world\n\n[foo]\nbar=baz\n",
+		},
+		{
+			name:    "AddNewSection",
+			source:  "[foo]\nbar=baz\n",
+			section: "python",
+			key:     "spam",
+			value:   "eggs",
+			want:    "[foo]\nbar=baz\n\n[python]\nspam=eggs\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := new(File)
+			if test.source != "" {
+				var err error
+				f, err = Parse(strings.NewReader(test.source), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			f.Set(test.section, test.key, test.value)
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalText (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTrySetAndTryAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		section string
+		key     string
+		wantErr *InvalidNameError
+		want    string
+	}{
+		{
+			name:    "Valid",
+			section: "foo",
+			key:     "bar",
+			want:    "[foo]\nbar=baz\n",
+		},
+		{
+			name:    "InvalidSection",
+			section: "[foo]",
+			key:     "bar",
+			wantErr: &InvalidNameError{Section: "[foo]", Key: "bar"},
+		},
+		{
+			name:    "InvalidKey",
+			section: "foo",
+			key:     "bar=",
+			wantErr: &InvalidNameError{Section: "foo", Key: "bar=", IsKey: true},
+		},
+		{
+			name:    "EmptyKey",
+			section: "",
+			key:     "",
+			wantErr: &InvalidNameError{Section: "", Key: "", IsKey: true},
+		},
+	}
+	ops := []struct {
+		name string
+		call func(f *File, section, key string) error
+	}{
+		{
+			name: "TrySet",
+			call: func(f *File, section, key string) error {
+				return f.TrySet(section, key, "baz")
+			},
+		},
+		{
+			name: "TryAdd",
+			call: func(f *File, section, key string) error {
+				return f.TryAdd(section, key, []string{"baz"})
+			},
+		},
+	}
+	for _, op := range ops {
+		t.Run(op.name, func(t *testing.T) {
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					f := new(File)
+					err := op.call(f, test.section, test.key)
+					if test.wantErr == nil {
+						if err != nil {
+							t.Errorf("%s(%q, %q, ...) = %v; want <nil>", op.name, test.section, test.key, err)
+						}
+					} else {
+						var nameErr *InvalidNameError
+						if !errors.As(err, &nameErr) {
+							t.Fatalf("%s(%q, %q, ...) = %v; want *InvalidNameError", op.name, test.section, test.key, err)
+						}
+						if diff := cmp.Diff(test.wantErr, nameErr); diff != "" {
+							t.Errorf("%s(%q, %q, ...) error (-want +got):\n%s", op.name, test.section, test.key, diff)
+						}
+					}
+					got, err := f.MarshalText()
+					if err != nil {
+						t.Fatal(err)
+					}
+					if diff := cmp.Diff(test.want, string(got)); diff != "" {
+						t.Errorf("MarshalText (-want +got):\n%s", diff)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		section string
+		key     string
+		values  []string
+		want    string
+	}{
+		{
+			name:    "AddNothing",
+			section: "",
+			key:     "foo",
+			values:  []string{},
+			want:    "",
+		},
+		{
+			name:    "AddToEmpty",
+			section: "",
+			key:     "foo",
+			values:  []string{"bar"},
+			want:    "foo=bar\n",
+		},
+		{
+			name:    "AddMultiple",
+			section: "",
+			key:     "foo",
+			values:  []string{"bar", "baz"},
+			want:    "foo=bar\nfoo=baz\n",
+		},
+		{
+			name:    "AddSectionToEmpty",
+			section: "foo",
+			key:     "bar",
+			values:  []string{"baz"},
+			want:    "[foo]\nbar=baz\n",
+		},
+		{
+			name:    "RetainPrevious",
+			source:  "; Comment 1\nfoo=bar\n; Comment 2\nfoo=baz\n",
+			section: "",
+			key:     "foo",
+			values:  []string{"quux"},
+			want:    "; Comment 1\nfoo=bar\n; Comment 2\nfoo=baz\nfoo=quux\n",
+		},
+		{
+			name:    "AddToExistingSection",
+			source:  "foo=bar\n",
+			section: "",
+			key:     "baz",
+			values:  []string{"quux"},
+			want:    "foo=bar\nbaz=quux\n",
+		},
+		{
+			name:    "AddGlobalSection",
+			source:  "[foo]\nbar=baz\n",
+			section: "",
+			key:     "global",
+			values:  []string{"world"},
+			want:    "global=world\n\n[foo]\nbar=baz\n",
+		},
+		{
+			name:    "AddNewSection",
+			source:  "[foo]\nbar=baz\n",
+			section: "python",
+			key:     "spam",
+			values:  []string{"eggs"},
+			want:    "[foo]\nbar=baz\n\n[python]\nspam=eggs\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := new(File)
+			if test.source != "" {
+				var err error
+				f, err = Parse(strings.NewReader(test.source), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			f.Add(test.section, test.key, test.values)
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalText (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyFragment(t *testing.T) {
+	const source = "top=1\n" +
+		"[build]\n" +
+		"; Command comment\n" +
+		"cmd=make\n" +
+		"tags=a\n" +
+		"tags=b\n" +
+		"[test]\n" +
+		"cmd=make test\n"
+
+	t.Run("Valid", func(t *testing.T) {
+		f, err := Parse(strings.NewReader(source), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		const fragment = "# Fragment comment\n" +
+			"cmd = go build\n" +
+			"tags=c\n" +
+			"env=\"x y\"\n" +
+			"env=z\n"
+		if err := f.ApplyFragment("build", strings.NewReader(fragment), nil); err != nil {
+			t.Fatal("ApplyFragment:", err)
+		}
+		got, err := f.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		const want = "top=1\n" +
+			"\n[build]\n" +
+			"; Command comment\n" +
+			"cmd=go build\n" +
+			"tags=c\n" +
+			"env=z\n" +
+			"\n[test]\n" +
+			"cmd=make test\n"
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("after ApplyFragment (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("NewSection", func(t *testing.T) {
+		f, err := Parse(strings.NewReader(source), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.ApplyFragment("deploy", strings.NewReader("target=prod\n"), nil); err != nil {
+			t.Fatal("ApplyFragment:", err)
+		}
+		if got, want := f.Get("deploy", "target"), "prod"; got != want {
+			t.Errorf("f.Get(\"deploy\", \"target\") = %q; want %q", got, want)
+		}
+	})
+
+	badFragments := []struct {
+		name     string
+		section  string
+		fragment string
+	}{
+		{name: "SectionHeader", section: "build", fragment: "cmd=go build\n[test]\ncmd=go test\n"},
+		{name: "ParseError", section: "build", fragment: "cmd\n"},
+		{name: "InvalidSection", section: "[build]", fragment: "cmd=go build\n"},
+	}
+	for _, test := range badFragments {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := f.ApplyFragment(test.section, strings.NewReader(test.fragment), nil); err == nil {
+				t.Errorf("f.ApplyFragment(%q, %q, nil) did not return an error", test.section, test.fragment)
+			}
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(string(want), string(got)); diff != "" {
+				t.Errorf("file modified (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRewriteValues(t *testing.T) {
+	const source = "; Global comment\n" +
+		"user=alice\n" +
+		"[db]\n" +
+		"; The password\n" +
+		"password=hunter2\n" +
+		"password=swordfish\n" +
+		"[cache]\n" +
+		"password=\n" +
+		"; Trailing comment\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var visited []Property
+	f.RewriteValues(func(section, key, value string) string {
+		visited = append(visited, Property{Section: section, Key: key, Value: value})
+		if key == "password" {
+			return "REDACTED"
+		}
+		return value
+	})
+	wantVisited := []Property{
+		{Section: "", Key: "user", Value: "alice"},
+		{Section: "db", Key: "password", Value: "hunter2"},
+		{Section: "db", Key: "password", Value: "swordfish"},
+		{Section: "cache", Key: "password", Value: ""},
+	}
+	if diff := cmp.Diff(wantVisited, visited); diff != "" {
+		t.Errorf("visited properties (-want +got):\n%s", diff)
+	}
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "; Global comment\n" +
+		"user=alice\n" +
+		"\n" +
+		"[db]\n" +
+		"; The password\n" +
+		"password=REDACTED\n" +
+		"password=REDACTED\n" +
+		"\n" +
+		"[cache]\n" +
+		"password=REDACTED\n" +
+		"\n" +
+		"; Trailing comment\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
 }
 
 func TestDelete(t *testing.T) {
@@ -0,0 +1,82 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+// InsertBefore adds a property with the given key and value directly before
+// the first property with anchorKey in the given section, so that related keys
+// can be kept together. Comments attached to the anchor property stay with
+// it. Like Add, InsertBefore does not remove other properties with the same
+// key. InsertBefore reports whether the anchor property exists; if it does
+// not, the file is not modified. InsertBefore will panic if
+// IsValidSection(section) or IsValidKey(key) reports false.
+func (f *File) InsertBefore(section, anchorKey, key, value string) bool {
+	f.beforeChange()
+	if !IsValidSection(section) {
+		panic("File.InsertBefore invalid section: " + section)
+	}
+	if !IsValidKey(key) {
+		panic("File.InsertBefore invalid key: " + key)
+	}
+	for i := range f.sections {
+		s := &f.sections[i]
+		if s.name != section {
+			continue
+		}
+		for j := range s.properties {
+			if s.properties[j].key == anchorKey {
+				prop := property{key: key, value: value}
+				if anchor := &s.properties[j]; anchor.hasBlankLines {
+					// Keep the blank lines above the pair rather than between them.
+					prop.blankLines, prop.hasBlankLines = anchor.blankLines, true
+					anchor.blankLines = 0
+				}
+				s.insertProperty(j, prop)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InsertAfter adds a property with the given key and value directly after
+// the last property with anchorKey in the given section, so that related keys
+// can be kept together. Like Add, InsertAfter does not remove other
+// properties with the same key. InsertAfter reports whether the anchor
+// property exists; if it does not, the file is not modified. InsertAfter will
+// panic if IsValidSection(section) or IsValidKey(key) reports false.
+func (f *File) InsertAfter(section, anchorKey, key, value string) bool {
+	f.beforeChange()
+	if !IsValidSection(section) {
+		panic("File.InsertAfter invalid section: " + section)
+	}
+	if !IsValidKey(key) {
+		panic("File.InsertAfter invalid key: " + key)
+	}
+	for i := len(f.sections) - 1; i >= 0; i-- {
+		s := &f.sections[i]
+		if s.name != section {
+			continue
+		}
+		for j := len(s.properties) - 1; j >= 0; j-- {
+			if s.properties[j].key == anchorKey {
+				prop := property{
+					key:   key,
+					value: value,
+					// Written directly after the anchor if its spacing was recorded.
+					hasBlankLines: s.properties[j].hasBlankLines,
+				}
+				s.insertProperty(j+1, prop)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// insertProperty inserts prop into the section's properties at index i.
+func (s *section) insertProperty(i int, prop property) {
+	s.properties = append(s.properties, property{})
+	copy(s.properties[i+1:], s.properties[i:])
+	s.properties[i] = prop
+}
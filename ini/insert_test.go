@@ -0,0 +1,154 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInsert(t *testing.T) {
+	const source = "top=1\n" +
+		"[server]\n" +
+		"; The host\n" +
+		"host=localhost\n" +
+		"debug=false\n" +
+		"[other]\n" +
+		"x=y\n" +
+		"[server]\n" +
+		"host=example.com\n"
+	tests := []struct {
+		name   string
+		after  bool
+		anchor string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "Before",
+			anchor: "host",
+			want: "top=1\n" +
+				"\n" +
+				"[server]\n" +
+				"port=80\n" +
+				"; The host\n" +
+				"host=localhost\n" +
+				"debug=false\n" +
+				"\n" +
+				"[other]\n" +
+				"x=y\n" +
+				"\n" +
+				"[server]\n" +
+				"host=example.com\n",
+			wantOK: true,
+		},
+		{
+			name:   "After",
+			after:  true,
+			anchor: "host",
+			want: "top=1\n" +
+				"\n" +
+				"[server]\n" +
+				"; The host\n" +
+				"host=localhost\n" +
+				"debug=false\n" +
+				"\n" +
+				"[other]\n" +
+				"x=y\n" +
+				"\n" +
+				"[server]\n" +
+				"host=example.com\n" +
+				"port=80\n",
+			wantOK: true,
+		},
+		{
+			name:   "AfterFirstSection",
+			after:  true,
+			anchor: "debug",
+			want: "top=1\n" +
+				"\n" +
+				"[server]\n" +
+				"; The host\n" +
+				"host=localhost\n" +
+				"debug=false\n" +
+				"port=80\n" +
+				"\n" +
+				"[other]\n" +
+				"x=y\n" +
+				"\n" +
+				"[server]\n" +
+				"host=example.com\n",
+			wantOK: true,
+		},
+		{
+			name:   "MissingAnchor",
+			anchor: "missing",
+			want: "top=1\n" +
+				"\n" +
+				"[server]\n" +
+				"; The host\n" +
+				"host=localhost\n" +
+				"debug=false\n" +
+				"\n" +
+				"[other]\n" +
+				"x=y\n" +
+				"\n" +
+				"[server]\n" +
+				"host=example.com\n",
+			wantOK: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ok bool
+			if test.after {
+				ok = f.InsertAfter("server", test.anchor, "port", "80")
+			} else {
+				ok = f.InsertBefore("server", test.anchor, "port", "80")
+			}
+			if ok != test.wantOK {
+				t.Errorf("ok = %t; want %t", ok, test.wantOK)
+			}
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalText (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInsertPreservesBlankLines(t *testing.T) {
+	const source = "[server]\n" +
+		"debug=false\n" +
+		"\n" +
+		"host=localhost\n"
+	f, err := Parse(strings.NewReader(source), &ParseOptions{PreserveBlankLines: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.InsertBefore("server", "host", "scheme", "http")
+	f.InsertAfter("server", "host", "port", "80")
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "[server]\n" +
+		"debug=false\n" +
+		"\n" +
+		"scheme=http\n" +
+		"host=localhost\n" +
+		"port=80\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
+}
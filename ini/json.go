@@ -0,0 +1,79 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MarshalJSON returns the properties of f as a JSON object that maps each
+// section name to an object mapping each key to an array of its values:
+//
+//	{"": {"top": ["1"]}, "server": {"host": ["a", "b"]}}
+//
+// The object holds the same data as Map. The global section is keyed by the
+// empty string and, like any other section, is omitted if it has no
+// properties. Every value of a repeated key is included in file order, so the
+// last element of each array is the value returned by Get. Sections that
+// appear under more than one header are combined into a single object.
+// Comments, formatting, the order of sections and keys, and defaults set by
+// SetSectionDefaults are not included.
+//
+// Since File has no exported fields, encoding/json encoded every File as {}
+// before File implemented json.Marshaler.
+func (f *File) MarshalJSON() ([]byte, error) {
+	m := f.Map()
+	if m == nil {
+		m = make(map[string]map[string][]string)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("marshal ini file to json: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalJSON replaces the properties and sections in f with the ones in
+// the JSON object, which is in the form returned by MarshalJSON. As a
+// convenience, a key's value may also be a single JSON string instead of an
+// array. Properties are ordered as by FromMap. UnmarshalJSON returns an error
+// if any section name or key is not valid.
+func (f *File) UnmarshalJSON(data []byte) error {
+	var sections map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return fmt.Errorf("unmarshal ini file from json: %w", err)
+	}
+	m := make(map[string]map[string][]string, len(sections))
+	for name, sect := range sections {
+		m[name] = make(map[string][]string, len(sect))
+		for key, data := range sect {
+			values, err := unmarshalJSONValues(data)
+			if err != nil {
+				return fmt.Errorf("unmarshal ini file from json: [%s] %s: %w", name, key, err)
+			}
+			m[name][key] = values
+		}
+	}
+	parsed, err := FromMap(m)
+	if err != nil {
+		return fmt.Errorf("unmarshal ini file from json: %w", err)
+	}
+	*f = *parsed
+	return nil
+}
+
+// unmarshalJSONValues decodes a JSON string or array of strings.
+func unmarshalJSONValues(data json.RawMessage) ([]string, error) {
+	var values []string
+	if err := json.Unmarshal(data, &values); err == nil {
+		return values, nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, errors.New("value is not a string or array of strings")
+	}
+	return []string{value}, nil
+}
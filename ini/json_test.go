@@ -0,0 +1,147 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	const source = "; Comment\n" +
+		"top=1\n" +
+		"[server]\n" +
+		"host=a\n" +
+		"host=b\n" +
+		"[empty]\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"":{"top":["1"]},"server":{"host":["a","b"]}}`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("json.Marshal(f) (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Empty",
+			json: `{}`,
+			want: "",
+		},
+		{
+			name: "Sorted",
+			json: `{"server": {"port": ["80"], "host": ["a", "b"]}, "": {"top": ["1"]}, "auth": {"user": "alice", "none": null}}`,
+			want: "top=1\n" +
+				"\n" +
+				"[auth]\n" +
+				"user=alice\n" +
+				"\n" +
+				"[server]\n" +
+				"host=a\n" +
+				"host=b\n" +
+				"port=80\n",
+		},
+		{
+			name:    "InvalidKey",
+			json:    `{"server": {"[host": ["a"]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "InvalidValue",
+			json:    `{"server": {"port": 80}}`,
+			wantErr: true,
+		},
+		{
+			name:    "NotObject",
+			json:    `["a"]`,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader("old=value\n"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = json.Unmarshal([]byte(test.json), f)
+			if err != nil {
+				if !test.wantErr {
+					t.Fatal("json.Unmarshal:", err)
+				}
+				return
+			}
+			if test.wantErr {
+				t.Fatal("json.Unmarshal did not return an error")
+			}
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalText (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("InvalidNameError", func(t *testing.T) {
+		err := new(File).UnmarshalJSON([]byte(`{"": {"a=b": ["c"]}}`))
+		var nameErr *InvalidNameError
+		if !errors.As(err, &nameErr) || nameErr.Key != "a=b" {
+			t.Errorf("UnmarshalJSON error = %v; want *InvalidNameError for key \"a=b\"", err)
+		}
+	})
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	const source = "top=1\n" +
+		"top=2\n" +
+		"[server]\n" +
+		"host=a\n" +
+		"[client]\n" +
+		"port=80\n" +
+		"[server]\n" +
+		"host=b\n" +
+		"port=\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantJSON = `{"":{"top":["1","2"]},"client":{"port":["80"]},"server":{"host":["a","b"],"port":[""]}}`
+	if diff := cmp.Diff(wantJSON, string(data)); diff != "" {
+		t.Errorf("json.Marshal(f) (-want +got):\n%s", diff)
+	}
+	g := new(File)
+	if err := json.Unmarshal(data, g); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(f.Map(), g.Map()); diff != "" {
+		t.Errorf("Map() after round trip (-want +got):\n%s", diff)
+	}
+	if got, want := g.Get("", "top"), "2"; got != want {
+		t.Errorf("g.Get(\"\", \"top\") = %q; want %q", got, want)
+	}
+	if got, want := g.Get("server", "host"), "b"; got != want {
+		t.Errorf("g.Get(\"server\", \"host\") = %q; want %q", got, want)
+	}
+}
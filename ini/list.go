@@ -0,0 +1,133 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// GetList returns the last value associated with the given key in the given
+// section split into a list of elements at each occurrence of sep, like
+// "a:b:c" with a separator of ":". Whitespace around each element is removed.
+// An element may be written in double quotes, using the same escape sequences
+// as property values, to include the separator or surrounding whitespace, as
+// in `"a,b", c`. If sep consists only of whitespace, elements are separated
+// by runs of whitespace instead.
+//
+// If the key is absent or its value is empty or only whitespace, GetList
+// returns an empty list. If a quoted element is malformed, GetList returns an
+// error. GetList will panic if sep is empty.
+func (f *File) GetList(section, key, sep string) ([]string, error) {
+	if sep == "" {
+		panic("File.GetList empty separator")
+	}
+	list, err := splitList(f.Get(section, key), sep)
+	if err != nil {
+		return nil, fmt.Errorf("parse ini value %s: %w", propertyName(section, key), err)
+	}
+	return list, nil
+}
+
+// SetList sets the value of the given key in the given section to the
+// elements of list joined by sep, quoting any element that GetList would not
+// otherwise read back the same way, such as an element that contains sep. An
+// empty list is written as an empty value. SetList will panic if sep is empty,
+// IsValidSection(section) reports false, or IsValidKey(key) reports false.
+func (f *File) SetList(section, key string, list []string, sep string) {
+	if sep == "" {
+		panic("File.SetList empty separator")
+	}
+	f.Set(section, key, joinList(list, sep))
+}
+
+// splitList splits v into the elements of a list as described in GetList.
+func splitList(v, sep string) ([]string, error) {
+	fields := strings.TrimSpace(sep) == ""
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+	var list []string
+	for {
+		var elem string
+		switch {
+		case strings.HasPrefix(v, `"`):
+			end := quotedElementEnd(v)
+			if err := validateQuotedString([]byte(v[:end])); err != nil {
+				return nil, fmt.Errorf("list element %d: %w", len(list), err)
+			}
+			elem, v = unquote(v[:end]), v[end:]
+			if !fields {
+				v = strings.TrimLeftFunc(v, unicode.IsSpace)
+			}
+			if v != "" && !strings.HasPrefix(v, sep) && !(fields && startsWithSpace(v)) {
+				return nil, fmt.Errorf("list element %d: %w", len(list),
+					&syntaxError{TrailingCharacters, "trailing characters after string"})
+			}
+		case fields:
+			i := strings.IndexFunc(v, unicode.IsSpace)
+			if i == -1 {
+				i = len(v)
+			}
+			elem, v = v[:i], v[i:]
+		default:
+			i := strings.Index(v, sep)
+			if i == -1 {
+				i = len(v)
+			}
+			elem, v = strings.TrimSpace(v[:i]), v[i:]
+		}
+		list = append(list, elem)
+		if v == "" {
+			return list, nil
+		}
+		if fields {
+			v = strings.TrimLeftFunc(v, unicode.IsSpace)
+		} else {
+			v = strings.TrimLeftFunc(v[len(sep):], unicode.IsSpace)
+		}
+	}
+}
+
+// quotedElementEnd returns the index just past the closing quote of the
+// double-quoted string at the start of v, or len(v) if it is unterminated.
+func quotedElementEnd(v string) int {
+	for i := 1; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(v)
+}
+
+func startsWithSpace(v string) bool {
+	return strings.IndexFunc(v, unicode.IsSpace) == 0
+}
+
+// joinList joins the elements of list with sep as described in SetList.
+func joinList(list []string, sep string) string {
+	fields := strings.TrimSpace(sep) == ""
+	var buf []byte
+	for i, elem := range list {
+		if i > 0 {
+			buf = append(buf, sep...)
+		}
+		mustQuote := elem == "" ||
+			strings.Contains(elem, sep) ||
+			strings.HasPrefix(elem, `"`) ||
+			strings.TrimSpace(elem) != elem ||
+			fields && strings.IndexFunc(elem, unicode.IsSpace) != -1
+		if mustQuote {
+			buf = appendQuotedString(buf, elem, rawNonASCII)
+		} else {
+			buf = append(buf, elem...)
+		}
+	}
+	return string(buf)
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestGetList(t *testing.T) {
+	tests := []struct {
+		value   string
+		sep     string
+		want    []string
+		wantErr bool
+	}{
+		{value: "", sep: ":", want: nil},
+		{value: "/usr/bin:/bin", sep: ":", want: []string{"/usr/bin", "/bin"}},
+		{value: "a, b ,c", sep: ",", want: []string{"a", "b", "c"}},
+		{value: "a,,b,", sep: ",", want: []string{"a", "", "b", ""}},
+		{value: `"a,b", c`, sep: ",", want: []string{"a,b", "c"}},
+		{value: `" padded ",x`, sep: ",", want: []string{" padded ", "x"}},
+		{value: `"say \"hi\""`, sep: ",", want: []string{`say "hi"`}},
+		{value: "a :: b", sep: "::", want: []string{"a", "b"}},
+		{value: "a  b\tc", sep: " ", want: []string{"a", "b", "c"}},
+		{value: `"a b" c`, sep: " ", want: []string{"a b", "c"}},
+		{value: `"a,b`, sep: ",", wantErr: true},
+		{value: `"a"b,c`, sep: ",", wantErr: true},
+		{value: `"a\q"`, sep: ",", wantErr: true},
+	}
+	for _, test := range tests {
+		f := new(File)
+		f.Set("", "list", test.value)
+		got, err := f.GetList("", "list", test.sep)
+		if err != nil {
+			if !test.wantErr {
+				t.Errorf("GetList for %q with separator %q: %v", test.value, test.sep, err)
+			}
+			continue
+		}
+		if test.wantErr {
+			t.Errorf("GetList for %q with separator %q = %q, <nil>; want error", test.value, test.sep, got)
+			continue
+		}
+		if diff := cmp.Diff(test.want, got, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("GetList for %q with separator %q (-want +got):\n%s", test.value, test.sep, diff)
+		}
+	}
+}
+
+func TestSetList(t *testing.T) {
+	tests := []struct {
+		list []string
+		sep  string
+		want string
+	}{
+		{list: nil, sep: ",", want: "list=\n"},
+		{list: []string{"/usr/bin", "/bin"}, sep: ":", want: "list=/usr/bin:/bin\n"},
+		{list: []string{"a,b", "c"}, sep: ",", want: "list=\"\\\"a,b\\\",c\"\n"},
+		{list: []string{""}, sep: ",", want: "list=\"\\\"\\\"\"\n"},
+		{list: []string{"a b", "c"}, sep: " ", want: "list=\"\\\"a b\\\" c\"\n"},
+	}
+	for _, test := range tests {
+		f := new(File)
+		f.SetList("", "list", test.list, test.sep)
+		got, err := f.MarshalText()
+		if err != nil {
+			t.Errorf("SetList(..., %q, %q): MarshalText: %v", test.list, test.sep, err)
+			continue
+		}
+		if diff := cmp.Diff(test.want, string(got)); diff != "" {
+			t.Errorf("SetList(..., %q, %q) (-want +got):\n%s", test.list, test.sep, diff)
+		}
+
+		// Verify that the list survives a round trip.
+		parsed, err := Parse(strings.NewReader(string(got)), nil)
+		if err != nil {
+			t.Errorf("SetList(..., %q, %q): Parse: %v", test.list, test.sep, err)
+			continue
+		}
+		roundTrip, err := parsed.GetList("", "list", test.sep)
+		if err != nil {
+			t.Errorf("SetList(..., %q, %q): GetList: %v", test.list, test.sep, err)
+			continue
+		}
+		if diff := cmp.Diff(test.list, roundTrip, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("SetList(..., %q, %q) round trip (-want +got):\n%s", test.list, test.sep, diff)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import "sort"
+
+// Map returns a copy of the properties in f as a map of section names to
+// sections. Only sections with properties are included. The global section
+// is keyed by the empty string.
+func (f *File) Map() map[string]map[string][]string {
+	if f == nil {
+		return nil
+	}
+	m := make(map[string]map[string][]string)
+	for _, s := range f.sections {
+		for _, prop := range s.properties {
+			sect := m[s.name]
+			if sect == nil {
+				sect = make(map[string][]string)
+				m[s.name] = sect
+			}
+			sect[prop.key] = append(sect[prop.key], prop.value)
+		}
+	}
+	return m
+}
+
+// FromMap returns a new File with the properties in m, which maps section
+// names to sections in the form returned by File.Map. The global section is
+// written first, followed by the other sections sorted by name. Keys are
+// sorted within each section. Keys with no values are omitted. FromMap
+// returns an *InvalidNameError if any section name or key is not valid.
+func FromMap(m map[string]map[string][]string) (*File, error) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	// The empty string sorts first, so the global section stays first.
+	sort.Strings(names)
+	f := new(File)
+	for _, name := range names {
+		keys := make([]string, 0, len(m[name]))
+		for key := range m[name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := validateName(name, key); err != nil {
+				return nil, err
+			}
+			f.Add(name, key, m[name][key])
+		}
+	}
+	return f, nil
+}
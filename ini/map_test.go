@@ -0,0 +1,68 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMap(t *testing.T) {
+	const source = "top=1\n" +
+		"[server]\n" +
+		"host=a\n" +
+		"[empty]\n" +
+		"[server]\n" +
+		"host=b\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string][]string{
+		"":       {"top": {"1"}},
+		"server": {"host": {"a", "b"}},
+	}
+	if diff := cmp.Diff(want, f.Map()); diff != "" {
+		t.Errorf("f.Map() (-want +got):\n%s", diff)
+	}
+	if got := (*File)(nil).Map(); got != nil {
+		t.Errorf("(*File)(nil).Map() = %v; want <nil>", got)
+	}
+}
+
+func TestFromMap(t *testing.T) {
+	f, err := FromMap(map[string]map[string][]string{
+		"server": {"port": {"80"}, "host": {"a", "b"}},
+		"auth":   {"user": {"alice"}, "none": nil},
+		"":       {"top": {"1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "top=1\n" +
+		"\n" +
+		"[auth]\n" +
+		"user=alice\n" +
+		"\n" +
+		"[server]\n" +
+		"host=a\n" +
+		"host=b\n" +
+		"port=80\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
+
+	_, err = FromMap(map[string]map[string][]string{"a]": {"b": {"c"}}})
+	var nameErr *InvalidNameError
+	if !errors.As(err, &nameErr) || nameErr.Section != "a]" || nameErr.IsKey {
+		t.Errorf("FromMap with invalid section error = %v; want *InvalidNameError for section \"a]\"", err)
+	}
+}
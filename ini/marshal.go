@@ -0,0 +1,578 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MarshalOptions holds optional parameters for MarshalTextWith.
+type MarshalOptions struct {
+	// SectionOrder lists section names in the order they should be written.
+	// Sections named in SectionOrder are written first, in the listed order,
+	// followed by the remaining sections in their existing order. Repeated
+	// sections with the same name keep their relative order. The global
+	// section is always written first, since its properties cannot follow a
+	// section header, so listing the empty string has no effect.
+	SectionOrder []string
+
+	// LineEnding is the sequence written at the end of every line, including
+	// comments, section headers, and blank lines between sections. It must be
+	// "\n", "\r\n", or empty. The empty string is treated as "\n".
+	LineEnding string
+
+	// IncludeDefaults causes properties filled in by ParseWithDefaults to be
+	// written. By default, they are omitted, along with any section that only
+	// contains such properties.
+	IncludeDefaults bool
+
+	// KeyValueSeparator is written between each property's key and value.
+	// It must consist of a single equals sign ('=') surrounded by optional
+	// spaces or tabs, such as " = ". The empty string is treated as "=".
+	// Properties parsed with ParseOptions.PreserveFormatting keep their
+	// original separator.
+	KeyValueSeparator string
+
+	// SectionIndent is written at the beginning of each property and
+	// property comment line inside a named section. It must consist only of
+	// spaces or tabs. Properties in the global section are not indented, and
+	// properties parsed with ParseOptions.PreserveFormatting keep their
+	// original indentation.
+	SectionIndent string
+
+	// BlankLines specifies where blank lines are written between the
+	// properties of a section. Properties parsed with
+	// ParseOptions.PreserveBlankLines keep their original blank lines.
+	BlankLines BlankLinePolicy
+
+	// Quoting is the quoting style used for every value when
+	// OverrideQuoting is true, in place of each property's own style, which
+	// is MinimalQuoting unless changed by File.NormalizeQuoting. Overriding
+	// the style also discards the value text recorded by
+	// ParseOptions.PreserveFormatting.
+	Quoting         QuoteStyle
+	OverrideQuoting bool
+
+	// StrictQuoting causes MarshalTextWith to return an error for any value
+	// written with NeverQuoting that would not parse back to the same value
+	// without quotes, instead of quoting it.
+	StrictQuoting bool
+
+	// EscapeNonASCII causes non-ASCII bytes in values to be written as hex
+	// escapes (like "\xc3\xa9") instead of raw UTF-8. Values that contain
+	// non-ASCII characters are quoted so that they can be escaped. Value text
+	// recorded by ParseOptions.PreserveFormatting is discarded.
+	EscapeNonASCII bool
+
+	// UnicodeEscapes is like EscapeNonASCII, but writes each non-ASCII
+	// character as a Unicode escape (like "\u00e9" or "\U0001f600") instead
+	// of escaping each byte of its UTF-8 encoding. Bytes that are not part of
+	// valid UTF-8 are still written as hex escapes.
+	UnicodeEscapes bool
+}
+
+// BlankLinePolicy specifies where MarshalTextWith writes blank lines between
+// properties. Regardless of policy, a blank line is always written before
+// each section header not parsed with ParseOptions.PreserveBlankLines.
+type BlankLinePolicy int
+
+// Blank line policies.
+const (
+	// NoBlankLines writes no blank lines between properties. This is the
+	// default.
+	NoBlankLines BlankLinePolicy = iota
+	// BlankLineBeforeComments writes a blank line before each property that
+	// has comments, unless it is the first property in its section.
+	BlankLineBeforeComments
+	// BlankLineBetweenProperties writes a blank line between every pair of
+	// adjacent properties in a section.
+	BlankLineBetweenProperties
+)
+
+// MarshalText serializes the file in INI format, including comments from the
+// original file.
+func (f *File) MarshalText() ([]byte, error) {
+	return f.MarshalTextWith(nil)
+}
+
+// MarshalTextWith serializes the file in INI format like MarshalText, using
+// the given options. Nil options are treated identically as passing the
+// zero value.
+func (f *File) MarshalTextWith(opts *MarshalOptions) ([]byte, error) {
+	if f == nil {
+		return nil, nil
+	}
+	buf := new(bytes.Buffer)
+	if _, err := f.MarshalTo(buf, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteTo writes the file to w in INI format, as MarshalText would return it.
+// It implements io.WriterTo.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	return f.MarshalTo(w, nil)
+}
+
+// marshalBufferSize is the amount of output MarshalTo buffers before writing.
+const marshalBufferSize = 32 << 10
+
+// MarshalTo writes the file to w in INI format like MarshalTextWith, using the
+// given options. Output is written in chunks as the file is serialized, so
+// the whole output is never held in memory at once. It returns the number of
+// bytes written. If an error occurs partway through, some output may have
+// already been written to w.
+func (f *File) MarshalTo(w io.Writer, opts *MarshalOptions) (int64, error) {
+	if f == nil {
+		return 0, nil
+	}
+	if opts == nil {
+		opts = new(MarshalOptions)
+	}
+	eol := opts.LineEnding
+	switch eol {
+	case "":
+		eol = "\n"
+	case "\n", "\r\n":
+	default:
+		return 0, fmt.Errorf("marshal ini file: invalid line ending %q", eol)
+	}
+	if sep := strings.Trim(opts.KeyValueSeparator, " \t"); opts.KeyValueSeparator != "" && sep != "=" {
+		return 0, fmt.Errorf("marshal ini file: invalid key/value separator %q", opts.KeyValueSeparator)
+	}
+	if strings.Trim(opts.SectionIndent, " \t") != "" {
+		return 0, fmt.Errorf("marshal ini file: invalid section indent %q", opts.SectionIndent)
+	}
+	var buf []byte
+	var n int64
+	flush := func() error {
+		nn, err := w.Write(buf)
+		n += int64(nn)
+		buf = buf[:0]
+		if err != nil {
+			return fmt.Errorf("marshal ini file: %w", err)
+		}
+		return nil
+	}
+	for _, line := range f.leadingLines {
+		buf = append(buf, line...)
+		buf = append(buf, eol...)
+	}
+	wroteSections := false
+	for _, i := range f.sectionOrder(opts.SectionOrder) {
+		prevLen := len(buf)
+		var err error
+		buf, err = f.appendSections(buf, []int{i}, eol, opts, wroteSections)
+		if err != nil {
+			return n, err
+		}
+		wroteSections = wroteSections || len(buf) > prevLen
+		if len(buf) >= marshalBufferSize {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	if f.hasBlankLines {
+		buf = appendBlankLines(buf, f.trailingBlankLines, eol)
+	} else if len(f.trailingComments) > 0 && wroteSections {
+		buf = append(buf, eol...)
+	}
+	for _, comment := range f.trailingComments {
+		buf = append(buf, comment...)
+		buf = append(buf, eol...)
+	}
+	if len(buf) == 0 {
+		return n, nil
+	}
+	return n, flush()
+}
+
+// MarshalSections serializes only the sections with the given names in INI
+// format, using the same formatting as MarshalText. The global section is
+// included only if the empty string is one of the names. Sections are written
+// in the file's existing order, regardless of the order of names. Comments
+// attached to the selected sections and their properties are included, but
+// leading lines and comments at the end of the file are not.
+func (f *File) MarshalSections(names ...string) ([]byte, error) {
+	if f == nil {
+		return nil, nil
+	}
+	var indices []int
+	for i, s := range f.sections {
+		for _, name := range names {
+			if s.name == name {
+				indices = append(indices, i)
+				break
+			}
+		}
+	}
+	return f.appendSections(nil, indices, "\n", new(MarshalOptions), false)
+}
+
+// appendSections appends the sections of f at the given indices to buf,
+// terminating each line with eol. A blank line is written before each section
+// header except the first one appended, unless wrote is true, which indicates
+// that sections have already been written before buf. The line ending in opts
+// is ignored in favor of eol.
+func (f *File) appendSections(buf []byte, indices []int, eol string, opts *MarshalOptions, wrote bool) ([]byte, error) {
+	includeDefaults := opts.IncludeDefaults
+	delim := opts.KeyValueSeparator
+	if delim == "" {
+		delim = "="
+	}
+	preserve := !opts.OverrideQuoting && opts.nonASCIIEscape() == rawNonASCII
+	start := len(buf)
+	for _, i := range indices {
+		s := &f.sections[i]
+		if !includeDefaults && s.onlyDefaults() {
+			continue
+		}
+		if s.hasBlankLines {
+			buf = appendBlankLines(buf, s.blankLines, eol)
+		} else if s.name != "" && (wrote || len(buf) > start) {
+			buf = append(buf, eol...)
+		}
+		for _, comment := range s.comments {
+			buf = append(buf, comment...)
+			buf = append(buf, eol...)
+		}
+		if s.header != "" {
+			buf = append(buf, s.header...)
+			buf = append(buf, eol...)
+		} else if s.name != "" {
+			buf = append(buf, '[')
+			buf = append(buf, s.name...)
+			buf = append(buf, ']')
+			buf = append(buf, eol...)
+		}
+		indent := ""
+		if s.name != "" {
+			indent = opts.SectionIndent
+		}
+		wroteProperty := false
+		for _, prop := range s.properties {
+			if prop.fromDefault && !includeDefaults {
+				continue
+			}
+			if prop.hasBlankLines {
+				buf = appendBlankLines(buf, prop.blankLines, eol)
+			} else if wroteProperty && (opts.BlankLines == BlankLineBetweenProperties ||
+				opts.BlankLines == BlankLineBeforeComments && len(prop.comments) > 0) {
+				buf = append(buf, eol...)
+			}
+			wroteProperty = true
+			for _, comment := range prop.comments {
+				buf = append(buf, indent...)
+				buf = append(buf, comment...)
+				buf = append(buf, eol...)
+			}
+			if prop.indent != "" {
+				buf = append(buf, prop.indent...)
+			} else {
+				buf = append(buf, indent...)
+			}
+			if prop.export {
+				buf = append(buf, "export "...)
+			}
+			buf = append(buf, prop.key...)
+			if prop.bare && prop.value == "" {
+				if prop.inlineComment != "" {
+					buf = append(buf, ' ')
+					buf = append(buf, prop.inlineComment...)
+				}
+				buf = append(buf, eol...)
+				continue
+			}
+			if prop.delim != "" {
+				buf = append(buf, prop.delim...)
+			} else {
+				buf = append(buf, delim...)
+			}
+			if preserve && len(prop.continuation) > 0 {
+				for _, line := range prop.continuation {
+					buf = append(buf, line...)
+					buf = append(buf, eol...)
+				}
+				continue
+			}
+			if preserve && prop.hasVerbatim {
+				buf = append(buf, prop.verbatim...)
+			} else {
+				var err error
+				buf, err = f.appendValue(buf, s.name, &prop, opts)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if prop.inlineComment != "" {
+				buf = append(buf, ' ')
+				buf = append(buf, prop.inlineComment...)
+			}
+			buf = append(buf, eol...)
+		}
+	}
+	return buf, nil
+}
+
+// appendBlankLines appends n empty lines to buf.
+func appendBlankLines(buf []byte, n int, eol string) []byte {
+	for ; n > 0; n-- {
+		buf = append(buf, eol...)
+	}
+	return buf
+}
+
+// appendValue appends the value of prop to buf, quoting it as specified by
+// opts and the syntax the file was parsed with.
+func (f *File) appendValue(buf []byte, sectionName string, prop *property, opts *MarshalOptions) ([]byte, error) {
+	v := prop.value
+	// escaped is v as written outside single quotes.
+	escaped := v
+	if f.expandEnv {
+		// Prevent expansion when the file is parsed again.
+		escaped = strings.ReplaceAll(v, "$", "$$")
+	}
+	if f.systemd {
+		// Values are literal. See ParseOptions.Systemd.
+		// A trailing backslash would continue the value onto the next line.
+		if strings.TrimSpace(v) != v || strings.ContainsAny(v, "\r\n") || hasContinuation([]byte(v)) {
+			return nil, fmt.Errorf("marshal ini file: [%s] %s: value %q cannot be written in systemd syntax", sectionName, prop.key, v)
+		}
+		return append(buf, escaped...), nil
+	}
+	escapeNonASCII := opts.nonASCIIEscape() != rawNonASCII && hasNonASCII(v)
+	mustQuote := mustQuoteValue(v) ||
+		f.inlineComments && hasCommentMarker(v) ||
+		f.singleQuotes && strings.HasPrefix(v, "'") ||
+		f.lineContinuation && hasContinuation([]byte(v)) ||
+		prop.export && strings.IndexFunc(v, unicode.IsSpace) != -1 ||
+		escapeNonASCII
+	style := prop.quote
+	if opts.OverrideQuoting {
+		style = opts.Quoting
+	}
+	if style == NeverQuoting && opts.StrictQuoting && mustQuote {
+		return nil, fmt.Errorf("marshal ini file: [%s] %s: value %q cannot be written without quotes", sectionName, prop.key, v)
+	}
+	quote := mustQuote || style.shouldQuote(v)
+	switch {
+	case !quote:
+		buf = append(buf, escaped...)
+	case f.singleQuotes && strings.Contains(v, `"`) && canSingleQuote(v) && !escapeNonASCII:
+		buf = append(buf, '\'')
+		buf = append(buf, v...)
+		buf = append(buf, '\'')
+	default:
+		buf = appendQuotedString(buf, escaped, opts.nonASCIIEscape())
+	}
+	return buf, nil
+}
+
+// sectionOrder returns the indices of f.sections in the order they should be
+// written. See MarshalOptions.SectionOrder for details.
+func (f *File) sectionOrder(order []string) []int {
+	indices := make([]int, 0, len(f.sections))
+	written := make([]bool, len(f.sections))
+	for i, s := range f.sections {
+		if s.name == "" {
+			indices = append(indices, i)
+			written[i] = true
+		}
+	}
+	for _, name := range order {
+		for i, s := range f.sections {
+			if !written[i] && s.name == name {
+				indices = append(indices, i)
+				written[i] = true
+			}
+		}
+	}
+	for i := range f.sections {
+		if !written[i] {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// nonASCIIEscape specifies how appendQuotedString writes non-ASCII bytes.
+type nonASCIIEscape int
+
+const (
+	rawNonASCII nonASCIIEscape = iota
+	hexNonASCII
+	unicodeNonASCII
+)
+
+// nonASCIIEscape returns the escaping specified by opts.
+func (opts *MarshalOptions) nonASCIIEscape() nonASCIIEscape {
+	switch {
+	case opts.UnicodeEscapes:
+		return unicodeNonASCII
+	case opts.EscapeNonASCII:
+		return hexNonASCII
+	default:
+		return rawNonASCII
+	}
+}
+
+// appendQuotedString appends v to dst as a double-quoted string, escaping
+// non-ASCII bytes as specified.
+func appendQuotedString(dst []byte, v string, escape nonASCIIEscape) []byte {
+	const hexDigits = "0123456789abcdef"
+	dst = append(dst, '"')
+	for i := 0; i < len(v); i++ {
+		if escape == unicodeNonASCII && v[i] >= utf8.RuneSelf {
+			if r, size := utf8.DecodeRuneInString(v[i:]); size > 1 {
+				if r > 0xffff {
+					dst = append(dst, '\\', 'U')
+					dst = appendHex(dst, uint32(r), 8)
+				} else {
+					dst = append(dst, '\\', 'u')
+					dst = appendHex(dst, uint32(r), 4)
+				}
+				i += size - 1
+				continue
+			}
+		}
+		switch c := v[i]; {
+		case c == '\n':
+			dst = append(dst, '\\', 'n')
+		case c == '\r':
+			dst = append(dst, '\\', 'r')
+		case c == '\t':
+			dst = append(dst, '\\', 't')
+		case c == '\\':
+			dst = append(dst, '\\', '\\')
+		case c == '"':
+			dst = append(dst, '\\', '"')
+		case c < ' ' || c == del || escape != rawNonASCII && c >= utf8.RuneSelf:
+			dst = append(dst, '\\', 'x', hexDigits[c>>4], hexDigits[c&0xf])
+		default:
+			dst = append(dst, c)
+		}
+	}
+	dst = append(dst, '"')
+	return dst
+}
+
+// appendHex appends the lowest n hex digits of x to dst.
+func appendHex(dst []byte, x uint32, n int) []byte {
+	const hexDigits = "0123456789abcdef"
+	for shift := 4 * (n - 1); shift >= 0; shift -= 4 {
+		dst = append(dst, hexDigits[x>>uint(shift)&0xf])
+	}
+	return dst
+}
+
+const del = '\x7f'
+
+// QuoteStyle specifies when property values are quoted in serialized output.
+type QuoteStyle int
+
+// Quoting styles. Regardless of style, values that cannot be represented
+// without quotes (such as values with leading or trailing whitespace or
+// newlines) are always quoted.
+const (
+	// MinimalQuoting quotes values that have leading or trailing whitespace
+	// or contain double quotes or control characters. This is the default
+	// for all properties.
+	MinimalQuoting QuoteStyle = iota
+	// AlwaysQuoting quotes every value, including empty values.
+	AlwaysQuoting
+	// NeverQuoting quotes only values that would not parse back to the same
+	// value without quotes. With MarshalOptions.StrictQuoting, such values
+	// are an error instead.
+	NeverQuoting
+)
+
+// NormalizeQuoting sets the quoting style used when marshaling every property
+// currently in the file. Setting an existing property keeps its style, but
+// properties added later use MinimalQuoting.
+// NormalizeQuoting does not change any values, but it discards any value text
+// recorded by ParseOptions.PreserveFormatting.
+func (f *File) NormalizeQuoting(style QuoteStyle) {
+	f.beforeChange()
+	for i := range f.sections {
+		s := &f.sections[i]
+		for j := range s.properties {
+			prop := &s.properties[j]
+			prop.quote = style
+			prop.verbatim = ""
+			prop.hasVerbatim = false
+		}
+	}
+}
+
+// shouldQuote reports whether v should be quoted under the given style.
+func (style QuoteStyle) shouldQuote(v string) bool {
+	switch style {
+	case AlwaysQuoting:
+		return true
+	case NeverQuoting:
+		return mustQuoteValue(v)
+	default:
+		return shouldQuoteValue(v)
+	}
+}
+
+func shouldQuoteValue(v string) bool {
+	if strings.TrimSpace(v) != v {
+		return true
+	}
+	for _, c := range v {
+		if c == '"' || (c < ' ' || c == del) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCommentMarker reports whether v would be read as having an inline
+// comment if it were written without quotes. See
+// ParseOptions.AllowInlineComments.
+func hasCommentMarker(v string) bool {
+	for i := 0; i < len(v); i++ {
+		if (v[i] == ';' || v[i] == '#') && (i == 0 || isSpaceByte(v[i-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasNonASCII reports whether v contains any non-ASCII bytes.
+func hasNonASCII(v string) bool {
+	for i := 0; i < len(v); i++ {
+		if v[i] >= utf8.RuneSelf {
+			return true
+		}
+	}
+	return false
+}
+
+// canSingleQuote reports whether v can be written in single quotes. See
+// ParseOptions.AllowSingleQuotes.
+func canSingleQuote(v string) bool {
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c == '\'' || c < ' ' || c == del {
+			return false
+		}
+	}
+	return true
+}
+
+// mustQuoteValue reports whether v would be read back differently if it were
+// written without quotes.
+func mustQuoteValue(v string) bool {
+	return strings.TrimSpace(v) != v ||
+		strings.HasPrefix(v, `"`) ||
+		strings.ContainsAny(v, "\r\n")
+}
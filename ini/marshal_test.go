@@ -0,0 +1,661 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMarshalTextWith(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		opts   *MarshalOptions
+		want   string
+	}{
+		{
+			name:   "NilOptions",
+			source: "global=1\n[build]\nb=1\n[meta]\nm=1\n",
+			opts:   nil,
+			want:   "global=1\n\n[build]\nb=1\n\n[meta]\nm=1\n",
+		},
+		{
+			name:   "SectionOrder/Full",
+			source: "[build]\nb=1\n[meta]\nm=1\n",
+			opts:   &MarshalOptions{SectionOrder: []string{"meta", "build"}},
+			want:   "[meta]\nm=1\n\n[build]\nb=1\n",
+		},
+		{
+			name: "SectionOrder/Partial",
+			source: "global=1\n" +
+				"[a]\na=1\n" +
+				"; Build comment\n[build]\nb=1\n" +
+				"[c]\nc=1\n" +
+				"[meta]\nm=1\n",
+			opts: &MarshalOptions{SectionOrder: []string{"meta", "build"}},
+			want: "global=1\n" +
+				"\n[meta]\nm=1\n" +
+				"\n; Build comment\n[build]\nb=1\n" +
+				"\n[a]\na=1\n" +
+				"\n[c]\nc=1\n",
+		},
+		{
+			name: "SectionOrder/Repeated",
+			source: "[build]\nb=1\n" +
+				"[meta]\nm=1\n" +
+				"[build]\nb=2\n",
+			opts: &MarshalOptions{SectionOrder: []string{"meta"}},
+			want: "[meta]\nm=1\n" +
+				"\n[build]\nb=1\n" +
+				"\n[build]\nb=2\n",
+		},
+		{
+			name:   "SectionOrder/GlobalListed",
+			source: "global=1\n[build]\nb=1\n[meta]\nm=1\n",
+			opts:   &MarshalOptions{SectionOrder: []string{"meta", ""}},
+			want:   "global=1\n\n[meta]\nm=1\n\n[build]\nb=1\n",
+		},
+		{
+			name:   "SectionOrder/UnknownNames",
+			source: "[build]\nb=1\n",
+			opts:   &MarshalOptions{SectionOrder: []string{"meta", "test"}},
+			want:   "[build]\nb=1\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(test.source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := f.MarshalTextWith(test.opts)
+			if err != nil {
+				t.Fatal("MarshalTextWith:", err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalTextWith (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMarshalTo(t *testing.T) {
+	const source = "; Leading\n" +
+		"global=1\n" +
+		"[build]\n" +
+		"; Build comment\n" +
+		"b=1\n" +
+		"[empty]\n" +
+		"[meta]\n" +
+		"m=\" spaced \"\n" +
+		"; Trailing\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb := new(strings.Builder)
+	n, err := f.WriteTo(sb)
+	if err != nil {
+		t.Fatal("WriteTo:", err)
+	}
+	if diff := cmp.Diff(string(want), sb.String()); diff != "" {
+		t.Errorf("WriteTo output (-want +got):\n%s", diff)
+	}
+	if n != int64(sb.Len()) {
+		t.Errorf("WriteTo(...) = %d, <nil>; want %d, <nil>", n, sb.Len())
+	}
+
+	t.Run("WriteError", func(t *testing.T) {
+		writeErr := errors.New("bork")
+		n, err := f.MarshalTo(errorWriter{writeErr}, nil)
+		if !errors.Is(err, writeErr) {
+			t.Errorf("MarshalTo(...) = %d, %v; want error wrapping %v", n, err, writeErr)
+		}
+	})
+
+	t.Run("InvalidOptions", func(t *testing.T) {
+		sb := new(strings.Builder)
+		_, err := f.MarshalTo(sb, &MarshalOptions{LineEnding: "\r"})
+		if err == nil {
+			t.Error("MarshalTo did not return an error")
+		}
+		if sb.Len() > 0 {
+			t.Errorf("MarshalTo wrote %q; want no output", sb.String())
+		}
+	})
+}
+
+type errorWriter struct {
+	err error
+}
+
+func (w errorWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestPreserveBlankLines(t *testing.T) {
+	const source = "top=1\n" +
+		"\n" +
+		"\n" +
+		"; About foo\n" +
+		"foo=2\n" +
+		"[a]\n" +
+		"x=1\n" +
+		"\n" +
+		"y=2\n" +
+		"\n" +
+		"\n" +
+		"\n" +
+		"; Section b\n" +
+		"\n" +
+		"[b]\n" +
+		"z=3\n" +
+		"\n" +
+		"; Trailing\n" +
+		"\n"
+	f, err := Parse(strings.NewReader(source), &ParseOptions{PreserveBlankLines: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Set("a", "y", "changed")
+	f.Set("a", "new", "4")
+	f.Set("c", "w", "5")
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "top=1\n" +
+		"\n" +
+		"\n" +
+		"; About foo\n" +
+		"foo=2\n" +
+		"[a]\n" +
+		"x=1\n" +
+		"\n" +
+		"y=changed\n" +
+		"new=4\n" +
+		"\n" +
+		"\n" +
+		"\n" +
+		"; Section b\n" +
+		"[b]\n" +
+		"z=3\n" +
+		"\n" +
+		"[c]\n" +
+		"w=5\n" +
+		"\n" +
+		"; Trailing\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshalSections(t *testing.T) {
+	const source = "# Leading comment\n" +
+		"global=1\n" +
+		"; Build comment\n" +
+		"[build]\n" +
+		"b=1\n" +
+		"[meta]\n" +
+		"; Property comment\n" +
+		"m=1\n" +
+		"[test]\n" +
+		"t=1\n" +
+		"[build]\n" +
+		"b=2\n" +
+		"; Trailing comment\n"
+	tests := []struct {
+		name  string
+		names []string
+		want  string
+	}{
+		{
+			name:  "None",
+			names: nil,
+			want:  "",
+		},
+		{
+			name:  "One",
+			names: []string{"meta"},
+			want:  "[meta]\n; Property comment\nm=1\n",
+		},
+		{
+			name:  "Multiple",
+			names: []string{"test", "build"},
+			want: "; Build comment\n[build]\nb=1\n" +
+				"\n[test]\nt=1\n" +
+				"\n[build]\nb=2\n",
+		},
+		{
+			name:  "Global",
+			names: []string{"", "test"},
+			want:  "# Leading comment\nglobal=1\n\n[test]\nt=1\n",
+		},
+		{
+			name:  "Unknown",
+			names: []string{"foo"},
+			want:  "",
+		},
+	}
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := f.MarshalSections(test.names...)
+			if err != nil {
+				t.Fatal("MarshalSections:", err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalSections(%q...) (-want +got):\n%s", test.names, diff)
+			}
+		})
+	}
+}
+
+func TestMarshalLineEnding(t *testing.T) {
+	f, err := Parse(strings.NewReader("# Leading\n"+
+		"global=1\n"+
+		"; Section comment\n"+
+		"[build]\n"+
+		"; Property comment\n"+
+		"b=\"multi\\nline\"\n"+
+		"[meta]\n"+
+		"m=1\n"+
+		"; Trailing\n"), &ParseOptions{PreserveLeadingLines: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		lineEnding string
+		want       string
+	}{
+		{
+			lineEnding: "",
+			want: "# Leading\n" +
+				"global=1\n" +
+				"\n" +
+				"; Section comment\n" +
+				"[build]\n" +
+				"; Property comment\n" +
+				"b=\"multi\\nline\"\n" +
+				"\n" +
+				"[meta]\n" +
+				"m=1\n" +
+				"\n" +
+				"; Trailing\n",
+		},
+		{
+			lineEnding: "\n",
+			want: "# Leading\n" +
+				"global=1\n" +
+				"\n" +
+				"; Section comment\n" +
+				"[build]\n" +
+				"; Property comment\n" +
+				"b=\"multi\\nline\"\n" +
+				"\n" +
+				"[meta]\n" +
+				"m=1\n" +
+				"\n" +
+				"; Trailing\n",
+		},
+		{
+			lineEnding: "\r\n",
+			want: "# Leading\r\n" +
+				"global=1\r\n" +
+				"\r\n" +
+				"; Section comment\r\n" +
+				"[build]\r\n" +
+				"; Property comment\r\n" +
+				"b=\"multi\\nline\"\r\n" +
+				"\r\n" +
+				"[meta]\r\n" +
+				"m=1\r\n" +
+				"\r\n" +
+				"; Trailing\r\n",
+		},
+	}
+	for _, test := range tests {
+		got, err := f.MarshalTextWith(&MarshalOptions{LineEnding: test.lineEnding})
+		if err != nil {
+			t.Errorf("MarshalTextWith(&MarshalOptions{LineEnding: %q}): %v", test.lineEnding, err)
+			continue
+		}
+		if diff := cmp.Diff(test.want, string(got)); diff != "" {
+			t.Errorf("MarshalTextWith(&MarshalOptions{LineEnding: %q}) (-want +got):\n%s", test.lineEnding, diff)
+		}
+	}
+
+	for _, lineEnding := range []string{"\r", "\n\r", " "} {
+		if _, err := f.MarshalTextWith(&MarshalOptions{LineEnding: lineEnding}); err == nil {
+			t.Errorf("MarshalTextWith(&MarshalOptions{LineEnding: %q}) did not return an error", lineEnding)
+		}
+	}
+}
+
+func TestMarshalStyle(t *testing.T) {
+	const source = "global=1\n" +
+		"[build]\n" +
+		"a=1\n" +
+		"; Comment\n" +
+		"b=2\n" +
+		"c=3\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		opts *MarshalOptions
+		want string
+	}{
+		{
+			name: "Separator",
+			opts: &MarshalOptions{KeyValueSeparator: " = "},
+			want: "global = 1\n" +
+				"\n" +
+				"[build]\n" +
+				"a = 1\n" +
+				"; Comment\n" +
+				"b = 2\n" +
+				"c = 3\n",
+		},
+		{
+			name: "SectionIndent",
+			opts: &MarshalOptions{SectionIndent: "\t"},
+			want: "global=1\n" +
+				"\n" +
+				"[build]\n" +
+				"\ta=1\n" +
+				"\t; Comment\n" +
+				"\tb=2\n" +
+				"\tc=3\n",
+		},
+		{
+			name: "BlankLineBeforeComments",
+			opts: &MarshalOptions{BlankLines: BlankLineBeforeComments},
+			want: "global=1\n" +
+				"\n" +
+				"[build]\n" +
+				"a=1\n" +
+				"\n" +
+				"; Comment\n" +
+				"b=2\n" +
+				"c=3\n",
+		},
+		{
+			name: "BlankLineBetweenProperties",
+			opts: &MarshalOptions{BlankLines: BlankLineBetweenProperties},
+			want: "global=1\n" +
+				"\n" +
+				"[build]\n" +
+				"a=1\n" +
+				"\n" +
+				"; Comment\n" +
+				"b=2\n" +
+				"\n" +
+				"c=3\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := f.MarshalTextWith(test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalTextWith(%+v) (-want +got):\n%s", test.opts, diff)
+			}
+		})
+	}
+
+	for _, opts := range []*MarshalOptions{
+		{KeyValueSeparator: ":"},
+		{KeyValueSeparator: "=="},
+		{KeyValueSeparator: " "},
+		{SectionIndent: "x"},
+	} {
+		if _, err := f.MarshalTextWith(opts); err == nil {
+			t.Errorf("MarshalTextWith(%+v) did not return an error", opts)
+		}
+	}
+}
+
+func TestMarshalQuoting(t *testing.T) {
+	const source = "plain=hello\n" +
+		"inner=say \"hi\"\n" +
+		"accent=caf\u00e9\n" +
+		"empty=\n" +
+		"kept = 'as is'\n"
+	f, err := Parse(strings.NewReader(source), &ParseOptions{PreserveFormatting: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		opts *MarshalOptions
+		want string
+	}{
+		{
+			name: "PerProperty",
+			opts: &MarshalOptions{},
+			want: "plain=hello\n" +
+				"inner=say \"hi\"\n" +
+				"accent=caf\u00e9\n" +
+				"empty=\n" +
+				"kept = 'as is'\n",
+		},
+		{
+			name: "Minimal",
+			opts: &MarshalOptions{Quoting: MinimalQuoting, OverrideQuoting: true},
+			want: "plain=hello\n" +
+				"inner=\"say \\\"hi\\\"\"\n" +
+				"accent=caf\u00e9\n" +
+				"empty=\n" +
+				"kept = 'as is'\n",
+		},
+		{
+			name: "Always",
+			opts: &MarshalOptions{Quoting: AlwaysQuoting, OverrideQuoting: true},
+			want: "plain=\"hello\"\n" +
+				"inner=\"say \\\"hi\\\"\"\n" +
+				"accent=\"caf\u00e9\"\n" +
+				"empty=\"\"\n" +
+				"kept = \"'as is'\"\n",
+		},
+		{
+			name: "Never",
+			opts: &MarshalOptions{Quoting: NeverQuoting, OverrideQuoting: true, StrictQuoting: true},
+			want: "plain=hello\n" +
+				"inner=say \"hi\"\n" +
+				"accent=caf\u00e9\n" +
+				"empty=\n" +
+				"kept = 'as is'\n",
+		},
+		{
+			name: "UnicodeEscapes",
+			opts: &MarshalOptions{UnicodeEscapes: true},
+			want: "plain=hello\n" +
+				"inner=\"say \\\"hi\\\"\"\n" +
+				"accent=\"caf\\u00e9\"\n" +
+				"empty=\n" +
+				"kept = 'as is'\n",
+		},
+		{
+			name: "EscapeNonASCII",
+			opts: &MarshalOptions{EscapeNonASCII: true},
+			want: "plain=hello\n" +
+				"inner=\"say \\\"hi\\\"\"\n" +
+				"accent=\"caf\\xc3\\xa9\"\n" +
+				"empty=\n" +
+				"kept = 'as is'\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := f.MarshalTextWith(test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalTextWith(%+v) (-want +got):\n%s", test.opts, diff)
+			}
+			g, err := Parse(strings.NewReader(string(got)), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !Equal(f, g) {
+				t.Errorf("MarshalTextWith(%+v) did not round-trip", test.opts)
+			}
+		})
+	}
+
+	t.Run("NeverUnsafe", func(t *testing.T) {
+		f := new(File)
+		f.Set("", "padded", " x ")
+		strict := &MarshalOptions{Quoting: NeverQuoting, OverrideQuoting: true, StrictQuoting: true}
+		if _, err := f.MarshalTextWith(strict); err == nil {
+			t.Errorf("MarshalTextWith(%+v) did not return an error for a padded value", strict)
+		}
+		strictEscaped := &MarshalOptions{Quoting: NeverQuoting, OverrideQuoting: true, StrictQuoting: true, EscapeNonASCII: true}
+		if _, err := f.MarshalTextWith(strictEscaped); err == nil {
+			t.Errorf("MarshalTextWith(%+v) did not return an error for a padded value", strictEscaped)
+		}
+
+		// Without StrictQuoting, the value is quoted as it must be.
+		got, err := f.MarshalTextWith(&MarshalOptions{Quoting: NeverQuoting, OverrideQuoting: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff("padded=\" x \"\n", string(got)); diff != "" {
+			t.Errorf("MarshalTextWith without StrictQuoting (-want +got):\n%s", diff)
+		}
+
+		// StrictQuoting also applies to styles set by NormalizeQuoting.
+		f.NormalizeQuoting(NeverQuoting)
+		if _, err := f.MarshalTextWith(&MarshalOptions{StrictQuoting: true}); err == nil {
+			t.Error("MarshalTextWith(&MarshalOptions{StrictQuoting: true}) after NormalizeQuoting(NeverQuoting) did not return an error")
+		}
+	})
+}
+
+func TestUnicodeEscapes(t *testing.T) {
+	f := new(File)
+	f.Set("", "smile", "\U0001F600!")
+	f.Set("", "invalid", "a\xffb")
+	got, err := f.MarshalTextWith(&MarshalOptions{UnicodeEscapes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `smile="\U0001f600!"` + "\n" +
+		`invalid="a\xffb"` + "\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalTextWith(&MarshalOptions{UnicodeEscapes: true}) (-want +got):\n%s", diff)
+	}
+	g, err := Parse(strings.NewReader(string(got)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(f, g) {
+		t.Errorf("MarshalTextWith(&MarshalOptions{UnicodeEscapes: true}) did not round-trip")
+	}
+}
+
+func TestNormalizeQuoting(t *testing.T) {
+	const source = "plain=hello world\n" +
+		"quoted=\"hello\"\n" +
+		"inner=say \"hi\"\n" +
+		"empty=\n" +
+		"space=\" padded \"\n" +
+		"newline=\"a\\nb\"\n" +
+		"leading=\"\\\"quoted\\\"\"\n"
+	tests := []struct {
+		style QuoteStyle
+		want  string
+	}{
+		{
+			style: MinimalQuoting,
+			want: "plain=hello world\n" +
+				"quoted=hello\n" +
+				"inner=\"say \\\"hi\\\"\"\n" +
+				"empty=\n" +
+				"space=\" padded \"\n" +
+				"newline=\"a\\nb\"\n" +
+				"leading=\"\\\"quoted\\\"\"\n",
+		},
+		{
+			style: AlwaysQuoting,
+			want: "plain=\"hello world\"\n" +
+				"quoted=\"hello\"\n" +
+				"inner=\"say \\\"hi\\\"\"\n" +
+				"empty=\"\"\n" +
+				"space=\" padded \"\n" +
+				"newline=\"a\\nb\"\n" +
+				"leading=\"\\\"quoted\\\"\"\n",
+		},
+		{
+			style: NeverQuoting,
+			want: "plain=hello world\n" +
+				"quoted=hello\n" +
+				"inner=say \"hi\"\n" +
+				"empty=\n" +
+				"space=\" padded \"\n" +
+				"newline=\"a\\nb\"\n" +
+				"leading=\"\\\"quoted\\\"\"\n",
+		},
+	}
+	for _, test := range tests {
+		f, err := Parse(strings.NewReader(source), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.NormalizeQuoting(test.style)
+		got, err := f.MarshalText()
+		if err != nil {
+			t.Errorf("style %d: MarshalText: %v", test.style, err)
+			continue
+		}
+		if diff := cmp.Diff(test.want, string(got)); diff != "" {
+			t.Errorf("style %d: MarshalText (-want +got):\n%s", test.style, diff)
+		}
+
+		// Verify that values survive a round trip.
+		f2, err := Parse(strings.NewReader(string(got)), nil)
+		if err != nil {
+			t.Errorf("style %d: parse output: %v", test.style, err)
+			continue
+		}
+		if diff := cmp.Diff(f.Section(""), f2.Section("")); diff != "" {
+			t.Errorf("style %d: values after round trip (-want +got):\n%s", test.style, diff)
+		}
+	}
+
+	t.Run("NewProperties", func(t *testing.T) {
+		f, err := Parse(strings.NewReader("a=1\n"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.NormalizeQuoting(AlwaysQuoting)
+		f.Set("", "a", "2")
+		f.Set("", "b", "3")
+		got, err := f.MarshalText()
+		if err != nil {
+			t.Fatal("MarshalText:", err)
+		}
+		const want = "a=\"2\"\nb=3\n"
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("MarshalText (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+// SectionsMatching returns the names of the sections with properties set
+// whose names match the given pattern, in the order they first appear in the
+// file. In the pattern, '*' matches any sequence of characters (including
+// none) and '?' matches any single character; all other characters match
+// themselves. For example, "profile *" matches every AWS CLI profile section
+// and `submodule "*"` matches every submodule in a Git config file. The global
+// section is only included if the pattern matches the empty string.
+func (f *File) SectionsMatching(pattern string) []string {
+	if f == nil {
+		return nil
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, s := range f.sections {
+		if len(s.properties) == 0 || seen[s.name] || !matchGlob(pattern, s.name) {
+			continue
+		}
+		seen[s.name] = true
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// SectionsMatching returns the names of the sections with properties set in
+// any file whose names match the given pattern. Names are listed in the order
+// they first appear in the set, starting with the first file. See
+// File.SectionsMatching for the pattern syntax.
+func (fset FileSet) SectionsMatching(pattern string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, f := range fset {
+		for _, name := range f.SectionsMatching(pattern) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// matchGlob reports whether name matches pattern, in which '*' matches any
+// sequence of characters and '?' matches any single character.
+func matchGlob(pattern, name string) bool {
+	// Iterative matching with backtracking to the most recent star.
+	p, n := []rune(pattern), []rune(name)
+	pi, ni := 0, 0
+	star, starN := -1, 0
+	for ni < len(n) {
+		switch {
+		case pi < len(p) && (p[pi] == '?' || p[pi] == n[ni]):
+			pi++
+			ni++
+		case pi < len(p) && p[pi] == '*':
+			star, starN = pi, ni
+			pi++
+		case star != -1:
+			starN++
+			pi, ni = star+1, starN
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSectionsMatching(t *testing.T) {
+	const source = "top=1\n" +
+		"[profile dev]\n" +
+		"region=us-east-1\n" +
+		"[default]\n" +
+		"region=us-west-2\n" +
+		"[submodule \"lib/a\"]\n" +
+		"path=a\n" +
+		"[profile prod]\n" +
+		"region=eu-west-1\n" +
+		"[profile dev]\n" +
+		"output=json\n" +
+		"[profile empty]\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"profile *", []string{"profile dev", "profile prod"}},
+		{`submodule "*"`, []string{`submodule "lib/a"`}},
+		{"profile ?ev", []string{"profile dev"}},
+		{"*", []string{"", "profile dev", "default", `submodule "lib/a"`, "profile prod"}},
+		{"default", []string{"default"}},
+		{"", []string{""}},
+		{"nope*", nil},
+	}
+	for _, test := range tests {
+		got := f.SectionsMatching(test.pattern)
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("f.SectionsMatching(%q) (-want +got):\n%s", test.pattern, diff)
+		}
+	}
+
+	other, err := Parse(strings.NewReader("[profile staging]\nregion=x\n[profile prod]\nregion=y\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := FileSet{other, nil, f}
+	want := []string{"profile staging", "profile prod", "profile dev"}
+	if diff := cmp.Diff(want, fset.SectionsMatching("profile *")); diff != "" {
+		t.Errorf("fset.SectionsMatching(\"profile *\") (-want +got):\n%s", diff)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"a*b", "ab", true},
+		{"a*b", "axxb", true},
+		{"a*b", "axxbc", false},
+		{"a*b*c", "abxbc", true},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"é?", "éé", true},
+		{"**x", "abx", true},
+	}
+	for _, test := range tests {
+		if got := matchGlob(test.pattern, test.name); got != test.want {
+			t.Errorf("matchGlob(%q, %q) = %t; want %t", test.pattern, test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+// MergePolicy specifies how File.Merge handles a key that is present in both
+// files.
+type MergePolicy int
+
+// Merge policies.
+const (
+	// MergeOverwrite replaces the values of a key in the destination with the
+	// key's values from the other file, as if by Encode for a slice field:
+	// existing properties keep their positions and comments.
+	MergeOverwrite MergePolicy = iota
+	// MergeKeepExisting ignores the other file's values for a key that the
+	// destination already has.
+	MergeKeepExisting
+	// MergeAppend adds the other file's values for a key after the
+	// destination's values, so Get returns the other file's last value and
+	// Find returns the values from both files.
+	MergeAppend
+)
+
+// Merge merges the sections and properties of other into f according to the
+// given policy. Properties for keys that f does not have are appended to the
+// last section in f with the same name, or to a new section at the end of f if
+// there is none. Sections are matched by name; a section that exists in f keeps
+// its comments unless it has none, in which case it takes the comments of the
+// first section in other with the same name. Comments attached to properties
+// that are added to f are kept, and comments at the end of other are added to
+// the end of f. Properties added to f do not keep their formatting from other,
+// such as quoting, export prefixes, or text recorded by
+// ParseOptions.PreserveFormatting: they are written like properties added by
+// Add, using f's syntax. A nil other is treated as an empty file. Section defaults set by
+// SetSectionDefaults are not merged.
+func (f *File) Merge(other *File, policy MergePolicy) {
+	f.beforeChange()
+	if other == nil {
+		return
+	}
+	type sectionKey struct {
+		section string
+		key     string
+	}
+	existed := make(map[sectionKey]bool)
+	for _, s := range f.sections {
+		for _, p := range s.properties {
+			existed[sectionKey{s.name, p.key}] = true
+		}
+	}
+	overwritten := make(map[sectionKey]bool)
+	for _, s := range other.sections {
+		f.mergeSectionComments(s.name, s.comments)
+		for _, p := range s.properties {
+			k := sectionKey{s.name, p.key}
+			switch {
+			case !existed[k] || policy == MergeAppend:
+				f.appendProperty(s.name, p)
+			case policy == MergeOverwrite && !overwritten[k]:
+				overwritten[k] = true
+				f.overwrite(other, s.name, p.key)
+			}
+		}
+	}
+	f.trailingComments = append(f.trailingComments, other.trailingComments...)
+}
+
+// mergeSectionComments ensures that f has a section with the given name,
+// creating one at the end of the file if necessary. If the first section with
+// the name has no comments, it is given a copy of comments.
+func (f *File) mergeSectionComments(name string, comments []string) {
+	for i := range f.sections {
+		if s := &f.sections[i]; s.name == name {
+			if len(s.comments) == 0 && name != "" {
+				s.comments = copyStrings(comments)
+			}
+			return
+		}
+	}
+	if name == "" {
+		// Global section must be first.
+		f.sections = append(f.sections, section{})
+		copy(f.sections[1:], f.sections)
+		f.sections[0] = section{}
+		return
+	}
+	f.sections = append(f.sections, section{
+		name:     name,
+		comments: copyStrings(comments),
+	})
+}
+
+// appendProperty appends a property with the key, value, and comments of prop
+// to the last section with the given name, which must exist. The source
+// formatting of prop, such as its quoting and export prefix, is discarded,
+// since it may not be valid in f's syntax. An inline comment is kept only if f
+// allows inline comments; otherwise, it is moved above the property.
+func (f *File) appendProperty(sectionName string, prop property) {
+	added := property{
+		key:      prop.key,
+		value:    prop.value,
+		comments: copyStrings(prop.comments),
+	}
+	if prop.inlineComment != "" {
+		if f.inlineComments {
+			added.inlineComment = prop.inlineComment
+		} else {
+			added.comments = append(added.comments, prop.inlineComment)
+		}
+	}
+	for i := len(f.sections) - 1; i >= 0; i-- {
+		if s := &f.sections[i]; s.name == sectionName {
+			s.properties = append(s.properties, added)
+			return
+		}
+	}
+	panic("section " + sectionName + " not found")
+}
+
+// overwrite replaces the values of the key in f with its values in other.
+// Properties in f without comments take the comments of the corresponding
+// property in other.
+func (f *File) overwrite(other *File, sectionName, key string) {
+	var src []*property
+	for i := range other.sections {
+		s := &other.sections[i]
+		if s.name != sectionName {
+			continue
+		}
+		for j := range s.properties {
+			if p := &s.properties[j]; p.key == key {
+				src = append(src, p)
+			}
+		}
+	}
+	values := make([]string, len(src))
+	for i, p := range src {
+		values[i] = p.value
+	}
+	f.replaceAll(sectionName, key, values)
+	n := 0
+	for i := range f.sections {
+		s := &f.sections[i]
+		if s.name != sectionName {
+			continue
+		}
+		for j := range s.properties {
+			p := &s.properties[j]
+			if p.key != key {
+				continue
+			}
+			if len(p.comments) == 0 && n < len(src) {
+				p.comments = copyStrings(src[n].comments)
+			}
+			n++
+		}
+	}
+}
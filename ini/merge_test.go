@@ -0,0 +1,180 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMerge(t *testing.T) {
+	const base = "; Template\n" +
+		"name=template\n" +
+		"\n" +
+		"[server]\n" +
+		"host=a\n" +
+		"host=b\n" +
+		"port=80\n" +
+		"; Trailing template comment\n"
+	const user = "name=user\n" +
+		"; User's debug flag\n" +
+		"debug=true\n" +
+		"\n" +
+		"; Server settings\n" +
+		"[server]\n" +
+		"; Only one host\n" +
+		"host=c\n" +
+		"\n" +
+		"; New section\n" +
+		"[client]\n" +
+		"retries=3\n" +
+		"; Trailing user comment\n"
+	tests := []struct {
+		policy MergePolicy
+		want   string
+	}{
+		{
+			policy: MergeOverwrite,
+			want: "; Template\n" +
+				"name=user\n" +
+				"; User's debug flag\n" +
+				"debug=true\n" +
+				"\n" +
+				"; Server settings\n" +
+				"[server]\n" +
+				"; Only one host\n" +
+				"host=c\n" +
+				"port=80\n" +
+				"\n" +
+				"; New section\n" +
+				"[client]\n" +
+				"retries=3\n" +
+				"\n" +
+				"; Trailing template comment\n" +
+				"; Trailing user comment\n",
+		},
+		{
+			policy: MergeKeepExisting,
+			want: "; Template\n" +
+				"name=template\n" +
+				"; User's debug flag\n" +
+				"debug=true\n" +
+				"\n" +
+				"; Server settings\n" +
+				"[server]\n" +
+				"host=a\n" +
+				"host=b\n" +
+				"port=80\n" +
+				"\n" +
+				"; New section\n" +
+				"[client]\n" +
+				"retries=3\n" +
+				"\n" +
+				"; Trailing template comment\n" +
+				"; Trailing user comment\n",
+		},
+		{
+			policy: MergeAppend,
+			want: "; Template\n" +
+				"name=template\n" +
+				"name=user\n" +
+				"; User's debug flag\n" +
+				"debug=true\n" +
+				"\n" +
+				"; Server settings\n" +
+				"[server]\n" +
+				"host=a\n" +
+				"host=b\n" +
+				"port=80\n" +
+				"; Only one host\n" +
+				"host=c\n" +
+				"\n" +
+				"; New section\n" +
+				"[client]\n" +
+				"retries=3\n" +
+				"\n" +
+				"; Trailing template comment\n" +
+				"; Trailing user comment\n",
+		},
+	}
+	for _, test := range tests {
+		f, err := Parse(strings.NewReader(base), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := Parse(strings.NewReader(user), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherBefore, err := other.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Merge(other, test.policy)
+		got, err := f.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.want, string(got)); diff != "" {
+			t.Errorf("Merge with policy %d (-want +got):\n%s", test.policy, diff)
+		}
+
+		// other must not be modified.
+		otherAfter, err := other.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(string(otherBefore), string(otherAfter)); diff != "" {
+			t.Errorf("Merge with policy %d modified other (-want +got):\n%s", test.policy, diff)
+		}
+	}
+}
+
+func TestMergeIntoEmpty(t *testing.T) {
+	other, err := Parse(strings.NewReader("a=1\n[s]\nb=2\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := new(File)
+	f.Merge(other, MergeOverwrite)
+	f.Merge(nil, MergeOverwrite)
+	if !f.EqualFold(other, nil) {
+		got, _ := f.MarshalText()
+		t.Errorf("merging into empty file produced:\n%s", got)
+	}
+}
+
+func TestMergeFormatting(t *testing.T) {
+	other, err := Parse(strings.NewReader("export A = 'x y' ; note\n"), &ParseOptions{
+		AllowExport:         true,
+		AllowSingleQuotes:   true,
+		AllowInlineComments: true,
+		PreserveFormatting:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(strings.NewReader("b=2\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Merge(other, MergeOverwrite)
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "b=2\n; note\nA=x y\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalText after Merge (-want +got):\n%s", diff)
+	}
+	reparsed, err := Parse(strings.NewReader(string(got)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := reparsed.Get("", "A"), "x y"; got != want {
+		t.Errorf("after round trip, Get(\"\", \"A\") = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseOptionsFromFile reads a set of parse options from an INI file at the
+// given path. This allows a tool to let its users configure how their files
+// are parsed. The options file may only contain the following properties,
+// outside of any section:
+//
+//	normalize_section        Transformation applied to section names: "none",
+//	                         "lower", or "upper". Defaults to "none".
+//	normalize_key            Transformation applied to keys: "none", "lower",
+//	                         or "upper". Defaults to "none".
+//	collapse_key_whitespace  Boolean. Sets CollapseKeyWhitespace.
+//	keep_raw_value           Boolean. Sets KeepRawValue.
+//	directive_prefix         String. Sets DirectivePrefix.
+//	preserve_leading_lines   Non-negative integer. Sets PreserveLeadingLines.
+//	reject_tabs              Boolean. Sets RejectTabs.
+//	allow_line_continuation  Boolean. Sets AllowLineContinuation.
+//	allow_inline_comments    Boolean. Sets AllowInlineComments.
+//	preserve_formatting      Boolean. Sets PreserveFormatting.
+//	preserve_blank_lines     Boolean. Sets PreserveBlankLines.
+//	expand_env               Boolean. Sets ExpandEnv.
+//	allow_single_quotes      Boolean. Sets AllowSingleQuotes.
+//	allow_export             Boolean. Sets AllowExport.
+//	allow_bare_keys          Boolean. Sets AllowBareKeys.
+//	systemd                  Boolean. Sets Systemd.
+//	default_section          String. Sets DefaultSection.
+//
+// Boolean values are parsed with strconv.ParseBool. Unknown keys, sections,
+// and invalid values are reported as errors. If there is more than one such
+// error, the error for the key that sorts first is reported.
+func ParseOptionsFromFile(path string) (*ParseOptions, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("parse options from file: %w", err)
+	}
+	defer f.Close()
+	opts, err := readParseOptions(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse options from file: %s: %w", path, err)
+	}
+	return opts, nil
+}
+
+func readParseOptions(r io.Reader) (*ParseOptions, error) {
+	f, err := Parse(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.HasSections() {
+		return nil, errors.New("sections not allowed")
+	}
+	opts := new(ParseOptions)
+	keys := f.Keys("")
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := f.Get("", key)
+		switch key {
+		case "normalize_section":
+			fn, err := namedTransform(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			opts.NormalizeSection = fn
+		case "normalize_key":
+			fn, err := namedTransform(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			if fn != nil {
+				opts.NormalizeKey = func(section, key string) string {
+					return fn(key)
+				}
+			}
+		case "collapse_key_whitespace":
+			opts.CollapseKeyWhitespace, err = strconv.ParseBool(value)
+		case "keep_raw_value":
+			opts.KeepRawValue, err = strconv.ParseBool(value)
+		case "directive_prefix":
+			opts.DirectivePrefix = value
+		case "preserve_leading_lines":
+			opts.PreserveLeadingLines, err = strconv.Atoi(value)
+			if err == nil && opts.PreserveLeadingLines < 0 {
+				err = errors.New("negative line count")
+			}
+		case "reject_tabs":
+			opts.RejectTabs, err = strconv.ParseBool(value)
+		case "allow_line_continuation":
+			opts.AllowLineContinuation, err = strconv.ParseBool(value)
+		case "allow_inline_comments":
+			opts.AllowInlineComments, err = strconv.ParseBool(value)
+		case "preserve_formatting":
+			opts.PreserveFormatting, err = strconv.ParseBool(value)
+		case "preserve_blank_lines":
+			opts.PreserveBlankLines, err = strconv.ParseBool(value)
+		case "expand_env":
+			opts.ExpandEnv, err = strconv.ParseBool(value)
+		case "allow_single_quotes":
+			opts.AllowSingleQuotes, err = strconv.ParseBool(value)
+		case "allow_export":
+			opts.AllowExport, err = strconv.ParseBool(value)
+		case "allow_bare_keys":
+			opts.AllowBareKeys, err = strconv.ParseBool(value)
+		case "systemd":
+			opts.Systemd, err = strconv.ParseBool(value)
+		case "default_section":
+			opts.DefaultSection = value
+		default:
+			return nil, fmt.Errorf("unknown option %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return opts, nil
+}
+
+// namedTransform returns the text transformation with the given name.
+// It returns nil for "none".
+func namedTransform(name string) (func(string) string, error) {
+	switch name {
+	case "none":
+		return nil, nil
+	case "lower":
+		return strings.ToLower, nil
+	case "upper":
+		return strings.ToUpper, nil
+	default:
+		return nil, fmt.Errorf("unknown transformation %q", name)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseOptionsFromFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Empty",
+			options: "",
+			source:  "[Foo]\nBar  Baz=1\n",
+			want:    "[Foo]\nBar  Baz=1\n",
+		},
+		{
+			name:    "Lower",
+			options: "normalize_section = lower\nnormalize_key = lower\n",
+			source:  "[Foo]\nBar=1\n",
+			want:    "[foo]\nbar=1\n",
+		},
+		{
+			name:    "UpperAndCollapse",
+			options: "normalize_key = upper\ncollapse_key_whitespace = true\n",
+			source:  "[Foo]\nBar  Baz=1\n",
+			want:    "[Foo]\nBAR BAZ=1\n",
+		},
+		{
+			name:    "None",
+			options: "normalize_section = none\nnormalize_key = none\ncollapse_key_whitespace = false\n",
+			source:  "[Foo]\nBar  Baz=1\n",
+			want:    "[Foo]\nBar  Baz=1\n",
+		},
+		{
+			name:    "DirectivePrefix",
+			options: "directive_prefix = %\n",
+			source:  "%include x\nfoo=1\n",
+			want:    "%include x\nfoo=1\n",
+		},
+		{
+			name:    "UnknownKey",
+			options: "frobnicate = true\n",
+			wantErr: true,
+		},
+		{
+			name:    "UnknownTransform",
+			options: "normalize_key = title\n",
+			wantErr: true,
+		},
+		{
+			name:    "BadBool",
+			options: "keep_raw_value = maybe\n",
+			wantErr: true,
+		},
+		{
+			name:    "Section",
+			options: "[options]\nkeep_raw_value = true\n",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "options.ini")
+			if err := ioutil.WriteFile(path, []byte(test.options), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			opts, err := ParseOptionsFromFile(path)
+			if err != nil {
+				t.Log("ParseOptionsFromFile:", err)
+				if !test.wantErr {
+					t.Fail()
+				}
+				return
+			}
+			if test.wantErr {
+				t.Fatal("ParseOptionsFromFile did not return an error")
+			}
+			f, err := Parse(strings.NewReader(test.source), opts)
+			if err != nil {
+				t.Fatal("Parse:", err)
+			}
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal("MarshalText:", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("MarshalText = %q; want %q", got, test.want)
+			}
+		})
+	}
+
+	t.Run("RejectTabs", func(t *testing.T) {
+		opts, err := readParseOptions(strings.NewReader("reject_tabs = true\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !opts.RejectTabs {
+			t.Error("opts.RejectTabs = false; want true")
+		}
+	})
+
+	t.Run("KeepRawValue", func(t *testing.T) {
+		opts, err := readParseOptions(strings.NewReader("keep_raw_value = true\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !opts.KeepRawValue {
+			t.Error("opts.KeepRawValue = false; want true")
+		}
+	})
+
+	t.Run("AllOptions", func(t *testing.T) {
+		const source = "collapse_key_whitespace = true\n" +
+			"keep_raw_value = true\n" +
+			"directive_prefix = %\n" +
+			"preserve_leading_lines = 2\n" +
+			"reject_tabs = true\n" +
+			"allow_line_continuation = true\n" +
+			"allow_inline_comments = true\n" +
+			"preserve_formatting = true\n" +
+			"preserve_blank_lines = true\n" +
+			"expand_env = true\n" +
+			"allow_single_quotes = true\n" +
+			"allow_export = true\n" +
+			"allow_bare_keys = true\n" +
+			"systemd = true\n" +
+			"default_section = DEFAULT\n"
+		opts, err := readParseOptions(strings.NewReader(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &ParseOptions{
+			CollapseKeyWhitespace: true,
+			KeepRawValue:          true,
+			DirectivePrefix:       "%",
+			PreserveLeadingLines:  2,
+			RejectTabs:            true,
+			AllowLineContinuation: true,
+			AllowInlineComments:   true,
+			PreserveFormatting:    true,
+			PreserveBlankLines:    true,
+			ExpandEnv:             true,
+			AllowSingleQuotes:     true,
+			AllowExport:           true,
+			AllowBareKeys:         true,
+			Systemd:               true,
+			DefaultSection:        "DEFAULT",
+		}
+		if diff := cmp.Diff(want, opts); diff != "" {
+			t.Errorf("options (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("NegativeLeadingLines", func(t *testing.T) {
+		if _, err := readParseOptions(strings.NewReader("preserve_leading_lines = -1\n")); err == nil {
+			t.Error("readParseOptions did not return an error")
+		}
+	})
+
+	t.Run("ErrorOrder", func(t *testing.T) {
+		// The first key in sorted order is reported, regardless of file order.
+		_, err := readParseOptions(strings.NewReader("zzz = 1\nsystemd = maybe\naaa = 1\n"))
+		if err == nil || !strings.Contains(err.Error(), `"aaa"`) {
+			t.Errorf("readParseOptions error = %v; want error about \"aaa\"", err)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		_, err := ParseOptionsFromFile(filepath.Join(t.TempDir(), "nonexistent.ini"))
+		if err == nil {
+			t.Error("ParseOptionsFromFile did not return an error")
+		}
+	})
+}
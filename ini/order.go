@@ -0,0 +1,74 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import "sort"
+
+// MoveSection moves the named section so that it is at the given index among
+// the distinct section names of f, in order of first appearance. Comments
+// attached to the section and its properties move with it. If the section
+// appears under multiple headers, all of them are moved and placed together,
+// keeping their relative order. The global section always stays first and
+// cannot be moved. MoveSection reports whether the section exists; if it does
+// not, the file is not modified. MoveSection will panic if the section exists
+// and index is out of range.
+func (f *File) MoveSection(name string, index int) bool {
+	names := f.sectionNames()
+	pos := -1
+	for i, n := range names {
+		if n == name {
+			pos = i
+			break
+		}
+	}
+	if pos == -1 {
+		return false
+	}
+	if index < 0 || index >= len(names) {
+		panic("File.MoveSection index out of range")
+	}
+	copy(names[pos:], names[pos+1:])
+	copy(names[index+1:], names[index:len(names)-1])
+	names[index] = name
+	f.reorderSections(names)
+	return true
+}
+
+// SortSections sorts the sections of f by name using less, keeping the
+// comments attached to each section and its properties. The sort is stable.
+// Sections that appear under multiple headers are placed together, keeping
+// their relative order. The global section always stays first.
+func (f *File) SortSections(less func(a, b string) bool) {
+	names := f.sectionNames()
+	sort.SliceStable(names, func(i, j int) bool {
+		return less(names[i], names[j])
+	})
+	f.reorderSections(names)
+}
+
+// sectionNames returns the distinct names of the named sections of f in
+// order of first appearance.
+func (f *File) sectionNames() []string {
+	var names []string
+	seen := make(map[string]struct{})
+	for _, s := range f.sections {
+		if _, dup := seen[s.name]; s.name == "" || dup {
+			continue
+		}
+		seen[s.name] = struct{}{}
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// reorderSections rearranges f.sections into the order given by names. See
+// MarshalOptions.SectionOrder for details.
+func (f *File) reorderSections(names []string) {
+	f.beforeChange()
+	sections := make([]section, 0, len(f.sections))
+	for _, i := range f.sectionOrder(names) {
+		sections = append(sections, f.sections[i])
+	}
+	f.sections = sections
+}
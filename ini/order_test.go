@@ -0,0 +1,145 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const orderSource = "top=1\n" +
+	"; About c\n" +
+	"[c]\n" +
+	"c=1\n" +
+	"[a]\n" +
+	"; About a\n" +
+	"a=1\n" +
+	"[b]\n" +
+	"b=1\n" +
+	"[c]\n" +
+	"c=2\n"
+
+func TestMoveSection(t *testing.T) {
+	tests := []struct {
+		name    string
+		section string
+		index   int
+		want    string
+		wantOK  bool
+	}{
+		{
+			name:    "ToFront",
+			section: "b",
+			index:   0,
+			want: "top=1\n" +
+				"\n" +
+				"[b]\n" +
+				"b=1\n" +
+				"\n" +
+				"; About c\n" +
+				"[c]\n" +
+				"c=1\n" +
+				"\n" +
+				"[c]\n" +
+				"c=2\n" +
+				"\n" +
+				"[a]\n" +
+				"; About a\n" +
+				"a=1\n",
+			wantOK: true,
+		},
+		{
+			name:    "Repeated",
+			section: "c",
+			index:   2,
+			want: "top=1\n" +
+				"\n" +
+				"[a]\n" +
+				"; About a\n" +
+				"a=1\n" +
+				"\n" +
+				"[b]\n" +
+				"b=1\n" +
+				"\n" +
+				"; About c\n" +
+				"[c]\n" +
+				"c=1\n" +
+				"\n" +
+				"[c]\n" +
+				"c=2\n",
+			wantOK: true,
+		},
+		{
+			name:    "Missing",
+			section: "missing",
+			index:   0,
+			want: "top=1\n" +
+				"\n" +
+				"; About c\n" +
+				"[c]\n" +
+				"c=1\n" +
+				"\n" +
+				"[a]\n" +
+				"; About a\n" +
+				"a=1\n" +
+				"\n" +
+				"[b]\n" +
+				"b=1\n" +
+				"\n" +
+				"[c]\n" +
+				"c=2\n",
+			wantOK: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(orderSource), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok := f.MoveSection(test.section, test.index); ok != test.wantOK {
+				t.Errorf("f.MoveSection(%q, %d) = %t; want %t", test.section, test.index, ok, test.wantOK)
+			}
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("MarshalText (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSortSections(t *testing.T) {
+	f, err := Parse(strings.NewReader(orderSource), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SortSections(func(a, b string) bool { return a < b })
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "top=1\n" +
+		"\n" +
+		"[a]\n" +
+		"; About a\n" +
+		"a=1\n" +
+		"\n" +
+		"[b]\n" +
+		"b=1\n" +
+		"\n" +
+		"; About c\n" +
+		"[c]\n" +
+		"c=1\n" +
+		"\n" +
+		"[c]\n" +
+		"c=2\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("MarshalText (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"sort"
+	"strings"
+)
+
+// GetPath returns the value of the property named by a dotted path of the
+// form "section.key". The path is split at its last dot: everything before it
+// is the section name and everything after it is the key. A path without a dot
+// names a key in the global section. Because section names may contain dots,
+// "a.b.c" refers to key "c" in section "a.b". Keys that contain dots cannot be
+// addressed by path; use Get instead.
+func (f *File) GetPath(path string) string {
+	section, key := splitPath(path)
+	return f.Get(section, key)
+}
+
+// HasPath reports whether the property named by the dotted path is defined.
+// See GetPath for how the path is split.
+func (f *File) HasPath(path string) bool {
+	section, key := splitPath(path)
+	return f.Has(section, key)
+}
+
+// SetPath sets the property named by the dotted path to the given value as if
+// by Set. See GetPath for how the path is split. SetPath will panic if the
+// resulting section name or key is invalid.
+func (f *File) SetPath(path, value string) {
+	section, key := splitPath(path)
+	f.Set(section, key, value)
+}
+
+// FlatMap returns a map from flattened keys to values for every property in
+// the file. Properties in a named section are keyed by the section name, sep,
+// and the property key; properties in the global section are keyed by the
+// property key alone. If a key has multiple values, the map holds the last one.
+func (f *File) FlatMap(sep string) map[string]string {
+	if f == nil {
+		return nil
+	}
+	m := make(map[string]string)
+	for _, s := range f.sections {
+		prefix := ""
+		if s.name != "" {
+			prefix = s.name + sep
+		}
+		for _, p := range s.properties {
+			m[prefix+p.key] = p.value
+		}
+	}
+	return m
+}
+
+// FromFlatMap returns a new file with the properties in m, reversing FlatMap.
+// Each key of m is split at the last occurrence of sep into a section name and
+// a property key; keys without sep are placed in the global section. Sections
+// and properties are added in sorted key order. FromFlatMap panics if sep is
+// empty or if splitting a key produces an invalid section name or key.
+func FromFlatMap(m map[string]string, sep string) *File {
+	if sep == "" {
+		panic("ini.FromFlatMap: empty separator")
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	f := new(File)
+	for _, k := range keys {
+		section, key := "", k
+		if i := strings.LastIndex(k, sep); i != -1 {
+			section, key = k[:i], k[i+len(sep):]
+		}
+		f.Set(section, key, m[k])
+	}
+	return f
+}
+
+// splitPath splits a dotted path at its last dot into a section name and key.
+func splitPath(path string) (section, key string) {
+	i := strings.LastIndexByte(path, '.')
+	if i == -1 {
+		return "", path
+	}
+	return path[:i], path[i+1:]
+}
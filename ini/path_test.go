@@ -0,0 +1,119 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPath(t *testing.T) {
+	const source = "global=g\n" +
+		"[build]\n" +
+		"cmd=make\n" +
+		"[remote.origin]\n" +
+		"url=example.com\n" +
+		"[web]\n" +
+		"host.name=localhost\n"
+	tests := []struct {
+		path    string
+		want    string
+		wantHas bool
+	}{
+		{path: "build.cmd", want: "make", wantHas: true},
+		{path: "global", want: "g", wantHas: true},
+		{path: ".global", want: "g", wantHas: true},
+		{path: "remote.origin.url", want: "example.com", wantHas: true},
+		{path: "web.host.name", want: "", wantHas: false},
+		{path: "build.missing", want: "", wantHas: false},
+		{path: "missing", want: "", wantHas: false},
+	}
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		if got := f.GetPath(test.path); got != test.want {
+			t.Errorf("f.GetPath(%q) = %q; want %q", test.path, got, test.want)
+		}
+		if got := f.HasPath(test.path); got != test.wantHas {
+			t.Errorf("f.HasPath(%q) = %t; want %t", test.path, got, test.wantHas)
+		}
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	f, err := Parse(strings.NewReader("[build]\ncmd=make\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SetPath("build.cmd", "go build")
+	f.SetPath("top", "1")
+	f.SetPath("remote.origin.url", "example.com")
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "top=1\n" +
+		"\n[build]\ncmd=go build\n" +
+		"\n[remote.origin]\nurl=example.com\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("after SetPath (-want +got):\n%s", diff)
+	}
+}
+
+func TestFlatMap(t *testing.T) {
+	const source = "global=g\n" +
+		"[build]\n" +
+		"cmd=make\n" +
+		"tags=a\n" +
+		"tags=b\n" +
+		"[remote.origin]\n" +
+		"url=example.com\n" +
+		"[build]\n" +
+		"cmd=go build\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := f.FlatMap(".")
+	want := map[string]string{
+		"global":            "g",
+		"build.cmd":         "go build",
+		"build.tags":        "b",
+		"remote.origin.url": "example.com",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("f.FlatMap(\".\") (-want +got):\n%s", diff)
+	}
+
+	if got := (*File)(nil).FlatMap("."); len(got) > 0 {
+		t.Errorf("(*File)(nil).FlatMap(\".\") = %v; want empty", got)
+	}
+}
+
+func TestFromFlatMap(t *testing.T) {
+	m := map[string]string{
+		"global":                   "g",
+		"build/cmd":                "make",
+		"remote/origin/url":        "example.com",
+		"remote/origin/fetch.tags": "yes",
+	}
+	f := FromFlatMap(m, "/")
+	got, err := f.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "global=g\n" +
+		"\n[build]\ncmd=make\n" +
+		"\n[remote/origin]\nfetch.tags=yes\nurl=example.com\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("FromFlatMap(m, \"/\").MarshalText() (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(m, f.FlatMap("/")); diff != "" {
+		t.Errorf("FlatMap after FromFlatMap (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+// SectionLine returns the 1-based line number of the first header for the
+// named section in the source read by Parse. It returns zero if the section
+// does not exist or was added after parsing. The global section has no
+// header, so SectionLine("") always returns zero.
+func (f *File) SectionLine(name string) int {
+	if f == nil || name == "" {
+		return 0
+	}
+	for _, s := range f.sections {
+		if s.name == name {
+			return s.line
+		}
+	}
+	return 0
+}
+
+// KeyLine returns the 1-based line number of the last property with the given
+// key in the given section in the source read by Parse, which is the property
+// whose value Get returns. If the value was continued across multiple lines,
+// KeyLine returns the number of the first line. KeyLine returns zero if the
+// property does not exist or was added after parsing. Setting the value of a
+// parsed property does not change its line number.
+func (f *File) KeyLine(section, key string) int {
+	prop := f.last(section, key)
+	if prop == nil {
+		return 0
+	}
+	return prop.line
+}
+
+// KeyLines returns the 1-based line numbers of every property with the given
+// key in the given section, in the same order as the values returned by
+// Find. Properties added after parsing have a line number of zero. Unlike
+// Find, KeyLines does not consider defaults.
+func (f *File) KeyLines(section, key string) []int {
+	if f == nil {
+		return nil
+	}
+	var lines []int
+	for _, s := range f.sections {
+		if s.name != section {
+			continue
+		}
+		for _, p := range s.properties {
+			if p.key != key {
+				continue
+			}
+			if f.systemd && p.value == "" {
+				// Empty assignments reset the list. See ParseOptions.Systemd.
+				lines = lines[:0]
+				continue
+			}
+			lines = append(lines, p.line)
+		}
+	}
+	return lines
+}
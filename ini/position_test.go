@@ -0,0 +1,73 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPositions(t *testing.T) {
+	const source = "# Leading\n" +
+		"top=1\n" +
+		"\n" +
+		"[server]\n" +
+		"host=a\n" +
+		"long=one \\\n" +
+		"  two\n" +
+		"host=b\n" +
+		"[other]\n" +
+		"[server]\n" +
+		"host=c\n"
+	f, err := Parse(strings.NewReader(source), &ParseOptions{
+		PreserveLeadingLines:  1,
+		AllowLineContinuation: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectionTests := []struct {
+		name string
+		want int
+	}{
+		{"", 0},
+		{"server", 4},
+		{"other", 9},
+		{"missing", 0},
+	}
+	for _, test := range sectionTests {
+		if got := f.SectionLine(test.name); got != test.want {
+			t.Errorf("f.SectionLine(%q) = %d; want %d", test.name, got, test.want)
+		}
+	}
+	keyTests := []struct {
+		section string
+		key     string
+		want    int
+	}{
+		{"", "top", 2},
+		{"server", "host", 11},
+		{"server", "long", 6},
+		{"server", "missing", 0},
+	}
+	for _, test := range keyTests {
+		if got := f.KeyLine(test.section, test.key); got != test.want {
+			t.Errorf("f.KeyLine(%q, %q) = %d; want %d", test.section, test.key, got, test.want)
+		}
+	}
+	if diff := cmp.Diff([]int{5, 8, 11}, f.KeyLines("server", "host")); diff != "" {
+		t.Errorf("f.KeyLines(\"server\", \"host\") (-want +got):\n%s", diff)
+	}
+
+	f.Set("", "top", "2")
+	f.Set("new", "key", "value")
+	if got, want := f.KeyLine("", "top"), 2; got != want {
+		t.Errorf("after Set, f.KeyLine(\"\", \"top\") = %d; want %d", got, want)
+	}
+	if got := f.KeyLine("new", "key"); got != 0 {
+		t.Errorf("f.KeyLine(\"new\", \"key\") = %d; want 0", got)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Filename returns the path that f was read from by ParseFiles or the path
+// set by SetFilename. It returns the empty string if f has no path.
+func (f *File) Filename() string {
+	if f == nil {
+		return ""
+	}
+	return f.path
+}
+
+// SetFilename sets the path that FileSet.Save and FileSet.SaveModified write f
+// to. This is useful for files that did not exist when the set was parsed,
+// since ParseFiles returns nil for them. SetFilename does not read or write the
+// file, and f is always considered modified after its path is changed.
+func (f *File) SetFilename(path string) {
+	f.path = path
+	f.saved = false
+	f.hasSavedSum = false
+}
+
+// beforeChange must be called by methods that change f before they make any
+// changes. If f's contents match the file at its path and have not been
+// changed since, beforeChange records a hash of them for modified to compare
+// against.
+func (f *File) beforeChange() {
+	if !f.saved || f.hasSavedSum {
+		return
+	}
+	text, err := f.MarshalText()
+	if err != nil {
+		// Treat the file as modified so that saving reports the error.
+		f.saved = false
+		return
+	}
+	f.savedSum = sha256.Sum256(text)
+	f.hasSavedSum = true
+}
+
+// modified reports whether f's contents differ from when it was last read
+// from or written to its path.
+func (f *File) modified() bool {
+	if !f.saved {
+		return true
+	}
+	if !f.hasSavedSum {
+		// Not changed since it was read.
+		return false
+	}
+	text, err := f.MarshalText()
+	if err != nil {
+		// Let save report the error.
+		return true
+	}
+	return sha256.Sum256(text) != f.savedSum
+}
+
+// Save writes every file in the set that has a path back to its path with
+// MarshalText. Files without a path and nil elements of the set are skipped.
+// Each file is written atomically by writing to a temporary file in the same
+// directory and renaming it over the original, preserving the original's
+// permissions if it exists. New files are created with mode 0600. If a path is
+// a symbolic link, the file it refers to is written and the link is kept.
+// Save stops on the first error.
+func (fset FileSet) Save() error {
+	return fset.save(false)
+}
+
+// SaveModified is like Save, but only writes files whose contents have
+// changed since they were read by ParseFiles or last written by Save or
+// SaveModified.
+func (fset FileSet) SaveModified() error {
+	return fset.save(true)
+}
+
+func (fset FileSet) save(onlyModified bool) error {
+	for _, f := range fset {
+		if f == nil || f.path == "" || onlyModified && !f.modified() {
+			continue
+		}
+		text, err := f.MarshalText()
+		if err != nil {
+			return fmt.Errorf("save ini files: %s: %w", f.path, err)
+		}
+		if err := writeFileAtomic(f.path, text); err != nil {
+			return fmt.Errorf("save ini files: %w", err)
+		}
+		f.saved = true
+		f.savedSum = sha256.Sum256(text)
+		f.hasSavedSum = true
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data by writing a temporary
+// file and renaming it. If path is a symbolic link, the file it refers to is
+// replaced instead of the link. New files are created with mode 0600, since
+// configuration files often hold credentials.
+func writeFileAtomic(path string, data []byte) (err error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	perm := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name()) // Best effort cleanup.
+		}
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	// Flush the data to disk before the rename makes it visible, so that a
+	// crash cannot leave an empty or partial file at path.
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,191 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFileSetSave(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{
+		filepath.Join(dir, "project.ini"),
+		filepath.Join(dir, "user.ini"),
+		filepath.Join(dir, "system.ini"),
+	}
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readFile := func(path string) string {
+		t.Helper()
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	writeFile(paths[1], "; User settings\ncolor = blue\n")
+	writeFile(paths[2], "color=red\n")
+	fset, err := ParseFiles(nil, paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fset[0] != nil {
+		t.Fatalf("fset[0] = %v; want <nil> for missing file", fset[0])
+	}
+	if got, want := fset[1].Filename(), paths[1]; got != want {
+		t.Errorf("fset[1].Filename() = %q; want %q", got, want)
+	}
+
+	// Changes on disk to an unmodified file are not overwritten.
+	fset[1].Set("", "color", "green")
+	writeFile(paths[2], "color=changed on disk\n")
+	if err := fset.SaveModified(); err != nil {
+		t.Fatal("SaveModified:", err)
+	}
+	if diff := cmp.Diff("; User settings\ncolor=green\n", readFile(paths[1])); diff != "" {
+		t.Errorf("user.ini after SaveModified (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("color=changed on disk\n", readFile(paths[2])); diff != "" {
+		t.Errorf("system.ini after SaveModified (-want +got):\n%s", diff)
+	}
+	if info, err := os.Stat(paths[1]); err != nil {
+		t.Error(err)
+	} else if got, want := info.Mode().Perm(), os.FileMode(0o600); got != want {
+		t.Errorf("user.ini mode = %v; want %v", got, want)
+	}
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Errorf("project.ini exists after SaveModified (err = %v)", err)
+	}
+
+	// A new file can be given a path.
+	fset.Set("", "color", "purple")
+	fset[0].SetFilename(paths[0])
+	if err := fset.SaveModified(); err != nil {
+		t.Fatal("SaveModified:", err)
+	}
+	if diff := cmp.Diff("color=purple\n", readFile(paths[0])); diff != "" {
+		t.Errorf("project.ini after SaveModified (-want +got):\n%s", diff)
+	}
+	if info, err := os.Stat(paths[0]); err != nil {
+		t.Error(err)
+	} else if got, want := info.Mode().Perm(), os.FileMode(0o600); got != want {
+		t.Errorf("new project.ini mode = %v; want %v", got, want)
+	}
+	if diff := cmp.Diff("", readFile(paths[1])); diff != "" {
+		t.Errorf("user.ini after second SaveModified (-want +got):\n%s", diff)
+	}
+
+	// Save writes every file, keeping existing permissions.
+	if err := os.Chmod(paths[2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fset.Save(); err != nil {
+		t.Fatal("Save:", err)
+	}
+	if diff := cmp.Diff("", readFile(paths[2])); diff != "" {
+		t.Errorf("system.ini after Save (-want +got):\n%s", diff)
+	}
+	if info, err := os.Stat(paths[2]); err != nil {
+		t.Error(err)
+	} else if got, want := info.Mode().Perm(), os.FileMode(0o644); got != want {
+		t.Errorf("system.ini mode = %v; want %v", got, want)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(paths) {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("directory contains %q; want only the saved files", names)
+	}
+}
+
+func TestFileSetSaveSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.ini")
+	link := filepath.Join(dir, "link.ini")
+	if err := ioutil.WriteFile(target, []byte("color=red\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("target.ini", link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	fset, err := ParseFiles(nil, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset.Set("", "color", "blue")
+	if err := fset.Save(); err != nil {
+		t.Fatal("Save:", err)
+	}
+	if info, err := os.Lstat(link); err != nil {
+		t.Error(err)
+	} else if info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("link.ini mode = %v after Save; want symlink", info.Mode())
+	}
+	data, err := ioutil.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("color=blue\n", string(data)); diff != "" {
+		t.Errorf("target.ini after Save (-want +got):\n%s", diff)
+	}
+	if info, err := os.Stat(target); err != nil {
+		t.Error(err)
+	} else if got, want := info.Mode().Perm(), os.FileMode(0o644); got != want {
+		t.Errorf("target.ini mode = %v; want %v", got, want)
+	}
+}
+
+func TestFileSetSaveModifiedTracking(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "unit.ini")
+	const source = "[Service]\nExecStart=/bin/true\n"
+	if err := ioutil.WriteFile(path, []byte(source), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fset, err := ParseFiles(&ParseOptions{Systemd: true}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := fset[0]
+	if f.hasSavedSum {
+		t.Error("ParseFiles marshaled the file before it was changed")
+	}
+
+	// Changes that leave the contents the same do not count.
+	f.Delete("Service", "Missing")
+	f.Set("Service", "ExecStart", "/bin/true")
+	if f.modified() {
+		t.Error("f.modified() = true after no-op changes; want false")
+	}
+
+	// Marshaling errors are reported rather than treating the file as saved.
+	f.Set("Service", "ExecStart", "a\nb")
+	if !f.modified() {
+		t.Error("f.modified() = false after invalid change; want true")
+	}
+	if err := fset.SaveModified(); err == nil {
+		t.Error("SaveModified with unmarshalable value did not return an error")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(source, string(data)); diff != "" {
+		t.Errorf("unit.ini after failed SaveModified (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"fmt"
+	"io"
+)
+
+// A Scanner answers queries about an INI file by reading through it for each
+// query instead of holding its properties in memory like a File. This is
+// useful for inspecting very large files, at the cost of each query taking
+// time proportional to the size of the file. A Scanner is not safe to use
+// from multiple goroutines concurrently.
+type Scanner struct {
+	r    io.ReadSeeker
+	opts ParseOptions
+}
+
+// NewScanner returns a Scanner that reads from r. Each query seeks r to its
+// start. Nil options are treated identically as passing the zero value.
+// Options are interpreted as by NewDecoder, and ParseOptions.Systemd and
+// ParseOptions.DefaultSection affect lookups the same way as for a File.
+func NewScanner(r io.ReadSeeker, opts *ParseOptions) *Scanner {
+	sc := &Scanner{r: r}
+	if opts != nil {
+		sc.opts = *opts
+	}
+	return sc
+}
+
+// Get returns the last value associated with the given key in the given
+// section, like File.Get. If there are no values associated with the key, Get
+// returns the empty string. Get returns an error if the file cannot be read
+// or is malformed.
+func (sc *Scanner) Get(section, key string) (string, error) {
+	values, err := sc.Find(section, key)
+	if err != nil || len(values) == 0 {
+		return "", err
+	}
+	return values[len(values)-1], nil
+}
+
+// Has reports whether the given section has at least one property with the
+// given key, like File.Has. Has stops reading at the first such property.
+func (sc *Scanner) Has(section, key string) (bool, error) {
+	found := false
+	err := sc.scan(func(p Property) bool {
+		found = p.Section == section && p.Key == key
+		return !found
+	})
+	if err != nil {
+		return false, err
+	}
+	return found, nil
+}
+
+// Find returns all the values associated with the given key in the given
+// section, like File.Find. Only the values for the key are held in memory.
+func (sc *Scanner) Find(section, key string) ([]string, error) {
+	inherit := sc.opts.DefaultSection != "" && section != "" && section != sc.opts.DefaultSection
+	var values, inherited []string
+	err := sc.scan(func(p Property) bool {
+		if p.Key != key {
+			return true
+		}
+		switch {
+		case p.Section == section:
+			values = appendFoundValue(values, p.Value, sc.opts.Systemd)
+		case inherit && p.Section == sc.opts.DefaultSection:
+			inherited = appendFoundValue(inherited, p.Value, sc.opts.Systemd)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return inherited, nil
+	}
+	return values, nil
+}
+
+// appendFoundValue appends a value to a list of values for a key, treating an
+// empty value as a reset if systemd is true. See ParseOptions.Systemd.
+func appendFoundValue(values []string, v string, systemd bool) []string {
+	if systemd && v == "" {
+		return values[:0]
+	}
+	return append(values, v)
+}
+
+// scan calls fn for each property in the file until fn returns false.
+func (sc *Scanner) scan(fn func(Property) bool) error {
+	if _, err := sc.r.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("scan ini file: %w", err)
+	}
+	d := NewDecoder(sc.r, &sc.opts)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if p, ok := tok.(Property); ok && !fn(p) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestScanner(t *testing.T) {
+	const source = "top=1\n" +
+		"[DEFAULT]\n" +
+		"user=root\n" +
+		"[server]\n" +
+		"host=a\n" +
+		"empty=\n" +
+		"[other]\n" +
+		"host=x\n" +
+		"[server]\n" +
+		"host=b\n"
+	opts := &ParseOptions{DefaultSection: "DEFAULT"}
+	f, err := Parse(strings.NewReader(source), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := NewScanner(strings.NewReader(source), opts)
+	queries := []struct {
+		section string
+		key     string
+	}{
+		{"", "top"},
+		{"server", "host"},
+		{"server", "empty"},
+		{"server", "user"},
+		{"other", "host"},
+		{"", "user"},
+		{"missing", "host"},
+	}
+	for _, q := range queries {
+		got, err := sc.Get(q.section, q.key)
+		if err != nil {
+			t.Errorf("sc.Get(%q, %q): %v", q.section, q.key, err)
+		} else if want := f.Get(q.section, q.key); got != want {
+			t.Errorf("sc.Get(%q, %q) = %q; want %q", q.section, q.key, got, want)
+		}
+		values, err := sc.Find(q.section, q.key)
+		if err != nil {
+			t.Errorf("sc.Find(%q, %q): %v", q.section, q.key, err)
+		} else if diff := cmp.Diff(f.Find(q.section, q.key), values, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("sc.Find(%q, %q) (-want +got):\n%s", q.section, q.key, diff)
+		}
+		has, err := sc.Has(q.section, q.key)
+		if err != nil {
+			t.Errorf("sc.Has(%q, %q): %v", q.section, q.key, err)
+		} else if want := f.Has(q.section, q.key); has != want {
+			t.Errorf("sc.Has(%q, %q) = %t; want %t", q.section, q.key, has, want)
+		}
+	}
+
+	t.Run("Malformed", func(t *testing.T) {
+		sc := NewScanner(strings.NewReader("a=1\n[bad\n"), nil)
+		_, err := sc.Get("", "a")
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Line != 2 {
+			t.Errorf("sc.Get(\"\", \"a\") error = %v; want *ParseError on line 2", err)
+		}
+	})
+}
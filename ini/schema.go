@@ -0,0 +1,195 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Schema describes the sections and keys that are allowed in a File.
+type Schema struct {
+	// Sections maps section names to the keys allowed in them. The global
+	// section is identified by the empty string.
+	Sections map[string]SectionSchema
+
+	// AllowUnknownSections permits sections that are not listed in
+	// Sections. Their properties are not checked.
+	AllowUnknownSections bool
+
+	// AllowUnknownKeys permits keys that are not listed in their section's
+	// schema.
+	AllowUnknownKeys bool
+}
+
+// A SectionSchema describes the keys allowed in a section.
+type SectionSchema struct {
+	Keys map[string]KeySchema
+}
+
+// A KeySchema describes the values allowed for a key.
+type KeySchema struct {
+	// Required is true if the key must have at least one value.
+	Required bool
+
+	// Validate is called for each of the key's values. If nil, any value is
+	// accepted.
+	Validate Validator
+}
+
+// A Validator checks a property value, returning an error describing why
+// the value is invalid or nil if it is valid.
+type Validator func(value string) error
+
+// MatchRegexp returns a Validator that accepts values that re matches in
+// their entirety.
+func MatchRegexp(re *regexp.Regexp) Validator {
+	anchored := regexp.MustCompile(`^(?:` + re.String() + `)$`)
+	return func(value string) error {
+		if !anchored.MatchString(value) {
+			return fmt.Errorf("%q does not match %v", value, re)
+		}
+		return nil
+	}
+}
+
+// OneOf returns a Validator that accepts only the given values.
+func OneOf(allowed ...string) Validator {
+	return func(value string) error {
+		for _, a := range allowed {
+			if value == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+// IntRange returns a Validator that accepts base-10 integers between min and
+// max, inclusive.
+func IntRange(min, max int) Validator {
+	return func(value string) error {
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		if i < min || i > max {
+			return fmt.Errorf("%d is not between %d and %d", i, min, max)
+		}
+		return nil
+	}
+}
+
+// Validate checks f against the schema. It returns a *ValidationError listing
+// every violation, or nil if f conforms to the schema. Properties are checked
+// in file order, followed by any missing required keys sorted by section and
+// key. A nil file is treated as an empty file.
+func (schema *Schema) Validate(f *File) error {
+	var violations []Violation
+	present := make(map[string]map[string]bool)
+	if f != nil {
+		for _, s := range f.sections {
+			sectSchema, knownSection := schema.Sections[s.name]
+			for _, prop := range s.properties {
+				if present[s.name] == nil {
+					present[s.name] = make(map[string]bool)
+				}
+				present[s.name][prop.key] = true
+				if !knownSection {
+					if !schema.AllowUnknownSections {
+						violations = append(violations, Violation{
+							Section: s.name,
+							Key:     prop.key,
+							Err:     errors.New("unknown section"),
+						})
+					}
+					continue
+				}
+				keySchema, knownKey := sectSchema.Keys[prop.key]
+				if !knownKey {
+					if !schema.AllowUnknownKeys {
+						violations = append(violations, Violation{
+							Section: s.name,
+							Key:     prop.key,
+							Err:     errors.New("unknown key"),
+						})
+					}
+					continue
+				}
+				if keySchema.Validate == nil {
+					continue
+				}
+				if err := keySchema.Validate(prop.value); err != nil {
+					violations = append(violations, Violation{
+						Section: s.name,
+						Key:     prop.key,
+						Err:     err,
+					})
+				}
+			}
+		}
+	}
+
+	var missing []Violation
+	for name, sectSchema := range schema.Sections {
+		for key, keySchema := range sectSchema.Keys {
+			if keySchema.Required && !present[name][key] {
+				missing = append(missing, Violation{
+					Section: name,
+					Key:     key,
+					Err:     errors.New("missing required key"),
+				})
+			}
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Section != missing[j].Section {
+			return missing[i].Section < missing[j].Section
+		}
+		return missing[i].Key < missing[j].Key
+	})
+	violations = append(violations, missing...)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+// ValidationError is the error returned by Schema.Validate.
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Error returns a message listing all of the violations.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Error())
+	}
+	return "validate ini: " + strings.Join(msgs, "; ")
+}
+
+// A Violation is a single problem found by Schema.Validate.
+type Violation struct {
+	Section string
+	Key     string
+	Err     error
+}
+
+// Error returns a message describing the violation with its section and key.
+func (v Violation) Error() string {
+	if v.Section == "" {
+		return fmt.Sprintf("%s: %v", v.Key, v.Err)
+	}
+	return fmt.Sprintf("[%s] %s: %v", v.Section, v.Key, v.Err)
+}
+
+// Unwrap returns v.Err.
+func (v Violation) Unwrap() error {
+	return v.Err
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schema := &Schema{
+		Sections: map[string]SectionSchema{
+			"": {Keys: map[string]KeySchema{
+				"name": {Required: true, Validate: MatchRegexp(regexp.MustCompile(`[a-z]+`))},
+			}},
+			"server": {Keys: map[string]KeySchema{
+				"host":  {Required: true},
+				"port":  {Validate: IntRange(1, 65535)},
+				"mode":  {Validate: OneOf("dev", "prod")},
+				"proto": {Required: true},
+			}},
+		},
+	}
+	tests := []struct {
+		name   string
+		source string
+		schema *Schema
+		want   []string
+	}{
+		{
+			name: "Valid",
+			source: "name=app\n" +
+				"[server]\n" +
+				"host=localhost\n" +
+				"port=8080\n" +
+				"mode=prod\n" +
+				"proto=tcp\n",
+			schema: schema,
+		},
+		{
+			name: "Violations",
+			source: "name=App1\n" +
+				"extra=1\n" +
+				"[server]\n" +
+				"port=http\n" +
+				"port=0\n" +
+				"mode=test\n" +
+				"[other]\n" +
+				"x=y\n",
+			schema: schema,
+			want: []string{
+				`name: "App1" does not match [a-z]+`,
+				`extra: unknown key`,
+				`[server] port: "http" is not an integer`,
+				`[server] port: 0 is not between 1 and 65535`,
+				`[server] mode: "test" is not one of dev, prod`,
+				`[other] x: unknown section`,
+				`[server] host: missing required key`,
+				`[server] proto: missing required key`,
+			},
+		},
+		{
+			name: "AllowUnknown",
+			source: "name=app\n" +
+				"extra=1\n" +
+				"[server]\n" +
+				"host=localhost\n" +
+				"proto=tcp\n" +
+				"[other]\n" +
+				"x=y\n",
+			schema: &Schema{
+				Sections:             schema.Sections,
+				AllowUnknownSections: true,
+				AllowUnknownKeys:     true,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(test.source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = test.schema.Validate(f)
+			if len(test.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate = %v; want *ValidationError", err)
+			}
+			var got []string
+			for _, v := range validationErr.Violations {
+				got = append(got, v.Error())
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("violations (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
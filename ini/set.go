@@ -6,6 +6,7 @@ package ini
 import (
 	"fmt"
 	"os"
+	"sort"
 )
 
 // FileSet is a list of files to obtain configuration from in descending order
@@ -16,7 +17,8 @@ type FileSet []*File
 // If the returned error is nil, the returned file set's length will be the same
 // as the number of arguments. ParseFiles will stop on the first error, but
 // ignores missing file errors, instead filling the corresponding element of the
-// set with a nil *File.
+// set with a nil *File. Each parsed File remembers its path so that it can be
+// written back with FileSet.Save or FileSet.SaveModified.
 func ParseFiles(opts *ParseOptions, paths ...string) (FileSet, error) {
 	fset := make(FileSet, 0, len(paths))
 	for _, p := range paths {
@@ -33,6 +35,8 @@ func ParseFiles(opts *ParseOptions, paths ...string) (FileSet, error) {
 		if err != nil {
 			return fset, fmt.Errorf("parse ini files: %s: %w", p, err)
 		}
+		parsed.path = p
+		parsed.saved = true
 		fset = append(fset, parsed)
 	}
 	return fset, nil
@@ -51,13 +55,56 @@ func (fset FileSet) Get(section, key string) string {
 	return ""
 }
 
+// Has reports whether any file in the set has at least one property with the
+// given key in the given section, even if its value is empty. Passing an empty
+// section name searches for properties outside any section.
+func (fset FileSet) Has(section, key string) bool {
+	for _, f := range fset {
+		if f.Has(section, key) {
+			return true
+		}
+	}
+	return false
+}
+
 // Find returns all the values associated with the given key in the given
 // section. Passing an empty section name searches for properties outside
 // any section.
 func (fset FileSet) Find(section, key string) []string {
 	var values []string
 	for i := len(fset) - 1; i >= 0; i-- {
-		values = append(values, fset[i].Find(section, key)...)
+		values = append(values, fset[i].find(section, key)...)
+	}
+	return values
+}
+
+// GetWithSource returns the value that Get would return along with the index
+// of the file in the set that it came from. If no file has a value for the
+// key, GetWithSource returns ok == false.
+func (fset FileSet) GetWithSource(section, key string) (value string, index int, ok bool) {
+	for i, f := range fset {
+		if v, ok := f.get(section, key); ok {
+			return v, i, true
+		}
+	}
+	return "", -1, false
+}
+
+// A SourcedValue is a value returned by FileSet.FindWithSource along with the
+// index of the file in the set that it came from.
+type SourcedValue struct {
+	Value string
+	Index int
+}
+
+// FindWithSource returns the values that Find would return, in the same
+// order, along with the index of the file each value came from.
+func (fset FileSet) FindWithSource(section, key string) []SourcedValue {
+	var values []SourcedValue
+	for i := len(fset) - 1; i >= 0; i-- {
+		for _, v := range fset[i].find(section, key) {
+			values = append(values, SourcedValue{Value: v, Index: i})
+		}
 	}
 	return values
 }
@@ -89,6 +136,12 @@ func (fset FileSet) HasSections() bool {
 // Section returns a copy of the properties in the named section.
 // Section("") returns the global section: the properties set outside any
 // section.
+//
+// The values for each key are ordered from lowest to highest precedence: values
+// from the last file in the set come first and values from the first file come
+// last. Values from the same file are in the order they appear in the file.
+// Thus the last value for a key is the one that Get returns. Use SectionOrdered
+// to obtain properties in precedence order.
 func (fset FileSet) Section(name string) Section {
 	merged := make(Section)
 	for i := len(fset) - 1; i >= 0; i-- {
@@ -99,6 +152,71 @@ func (fset FileSet) Section(name string) Section {
 	return merged
 }
 
+// SectionOrdered returns the properties in the named section of every file
+// in precedence order: properties from the first file in the set come first,
+// followed by those from the second file, and so on. Properties from the same
+// file are in the order they appear in the file, even if the section is split
+// across multiple headers. SectionOrdered("") returns the properties set
+// outside any section. Nil elements of the set are ignored.
+func (fset FileSet) SectionOrdered(name string) []Property {
+	var props []Property
+	for _, f := range fset {
+		if f == nil {
+			continue
+		}
+		for _, s := range f.sections {
+			if s.name != name {
+				continue
+			}
+			for _, p := range s.properties {
+				props = append(props, Property{
+					Section: s.name,
+					Key:     p.key,
+					Value:   p.value,
+				})
+			}
+		}
+	}
+	return props
+}
+
+// Environ returns the properties in the global section as a list of
+// "KEY=VALUE" strings suitable for exec.Cmd.Env, sorted by key. Each key
+// appears once with the value that Get would return. Keys that are not valid
+// environment variable names (an ASCII letter or underscore followed by
+// ASCII letters, digits, or underscores) are skipped.
+func (fset FileSet) Environ() []string {
+	var keys []string
+	for _, f := range fset {
+		for k := range f.Section("") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var env []string
+	for i, k := range keys {
+		if i > 0 && keys[i-1] == k || !isEnvName(k) {
+			continue
+		}
+		env = append(env, k+"="+fset.Get("", k))
+	}
+	return env
+}
+
+// isEnvName reports whether name is a portable environment variable name.
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
 // Set sets the property on the first file and deletes the property in all
 // subsequent files. Set will panic if len(fset) == 0, IsValidSection(sectionName)
 // reports false, or IsValidKey(key) reports false.
@@ -113,6 +231,23 @@ func (fset FileSet) Set(sectionName, key, value string) {
 	fset[1:].Delete(sectionName, key)
 }
 
+// SetAt sets the property on fset[index] and deletes the property in all
+// files with higher precedence (fset[:index]) so that the new value takes
+// effect. Files with lower precedence are left unmodified, since their values
+// are shadowed. SetAt(0, ...) differs from Set only in leaving lower-precedence
+// files unmodified. SetAt will panic if index is out of range,
+// IsValidSection(sectionName) reports false, or IsValidKey(key) reports false.
+//
+// If fset[index] == nil, SetAt allocates a new File. Any other nil files in the
+// set will be ignored.
+func (fset FileSet) SetAt(index int, sectionName, key, value string) {
+	if fset[index] == nil {
+		fset[index] = new(File)
+	}
+	fset[index].Set(sectionName, key, value)
+	fset[:index].Delete(sectionName, key)
+}
+
 // Delete deletes any property with the given key in sections with the given
 // name. If this causes any sections that do not have comments attached to
 // become empty, then those sections will be removed. Nil elements of the set
@@ -136,3 +271,91 @@ func (fset FileSet) Add(sectionName, key string, values []string) {
 	}
 	fset[0].Add(sectionName, key, values)
 }
+
+// ReadOnly returns a view of the set that only permits lookups.
+func (fset FileSet) ReadOnly() ReadOnlySet {
+	return ReadOnlySet{fset: fset}
+}
+
+// ReadOnlySet is a view of a FileSet that does not permit modification. It is
+// suitable for passing configuration across API boundaries where callers
+// should be able to observe the configuration but not edit it. The zero value
+// is an empty set.
+//
+// A ReadOnlySet reflects changes made to the underlying FileSet and its Files
+// by other code.
+type ReadOnlySet struct {
+	fset FileSet
+}
+
+// Get returns the last value associated with the given key in the given
+// section. See FileSet.Get for details.
+func (ro ReadOnlySet) Get(section, key string) string {
+	return ro.fset.Get(section, key)
+}
+
+// Has reports whether any file in the set has at least one property with the
+// given key in the given section. See FileSet.Has for details.
+func (ro ReadOnlySet) Has(section, key string) bool {
+	return ro.fset.Has(section, key)
+}
+
+// Find returns all the values associated with the given key in the given
+// section. See FileSet.Find for details.
+func (ro ReadOnlySet) Find(section, key string) []string {
+	return ro.fset.Find(section, key)
+}
+
+// Sections returns the names of sections that have properties set in any file.
+// See FileSet.Sections for details.
+func (ro ReadOnlySet) Sections() map[string]struct{} {
+	return ro.fset.Sections()
+}
+
+// HasSections reports whether the set has any sections with properties set
+// other than the unnamed global section.
+func (ro ReadOnlySet) HasSections() bool {
+	return ro.fset.HasSections()
+}
+
+// Section returns a copy of the properties in the named section.
+// See FileSet.Section for details.
+func (ro ReadOnlySet) Section(name string) Section {
+	return ro.fset.Section(name)
+}
+
+// EffectiveEqual reports whether fset and other produce the same results from
+// Get and Find for every section and key that has a property in either set.
+// Comments, formatting, and which file in the set provides each value are
+// ignored, so two sets with different layering can be effectively equal.
+func (fset FileSet) EffectiveEqual(other FileSet) bool {
+	keys := make(map[string]map[string]struct{})
+	for _, s := range []FileSet{fset, other} {
+		for name := range s.Sections() {
+			if keys[name] == nil {
+				keys[name] = make(map[string]struct{})
+			}
+			for key := range s.Section(name) {
+				keys[name][key] = struct{}{}
+			}
+		}
+	}
+	for name, sectionKeys := range keys {
+		for key := range sectionKeys {
+			if fset.Get(name, key) != other.Get(name, key) {
+				return false
+			}
+			v1 := fset.Find(name, key)
+			v2 := other.Find(name, key)
+			if len(v1) != len(v2) {
+				return false
+			}
+			for i := range v1 {
+				if v1[i] != v2[i] {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
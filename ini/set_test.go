@@ -11,6 +11,16 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
+// Ensure ReadOnlySet provides lookup methods.
+var _ interface {
+	Get(section, key string) string
+	Has(section, key string) bool
+	Find(section, key string) []string
+	Sections() map[string]struct{}
+	HasSections() bool
+	Section(name string) Section
+} = ReadOnlySet{}
+
 func TestNilFileSet(t *testing.T) {
 	fset := (FileSet)(nil)
 	if got := fset.Get("foo", "bar"); got != "" {
@@ -19,6 +29,9 @@ func TestNilFileSet(t *testing.T) {
 	if got := fset.Find("foo", "bar"); len(got) > 0 {
 		t.Errorf("Find(...) = %q; want empty", got)
 	}
+	if fset.Has("foo", "bar") {
+		t.Error("Has(...) = true; want false")
+	}
 	if got := fset.Sections(); len(got) > 0 {
 		t.Errorf("Sections(...) = %q; want empty", got)
 	}
@@ -28,6 +41,9 @@ func TestNilFileSet(t *testing.T) {
 	if got := fset.Section("foo"); len(got) > 0 {
 		t.Errorf("Section(...) = %q; want empty", got)
 	}
+	if got := fset.SectionOrdered("foo"); len(got) > 0 {
+		t.Errorf("SectionOrdered(...) = %v; want empty", got)
+	}
 }
 
 func TestFileSetAccess(t *testing.T) {
@@ -38,6 +54,7 @@ func TestFileSetAccess(t *testing.T) {
 		key      string
 		wantGet  string
 		wantFind []string
+		wantHas  bool
 	}{
 		{
 			name:     "ExistsInFirst",
@@ -46,6 +63,7 @@ func TestFileSetAccess(t *testing.T) {
 			key:      "FOO",
 			wantGet:  "bar",
 			wantFind: []string{"bar"},
+			wantHas:  true,
 		},
 		{
 			name:     "ExistsInSecond",
@@ -54,6 +72,7 @@ func TestFileSetAccess(t *testing.T) {
 			key:      "BAZ",
 			wantGet:  "quux",
 			wantFind: []string{"quux"},
+			wantHas:  true,
 		},
 		{
 			name:     "DoesNotExist",
@@ -70,6 +89,7 @@ func TestFileSetAccess(t *testing.T) {
 			key:      "FOO",
 			wantGet:  "bar",
 			wantFind: []string{"baz", "bar"},
+			wantHas:  true,
 		},
 		{
 			name: "Section",
@@ -85,6 +105,7 @@ func TestFileSetAccess(t *testing.T) {
 			key:      "bar",
 			wantGet:  "baz",
 			wantFind: []string{"baz"},
+			wantHas:  true,
 		},
 	}
 	t.Run("Get", func(t *testing.T) {
@@ -104,6 +125,9 @@ func TestFileSetAccess(t *testing.T) {
 				if got := fset.Section(test.section).Get(test.key); got != test.wantGet {
 					t.Errorf("fset.Section(%q).Get(%q) = %q; want %q", test.section, test.key, got, test.wantGet)
 				}
+				if got := fset.Has(test.section, test.key); got != test.wantHas {
+					t.Errorf("fset.Has(%q, %q) = %t; want %t", test.section, test.key, got, test.wantHas)
+				}
 			})
 		}
 	})
@@ -131,6 +155,147 @@ func TestFileSetAccess(t *testing.T) {
 	})
 }
 
+func TestFileSetProvenance(t *testing.T) {
+	var fset FileSet
+	for _, src := range []string{"a=project\n", "", "a=user\nb=user\nb=user2\n", "b=system\n"} {
+		f, err := Parse(strings.NewReader(src), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fset = append(fset, f)
+	}
+	fset[1] = nil
+
+	tests := []struct {
+		key       string
+		wantValue string
+		wantIndex int
+		wantOK    bool
+		wantFind  []SourcedValue
+	}{
+		{
+			key:       "a",
+			wantValue: "project",
+			wantIndex: 0,
+			wantOK:    true,
+			wantFind:  []SourcedValue{{"user", 2}, {"project", 0}},
+		},
+		{
+			key:       "b",
+			wantValue: "user2",
+			wantIndex: 2,
+			wantOK:    true,
+			wantFind:  []SourcedValue{{"system", 3}, {"user", 2}, {"user2", 2}},
+		},
+		{
+			key:       "missing",
+			wantIndex: -1,
+		},
+	}
+	for _, test := range tests {
+		value, index, ok := fset.GetWithSource("", test.key)
+		if value != test.wantValue || index != test.wantIndex || ok != test.wantOK {
+			t.Errorf("fset.GetWithSource(\"\", %q) = %q, %d, %t; want %q, %d, %t",
+				test.key, value, index, ok, test.wantValue, test.wantIndex, test.wantOK)
+		}
+		if diff := cmp.Diff(test.wantFind, fset.FindWithSource("", test.key)); diff != "" {
+			t.Errorf("fset.FindWithSource(\"\", %q) (-want +got):\n%s", test.key, diff)
+		}
+	}
+}
+
+func TestFileSetSectionOrder(t *testing.T) {
+	sources := []string{
+		"[build]\n" +
+			"cmd=first\n" +
+			"tag=a\n" +
+			"[other]\n" +
+			"x=1\n" +
+			"[build]\n" +
+			"cmd=first again\n",
+		"",
+		"[build]\n" +
+			"tag=b\n" +
+			"cmd=third\n" +
+			"tag=c\n",
+	}
+	fset := make(FileSet, len(sources))
+	for i, src := range sources {
+		if src == "" {
+			continue
+		}
+		var err error
+		fset[i], err = Parse(strings.NewReader(src), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wantSection := Section{
+		"cmd": {"third", "first", "first again"},
+		"tag": {"b", "c", "a"},
+	}
+	if diff := cmp.Diff(wantSection, fset.Section("build")); diff != "" {
+		t.Errorf("fset.Section(\"build\") (-want +got):\n%s", diff)
+	}
+
+	wantOrdered := []Property{
+		{Section: "build", Key: "cmd", Value: "first"},
+		{Section: "build", Key: "tag", Value: "a"},
+		{Section: "build", Key: "cmd", Value: "first again"},
+		{Section: "build", Key: "tag", Value: "b"},
+		{Section: "build", Key: "cmd", Value: "third"},
+		{Section: "build", Key: "tag", Value: "c"},
+	}
+	if diff := cmp.Diff(wantOrdered, fset.SectionOrdered("build")); diff != "" {
+		t.Errorf("fset.SectionOrdered(\"build\") (-want +got):\n%s", diff)
+	}
+	if got := fset.SectionOrdered("missing"); len(got) > 0 {
+		t.Errorf("fset.SectionOrdered(\"missing\") = %v; want empty", got)
+	}
+}
+
+func TestFileSetEnviron(t *testing.T) {
+	sources := []string{
+		"PATH=/override\n" +
+			"DEBUG=1\n" +
+			"DEBUG=2\n" +
+			"[section]\n" +
+			"IGNORED=1\n",
+		"",
+		"PATH=/usr/bin\n" +
+			"HOME=/home/me\n" +
+			"my.key=x\n" +
+			"1ABC=x\n" +
+			"_PRIVATE=y\n" +
+			"EMPTY=\n",
+	}
+	fset := make(FileSet, len(sources))
+	for i, src := range sources {
+		if src == "" {
+			continue
+		}
+		var err error
+		fset[i], err = Parse(strings.NewReader(src), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"DEBUG=2",
+		"EMPTY=",
+		"HOME=/home/me",
+		"PATH=/override",
+		"_PRIVATE=y",
+	}
+	if diff := cmp.Diff(want, fset.Environ()); diff != "" {
+		t.Errorf("fset.Environ() (-want +got):\n%s", diff)
+	}
+	if got := (FileSet)(nil).Environ(); len(got) > 0 {
+		t.Errorf("FileSet(nil).Environ() = %q; want empty", got)
+	}
+}
+
 func TestFileSetSet(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -212,3 +377,238 @@ func TestFileSetSet(t *testing.T) {
 		})
 	}
 }
+
+func TestFileSetSetAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string
+		index   int
+		want    []string
+	}{
+		{
+			name:    "AllocateFile",
+			sources: []string{"", ""},
+			index:   1,
+			want:    []string{"", "[user]\nname=quux\n"},
+		},
+		{
+			name: "DeleteInEarlierFiles",
+			sources: []string{
+				"[user]\nname=project\n",
+				"[user]\nname=user\n",
+				"[user]\nname=system\n",
+			},
+			index: 1,
+			want: []string{
+				"",
+				"[user]\nname=quux\n",
+				"[user]\nname=system\n",
+			},
+		},
+		{
+			name: "First",
+			sources: []string{
+				"[user]\nemail=x\n",
+				"[user]\nname=user\n",
+			},
+			index: 0,
+			want: []string{
+				"[user]\nemail=x\nname=quux\n",
+				"[user]\nname=user\n",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var fset FileSet
+			for _, src := range test.sources {
+				var f *File
+				if src != "" {
+					var err error
+					f, err = Parse(strings.NewReader(src), nil)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				fset = append(fset, f)
+			}
+
+			fset.SetAt(test.index, "user", "name", "quux")
+
+			got := make([]string, len(fset))
+			for i, f := range fset {
+				text, err := f.MarshalText()
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[i] = string(text)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("MarshalText (-want +got):\n%s", diff)
+			}
+			if got := fset.Get("user", "name"); got != "quux" {
+				t.Errorf("fset.Get(\"user\", \"name\") = %q; want \"quux\"", got)
+			}
+		})
+	}
+}
+
+func TestReadOnlySet(t *testing.T) {
+	f1, err := Parse(strings.NewReader("FOO=bar\n[sect]\nkey=1\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2, err := Parse(strings.NewReader("FOO=baz\nQUUX=\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := FileSet{f1, f2}
+	view := fset.ReadOnly()
+	// FileSet's mutation methods must not be available on the view.
+	type setter interface {
+		Set(section, key, value string)
+	}
+	type deleter interface {
+		Delete(section, key string)
+	}
+	type adder interface {
+		Add(section, key string, values []string)
+	}
+	if _, ok := interface{}(view).(setter); ok {
+		t.Error("ReadOnlySet has a Set method")
+	}
+	if _, ok := interface{}(view).(deleter); ok {
+		t.Error("ReadOnlySet has a Delete method")
+	}
+	if _, ok := interface{}(view).(adder); ok {
+		t.Error("ReadOnlySet has an Add method")
+	}
+	if got, want := view.Get("", "FOO"), "bar"; got != want {
+		t.Errorf("view.Get(\"\", \"FOO\") = %q; want %q", got, want)
+	}
+	if !view.Has("", "QUUX") {
+		t.Error("view.Has(\"\", \"QUUX\") = false; want true")
+	}
+	if diff := cmp.Diff([]string{"baz", "bar"}, view.Find("", "FOO")); diff != "" {
+		t.Errorf("view.Find(\"\", \"FOO\") (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]struct{}{"": {}, "sect": {}}, view.Sections()); diff != "" {
+		t.Errorf("view.Sections() (-want +got):\n%s", diff)
+	}
+	if !view.HasSections() {
+		t.Error("view.HasSections() = false; want true")
+	}
+	if diff := cmp.Diff(Section{"key": {"1"}}, view.Section("sect")); diff != "" {
+		t.Errorf("view.Section(\"sect\") (-want +got):\n%s", diff)
+	}
+
+	// Changes to the underlying set are visible.
+	fset.Set("", "FOO", "xyzzy")
+	if got, want := view.Get("", "FOO"), "xyzzy"; got != want {
+		t.Errorf("after fset.Set, view.Get(\"\", \"FOO\") = %q; want %q", got, want)
+	}
+}
+
+func TestEffectiveEqual(t *testing.T) {
+	tests := []struct {
+		name  string
+		set1  []string
+		set2  []string
+		equal bool
+	}{
+		{
+			name:  "Empty",
+			equal: true,
+		},
+		{
+			name:  "SameSingleFile",
+			set1:  []string{"a=1\n[foo]\nb=2\n"},
+			set2:  []string{"; Comment\na = 1\n[foo]\nb = \"2\"\n"},
+			equal: true,
+		},
+		{
+			name: "DifferentLayering",
+			set1: []string{
+				"a=1\n",
+				"b=2\n[foo]\nc=3\n",
+			},
+			set2: []string{
+				"a=1\nb=2\n",
+				"[foo]\nc=3\n",
+				"",
+			},
+			equal: true,
+		},
+		{
+			name: "SplitAcrossFiles",
+			set1: []string{
+				"a=1\n",
+				"b=2\n",
+			},
+			set2: []string{
+				"a=1\nb=2\n",
+			},
+			equal: true,
+		},
+		{
+			name: "DifferentValue",
+			set1: []string{
+				"a=1\n",
+				"b=2\n",
+			},
+			set2: []string{
+				"a=1\nb=3\n",
+			},
+			equal: false,
+		},
+		{
+			name: "DifferentPrecedence",
+			set1: []string{
+				"a=1\n",
+				"a=2\n",
+			},
+			set2: []string{
+				"a=2\n",
+				"a=1\n",
+			},
+			equal: false,
+		},
+		{
+			name:  "ExtraKey",
+			set1:  []string{"a=1\n"},
+			set2:  []string{"a=1\n[foo]\nb=\n"},
+			equal: false,
+		},
+		{
+			name:  "ExtraValue",
+			set1:  []string{"a=1\n"},
+			set2:  []string{"a=0\na=1\n"},
+			equal: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var fset1, fset2 FileSet
+			for _, src := range test.set1 {
+				f, err := Parse(strings.NewReader(src), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				fset1 = append(fset1, f)
+			}
+			for _, src := range test.set2 {
+				f, err := Parse(strings.NewReader(src), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				fset2 = append(fset2, f)
+			}
+			if got := fset1.EffectiveEqual(fset2); got != test.equal {
+				t.Errorf("fset1.EffectiveEqual(fset2) = %t; want %t", got, test.equal)
+			}
+			if got := fset2.EffectiveEqual(fset1); got != test.equal {
+				t.Errorf("fset2.EffectiveEqual(fset1) = %t; want %t", got, test.equal)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ContextSnippet formats the lines of source around the error for display,
+// with up to contextLines lines before and after the error line. Each line is
+// prefixed with its line number, the error line is marked with '>', and if the
+// column is known, a caret is shown beneath it:
+//
+//	  2 | foo=1
+//	> 3 | [bar
+//	    | ^
+//	  4 | baz=2
+//
+// Fewer context lines are shown if the error is near the beginning or end of
+// the source. source must be the input that was passed to Parse. If the error
+// line is not in source, ContextSnippet returns the empty string.
+func (e *ParseError) ContextSnippet(source []byte, contextLines int) string {
+	lines := strings.Split(string(source), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if e.Line < 1 || e.Line > len(lines) {
+		return ""
+	}
+	if contextLines < 0 {
+		contextLines = 0
+	}
+	first := e.Line - contextLines
+	if first < 1 {
+		first = 1
+	}
+	last := e.Line + contextLines
+	if last > len(lines) {
+		last = len(lines)
+	}
+	width := len(strconv.Itoa(last))
+
+	sb := new(strings.Builder)
+	for n := first; n <= last; n++ {
+		line := strings.TrimSuffix(lines[n-1], "\r")
+		if n == e.Line {
+			sb.WriteString("> ")
+		} else {
+			sb.WriteString("  ")
+		}
+		num := strconv.Itoa(n)
+		sb.WriteString(strings.Repeat(" ", width-len(num)))
+		sb.WriteString(num)
+		sb.WriteString(" | ")
+		sb.WriteString(line)
+		sb.WriteString("\n")
+		if n == e.Line && e.Column > 0 {
+			sb.WriteString(strings.Repeat(" ", width+2))
+			sb.WriteString(" | ")
+			for i := 0; i < e.Column-1 && i < len(line); i++ {
+				// Keep tabs so the caret lines up.
+				if line[i] == '\t' {
+					sb.WriteByte('\t')
+				} else {
+					sb.WriteByte(' ')
+				}
+			}
+			sb.WriteString("^\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestContextSnippet(t *testing.T) {
+	tests := []struct {
+		name         string
+		source       string
+		opts         *ParseOptions
+		contextLines int
+		want         string
+	}{
+		{
+			name: "Middle",
+			source: "a=1\n" +
+				"b=2\n" +
+				"  [bad\n" +
+				"c=3\n" +
+				"d=4\n",
+			contextLines: 1,
+			want: "  2 | b=2\n" +
+				"> 3 |   [bad\n" +
+				"    |   ^\n" +
+				"  4 | c=3\n",
+		},
+		{
+			name:         "FirstLine",
+			source:       "bad\nb=2\nc=3\n",
+			contextLines: 2,
+			want: "> 1 | bad\n" +
+				"    | ^\n" +
+				"  2 | b=2\n" +
+				"  3 | c=3\n",
+		},
+		{
+			name:         "LastLine",
+			source:       "a=1\nb=2\nbad",
+			contextLines: 5,
+			want: "  1 | a=1\n" +
+				"  2 | b=2\n" +
+				"> 3 | bad\n" +
+				"    | ^\n",
+		},
+		{
+			name:         "NoContext",
+			source:       "a=1\nbad\nc=3\n",
+			contextLines: 0,
+			want: "> 2 | bad\n" +
+				"    | ^\n",
+		},
+		{
+			name: "WideLineNumbers",
+			source: strings.Repeat("a=1\n", 9) +
+				"bad\n" +
+				"b=2\n",
+			contextLines: 1,
+			want: "   9 | a=1\n" +
+				"> 10 | bad\n" +
+				"     | ^\n" +
+				"  11 | b=2\n",
+		},
+		{
+			name:         "Tab",
+			source:       "a=1\n \tb=2\n",
+			opts:         &ParseOptions{RejectTabs: true},
+			contextLines: 0,
+			want: "> 2 |  \tb=2\n" +
+				"    |  ^\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(test.source), test.opts)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Parse error = %v; want *ParseError", err)
+			}
+			got := parseErr.ContextSnippet([]byte(test.source), test.contextLines)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ContextSnippet(...) (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("NoColumn", func(t *testing.T) {
+		e := &ParseError{Line: 2, Err: errors.New("bork")}
+		got := e.ContextSnippet([]byte("a=1\nb=2\r\nc=3\n"), 0)
+		if want := "> 2 | b=2\n"; got != want {
+			t.Errorf("ContextSnippet(...) = %q; want %q", got, want)
+		}
+	})
+
+	t.Run("LineOutOfRange", func(t *testing.T) {
+		e := &ParseError{Line: 5, Column: 1, Err: errors.New("bork")}
+		if got := e.ContextSnippet([]byte("a=1\n"), 1); got != "" {
+			t.Errorf("ContextSnippet(...) = %q; want \"\"", got)
+		}
+	})
+}
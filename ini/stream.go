@@ -0,0 +1,242 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A Token is an element of an INI file returned by Decoder.Token and accepted
+// by Encoder.Encode. It is one of SectionStart, Property, or Comment.
+type Token interface{}
+
+// SectionStart is a Token for a section header.
+type SectionStart struct {
+	Name string
+}
+
+// Comment is a Token for a comment line.
+type Comment struct {
+	// Text is the comment line, including its leading ';' or '#'.
+	Text string
+}
+
+// A Decoder reads tokens from an INI file one at a time without storing the
+// whole file in memory.
+type Decoder struct {
+	s       *bufio.Scanner
+	opts    ParseOptions
+	lineno  int
+	section string
+	pending []Token
+	err     error
+}
+
+// NewDecoder returns a Decoder that reads from r. Nil options are treated
+// identically as passing the zero value. The Decoder interprets options the
+// same way as Parse, except that KeepRawValue and PreserveFormatting have no
+// effect and lines covered by PreserveLeadingLines are skipped. Directive lines
+// are returned as comments.
+func NewDecoder(r io.Reader, opts *ParseOptions) *Decoder {
+	d := &Decoder{s: bufio.NewScanner(r)}
+	if opts != nil {
+		d.opts = *opts
+	}
+	return d
+}
+
+// Token returns the next token in the input. Properties are returned with the
+// name of the section they are in. An inline comment (see
+// ParseOptions.AllowInlineComments) is returned as a Comment immediately
+// after its Property. Blank lines are skipped.
+//
+// At the end of the input, Token returns io.EOF. If the input is malformed,
+// Token returns a *ParseError. Once Token has returned an error, it returns
+// the same error on subsequent calls.
+func (d *Decoder) Token() (Token, error) {
+	if len(d.pending) > 0 {
+		tok := d.pending[0]
+		d.pending = d.pending[1:]
+		return tok, nil
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	for d.s.Scan() {
+		d.lineno++
+		if d.lineno <= d.opts.PreserveLeadingLines {
+			continue
+		}
+		tok, err := d.parseLine()
+		if err != nil {
+			d.err = err
+			return nil, err
+		}
+		if tok != nil {
+			return tok, nil
+		}
+	}
+	if err := d.s.Err(); err != nil {
+		d.err = fmt.Errorf("parse ini file: line %d: %w", d.lineno+1, err)
+	} else {
+		d.err = io.EOF
+	}
+	return nil, d.err
+}
+
+// parseLine returns the token for the scanner's current line or nil if the
+// line is blank.
+func (d *Decoder) parseLine() (Token, error) {
+	l, err := parseLine(d.s, &d.lineno, &d.opts, d.section)
+	if err != nil {
+		return nil, err
+	}
+	switch l.kind {
+	case commentLine, directiveLine:
+		return Comment{Text: l.text}, nil
+	case sectionLine:
+		d.section = l.name
+		return SectionStart{Name: l.name}, nil
+	case propertyLine:
+		if l.prop.inlineComment != "" {
+			d.pending = append(d.pending, Comment{Text: l.prop.inlineComment})
+		}
+		return Property{
+			Section: d.section,
+			Key:     l.prop.key,
+			Value:   l.prop.value,
+		}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// An Encoder writes tokens to an INI file one at a time. The output is
+// formatted the same way as File.MarshalText.
+type Encoder struct {
+	w        io.Writer
+	section  string
+	wrote    bool
+	comments []string
+	buf      []byte
+	err      error
+}
+
+// NewEncoder returns an Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes a token. Comments are held until the next section header or
+// property so that a blank line can be written before the comments of a
+// section, so callers must call Flush after the last token.
+//
+// If a Property's section differs from the current section, Encode writes a
+// header for the property's section first. Encode returns an error if a
+// Property for the global section follows a section header, if a section
+// name or key is not valid, or if a Comment's text contains a newline.
+// Comment text that does not start with ';' or '#' is prefixed with "; ".
+// Once writing to the underlying io.Writer fails, Encode and Flush return the
+// same error.
+func (e *Encoder) Encode(tok Token) error {
+	if e.err != nil {
+		return e.err
+	}
+	switch tok := tok.(type) {
+	case Comment:
+		if strings.ContainsAny(tok.Text, "\r\n") {
+			return errors.New("encode ini token: comment contains newline")
+		}
+		text := strings.TrimSpace(tok.Text)
+		if !strings.HasPrefix(text, ";") && !strings.HasPrefix(text, "#") {
+			text = strings.TrimSpace("; " + text)
+		}
+		e.comments = append(e.comments, text)
+		return nil
+	case SectionStart:
+		if tok.Name == "" || !IsValidSection(tok.Name) {
+			return fmt.Errorf("encode ini token: %w", &InvalidNameError{Section: tok.Name})
+		}
+		e.appendHeader(tok.Name)
+	case Property:
+		if err := validateName(tok.Section, tok.Key); err != nil {
+			return fmt.Errorf("encode ini token: %w", err)
+		}
+		if tok.Section != e.section {
+			if tok.Section == "" {
+				return fmt.Errorf("encode ini token: property %q in global section after [%s]", tok.Key, e.section)
+			}
+			e.appendHeader(tok.Section)
+		}
+		e.appendComments()
+		e.buf = append(e.buf, tok.Key...)
+		e.buf = append(e.buf, '=')
+		if shouldQuoteValue(tok.Value) {
+			e.buf = appendQuotedString(e.buf, tok.Value, rawNonASCII)
+		} else {
+			e.buf = append(e.buf, tok.Value...)
+		}
+		e.buf = append(e.buf, '\n')
+	default:
+		return fmt.Errorf("encode ini token: unknown token type %T", tok)
+	}
+	return e.write()
+}
+
+// Flush writes any comments that have not been written yet. A blank line is
+// written before them if anything else has been written, as MarshalText does
+// for comments at the end of a file.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	if len(e.comments) == 0 {
+		return nil
+	}
+	if e.wrote {
+		e.buf = append(e.buf, '\n')
+	}
+	e.appendComments()
+	return e.write()
+}
+
+// appendHeader appends a section header preceded by a blank line (if
+// anything has been written) and any pending comments.
+func (e *Encoder) appendHeader(name string) {
+	if e.wrote {
+		e.buf = append(e.buf, '\n')
+	}
+	e.appendComments()
+	e.buf = append(e.buf, '[')
+	e.buf = append(e.buf, name...)
+	e.buf = append(e.buf, "]\n"...)
+	e.section = name
+}
+
+func (e *Encoder) appendComments() {
+	for _, comment := range e.comments {
+		e.buf = append(e.buf, comment...)
+		e.buf = append(e.buf, '\n')
+	}
+	e.comments = e.comments[:0]
+}
+
+// write writes the buffered output to the underlying writer.
+func (e *Encoder) write() error {
+	if len(e.buf) == 0 {
+		return nil
+	}
+	_, err := e.w.Write(e.buf)
+	e.wrote = true
+	e.buf = e.buf[:0]
+	if err != nil {
+		e.err = fmt.Errorf("encode ini token: %w", err)
+		return e.err
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecoder(t *testing.T) {
+	const source = "; Leading\n" +
+		"a = 1 ; inline\n" +
+		"\n" +
+		"# Section comment\n" +
+		"[ Server ]\n" +
+		"Host = \"example.com\"\n" +
+		"host = other\n"
+	d := NewDecoder(strings.NewReader(source), &ParseOptions{
+		NormalizeSection:    strings.ToLower,
+		NormalizeKey:        func(section, key string) string { return strings.ToLower(key) },
+		AllowInlineComments: true,
+	})
+	var got []Token
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, tok)
+	}
+	want := []Token{
+		Comment{Text: "; Leading"},
+		Property{Section: "", Key: "a", Value: "1"},
+		Comment{Text: "; inline"},
+		Comment{Text: "# Section comment"},
+		SectionStart{Name: "server"},
+		Property{Section: "server", Key: "host", Value: "example.com"},
+		Property{Section: "server", Key: "host", Value: "other"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("tokens (-want +got):\n%s", diff)
+	}
+	if _, err := d.Token(); !errors.Is(err, io.EOF) {
+		t.Errorf("Token() after EOF error = %v; want %v", err, io.EOF)
+	}
+}
+
+func TestDecoderError(t *testing.T) {
+	d := NewDecoder(strings.NewReader("a=1\nbork\nb=2\n"), nil)
+	if _, err := d.Token(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := d.Token()
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 2 {
+		t.Fatalf("second Token() error = %v; want *ParseError on line 2", err)
+	}
+	if _, err2 := d.Token(); err2 != err {
+		t.Errorf("third Token() error = %v; want %v", err2, err)
+	}
+}
+
+func TestDecoderErrorMatchesParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		opts   *ParseOptions
+	}{
+		{
+			name:   "InvalidKey",
+			source: "a=1\n[b\n",
+		},
+		{
+			name:   "Tab",
+			source: "a=1\n\tb=2\n",
+			opts:   &ParseOptions{RejectTabs: true},
+		},
+		{
+			name:   "InvalidKeyAfterExport",
+			source: "export a;b=1\n",
+			opts:   &ParseOptions{AllowExport: true},
+		},
+		{
+			name:   "UnterminatedBeforeInlineComment",
+			source: "a = \"x ; comment\n",
+			opts:   &ParseOptions{AllowInlineComments: true},
+		},
+		{
+			name:   "ContinuedLine",
+			source: "a=1\nb = \"x\\\n  y\n",
+			opts:   &ParseOptions{AllowLineContinuation: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, parseErr := Parse(strings.NewReader(test.source), test.opts)
+			var want *ParseError
+			if !errors.As(parseErr, &want) {
+				t.Fatalf("Parse(...) error = %v; want *ParseError", parseErr)
+			}
+			d := NewDecoder(strings.NewReader(test.source), test.opts)
+			var decodeErr error
+			for decodeErr == nil {
+				_, decodeErr = d.Token()
+			}
+			var got *ParseError
+			if !errors.As(decodeErr, &got) {
+				t.Fatalf("Token() error = %v; want *ParseError", decodeErr)
+			}
+			if got.Line != want.Line || got.Column != want.Column || got.Kind != want.Kind || got.Text != want.Text || got.Error() != want.Error() {
+				t.Errorf("Token() error = %+v; want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestEncoderMatchesMarshalText(t *testing.T) {
+	sources := []string{
+		"",
+		"; Only a comment\n",
+		"a=1\n",
+		"; About a\na=1\n\n; About s\n[s]\nb=\" x \"\n; Trailing\n",
+		"[s]\nb=2\n[t]\n; c\nc=3\n[s]\nd=4\n",
+	}
+	for _, source := range sources {
+		f, err := Parse(strings.NewReader(source), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := f.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sb := new(strings.Builder)
+		d := NewDecoder(strings.NewReader(source), nil)
+		e := NewEncoder(sb)
+		for {
+			tok, err := d.Token()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := e.Encode(tok); err != nil {
+				t.Fatalf("Encode(%#v): %v", tok, err)
+			}
+		}
+		if err := e.Flush(); err != nil {
+			t.Fatal("Flush:", err)
+		}
+		if diff := cmp.Diff(string(want), sb.String()); diff != "" {
+			t.Errorf("for source %q, Encoder output (-MarshalText +got):\n%s", source, diff)
+		}
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	sb := new(strings.Builder)
+	e := NewEncoder(sb)
+	tokens := []Token{
+		Property{Key: "a", Value: "1"},
+		Comment{Text: "no marker"},
+		Property{Section: "s", Key: "b", Value: "2"},
+		Comment{Text: "# end"},
+	}
+	for _, tok := range tokens {
+		if err := e.Encode(tok); err != nil {
+			t.Fatalf("Encode(%#v): %v", tok, err)
+		}
+	}
+	if err := e.Flush(); err != nil {
+		t.Fatal("Flush:", err)
+	}
+	const want = "a=1\n\n; no marker\n[s]\nb=2\n\n# end\n"
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Errorf("output (-want +got):\n%s", diff)
+	}
+
+	badTokens := []Token{
+		Property{Key: "a=b"},
+		SectionStart{Name: ""},
+		Comment{Text: "a\nb"},
+		"bork",
+		Property{Key: "global"},
+	}
+	for _, tok := range badTokens {
+		if err := e.Encode(tok); err == nil {
+			t.Errorf("Encode(%#v) = <nil>; want error", tok)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import "strings"
+
+// DisableSection comments out every section with the given name, turning
+// each into a block of comments that EnableSection can restore. The block
+// starts with any comments attached to the section, followed by a comment
+// for the section header and one for each property, preserving the comments
+// attached to each property:
+//
+//	; [name]
+//	; key=value
+//
+// Values are quoted in the block as they would be by MarshalText. The block is
+// attached to the next section or to the end of the file, so it is preserved
+// by MarshalText and by parsing the output again. DisableSection does nothing
+// for the global section.
+func (f *File) DisableSection(name string) {
+	f.beforeChange()
+	if name == "" {
+		return
+	}
+	var pending []string
+	sections := f.sections[:0]
+	for _, s := range f.sections {
+		if s.name == name {
+			pending = append(pending, s.comments...)
+			pending = append(pending, disabledHeader(name))
+			for _, p := range s.properties {
+				pending = append(pending, p.comments...)
+				pending = append(pending, disabledProperty(p))
+			}
+			continue
+		}
+		if len(pending) > 0 {
+			s.comments = append(pending, s.comments...)
+			pending = nil
+		}
+		sections = append(sections, s)
+	}
+	for i := len(sections); i < len(f.sections); i++ {
+		f.sections[i] = section{}
+	}
+	f.sections = sections
+	if len(pending) > 0 {
+		f.trailingComments = append(pending, f.trailingComments...)
+	}
+}
+
+// EnableSection restores sections with the given name that were commented out
+// by DisableSection. It looks for blocks of comments in the form described
+// by DisableSection that are attached to a section header or to the end of
+// the file. Each block ends after its last property comment; comments after
+// it stay attached to what follows. EnableSection does nothing for the global
+// section or if there are no such blocks.
+func (f *File) EnableSection(name string) {
+	f.beforeChange()
+	if name == "" {
+		return
+	}
+	sections := make([]section, 0, len(f.sections))
+	for _, s := range f.sections {
+		var restored []section
+		restored, s.comments = enableBlocks(name, s.comments)
+		sections = append(sections, restored...)
+		sections = append(sections, s)
+	}
+	var restored []section
+	restored, f.trailingComments = enableBlocks(name, f.trailingComments)
+	f.sections = append(sections, restored...)
+}
+
+// enableBlocks restores the disabled sections with the given name in a list
+// of comments. It returns the restored sections and the comments that are not
+// part of any block.
+func enableBlocks(name string, comments []string) (_ []section, rest []string) {
+	header := disabledHeader(name)
+	var sections []section
+	for i := 0; i < len(comments); {
+		if comments[i] != header {
+			rest = append(rest, comments[i])
+			i++
+			continue
+		}
+		s := section{name: name, comments: rest}
+		rest = nil
+		i++
+		end := i
+		var propComments []string
+		for j := i; j < len(comments) && comments[j] != header; j++ {
+			key, value, ok := parseDisabledProperty(comments[j])
+			if !ok {
+				propComments = append(propComments, comments[j])
+				continue
+			}
+			s.properties = append(s.properties, property{
+				comments: propComments,
+				key:      key,
+				value:    value,
+			})
+			propComments = nil
+			end = j + 1
+		}
+		sections = append(sections, s)
+		i = end
+	}
+	if len(sections) == 0 {
+		return nil, comments
+	}
+	return sections, rest
+}
+
+func disabledHeader(name string) string {
+	return "; [" + name + "]"
+}
+
+func disabledProperty(p property) string {
+	buf := []byte("; ")
+	buf = append(buf, p.key...)
+	buf = append(buf, '=')
+	if p.quote.shouldQuote(p.value) {
+		buf = appendQuotedString(buf, p.value, rawNonASCII)
+	} else {
+		buf = append(buf, p.value...)
+	}
+	return string(buf)
+}
+
+// parseDisabledProperty parses a comment produced by disabledProperty.
+func parseDisabledProperty(comment string) (key, value string, ok bool) {
+	if !strings.HasPrefix(comment, "; ") {
+		return "", "", false
+	}
+	line, err := cleanLine([]byte(comment[len("; "):]), doubleQuotes)
+	if err != nil || line == "" || strings.IndexByte(";#[", line[0]) != -1 {
+		return "", "", false
+	}
+	i := strings.IndexByte(line, '=')
+	key = line[:i]
+	if !IsValidKey(key) {
+		return "", "", false
+	}
+	return key, unquote(line[i+1:]), true
+}
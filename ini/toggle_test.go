@@ -0,0 +1,211 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDisableSection(t *testing.T) {
+	tests := []struct {
+		name         string
+		source       string
+		section      string
+		wantDisabled string
+	}{
+		{
+			name: "Middle",
+			source: "top=1\n" +
+				"\n" +
+				"# Feature comment\n" +
+				"[feature]\n" +
+				"# Property comment\n" +
+				"enabled=true\n" +
+				"name=\" padded \"\n" +
+				"\n" +
+				"# Other comment\n" +
+				"[other]\n" +
+				"x=1\n",
+			section: "feature",
+			wantDisabled: "top=1\n" +
+				"\n" +
+				"# Feature comment\n" +
+				"; [feature]\n" +
+				"# Property comment\n" +
+				"; enabled=true\n" +
+				"; name=\" padded \"\n" +
+				"# Other comment\n" +
+				"[other]\n" +
+				"x=1\n",
+		},
+		{
+			name: "Last",
+			source: "[other]\n" +
+				"x=1\n" +
+				"\n" +
+				"[feature]\n" +
+				"enabled=true\n" +
+				"\n" +
+				"# Trailing comment\n",
+			section: "feature",
+			wantDisabled: "[other]\n" +
+				"x=1\n" +
+				"\n" +
+				"; [feature]\n" +
+				"; enabled=true\n" +
+				"# Trailing comment\n",
+		},
+		{
+			name: "Repeated",
+			source: "[feature]\n" +
+				"a=1\n" +
+				"\n" +
+				"[other]\n" +
+				"x=1\n" +
+				"\n" +
+				"[feature]\n" +
+				"b=2\n",
+			section: "feature",
+			wantDisabled: "; [feature]\n" +
+				"; a=1\n" +
+				"[other]\n" +
+				"x=1\n" +
+				"\n" +
+				"; [feature]\n" +
+				"; b=2\n",
+		},
+		{
+			name: "Consecutive",
+			source: "[feature]\n" +
+				"a=1\n" +
+				"\n" +
+				"[feature]\n" +
+				"\n" +
+				"[other]\n" +
+				"x=1\n",
+			section: "feature",
+			wantDisabled: "; [feature]\n" +
+				"; a=1\n" +
+				"; [feature]\n" +
+				"[other]\n" +
+				"x=1\n",
+		},
+		{
+			name: "Missing",
+			source: "[other]\n" +
+				"x=1\n",
+			section: "feature",
+			wantDisabled: "[other]\n" +
+				"x=1\n",
+		},
+		{
+			name: "Global",
+			source: "top=1\n" +
+				"\n" +
+				"[other]\n" +
+				"x=1\n",
+			section: "",
+			wantDisabled: "top=1\n" +
+				"\n" +
+				"[other]\n" +
+				"x=1\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(test.source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.DisableSection(test.section)
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.wantDisabled, string(got)); diff != "" {
+				t.Errorf("after DisableSection(%q) (-want +got):\n%s", test.section, diff)
+			}
+			if _, present := f.Sections()[test.section]; present && test.section != "" {
+				t.Errorf("section %q still present after DisableSection", test.section)
+			}
+
+			// Round trip through parsing, then re-enable.
+			f, err = Parse(strings.NewReader(string(got)), nil)
+			if err != nil {
+				t.Fatal("Parse disabled output:", err)
+			}
+			f.EnableSection(test.section)
+			got, err = f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.source, string(got)); diff != "" {
+				t.Errorf("after EnableSection(%q) (-want +got):\n%s", test.section, diff)
+			}
+		})
+	}
+}
+
+func TestEnableSection(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		section string
+		want    string
+	}{
+		{
+			name: "HandWritten",
+			source: "[other]\n" +
+				"x=1\n" +
+				"; [feature]\n" +
+				"; enabled = true\n" +
+				"; path=\"a b\\n\"\n" +
+				"; Unrelated comment\n" +
+				"[last]\n" +
+				"y=2\n",
+			section: "feature",
+			want: "[other]\n" +
+				"x=1\n" +
+				"\n" +
+				"[feature]\n" +
+				"enabled=true\n" +
+				"path=\"a b\\n\"\n" +
+				"\n" +
+				"; Unrelated comment\n" +
+				"[last]\n" +
+				"y=2\n",
+		},
+		{
+			name: "OtherSectionName",
+			source: "; [other]\n" +
+				"; x=1\n" +
+				"[last]\n" +
+				"y=2\n",
+			section: "feature",
+			want: "; [other]\n" +
+				"; x=1\n" +
+				"[last]\n" +
+				"y=2\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(test.source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.EnableSection(test.section)
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("after EnableSection(%q) (-want +got):\n%s", test.section, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+// A Tx is a batch of edits to a File made inside File.Update. Edits made
+// through a Tx are visible to reads through the same Tx, but not to the
+// File until Update returns successfully.
+type Tx struct {
+	f          *File
+	validators []func(*File) error
+}
+
+// Update calls fn with a transaction and applies the edits made through it to
+// f only if fn returns nil and every validator registered with Tx.Validate
+// accepts the result. Otherwise, f is left unmodified and Update returns the
+// first error encountered. If fn panics (for example, by calling Tx.Set with
+// an invalid key), f is also left unmodified and the panic is propagated.
+//
+// The Tx must not be used after fn returns.
+func (f *File) Update(fn func(tx *Tx) error) error {
+	tx := &Tx{f: f.Clone()}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	for _, validate := range tx.validators {
+		if err := validate(tx.f); err != nil {
+			return err
+		}
+	}
+	*f = *tx.f
+	return nil
+}
+
+// Validate registers a function to be called with the edited file after the
+// transaction's function returns nil. If validate returns an error, the
+// transaction is discarded and Update returns that error. Validators are
+// called in the order they were registered. Schema.Validate can be used as a
+// validator.
+func (tx *Tx) Validate(validate func(*File) error) {
+	tx.validators = append(tx.validators, validate)
+}
+
+// File returns the file as edited by the transaction so far. Changes made
+// directly to the returned file are part of the transaction.
+func (tx *Tx) File() *File {
+	return tx.f
+}
+
+// Get returns the last value associated with the given key as edited by the
+// transaction. See File.Get for details.
+func (tx *Tx) Get(section, key string) string {
+	return tx.f.Get(section, key)
+}
+
+// Find returns all the values associated with the given key as edited by the
+// transaction. See File.Find for details.
+func (tx *Tx) Find(section, key string) []string {
+	return tx.f.Find(section, key)
+}
+
+// Set sets the value of a key as part of the transaction. See File.Set for
+// details.
+func (tx *Tx) Set(section, key, value string) {
+	tx.f.Set(section, key, value)
+}
+
+// Add appends values for a key as part of the transaction. See File.Add for
+// details.
+func (tx *Tx) Add(section, key string, values []string) {
+	tx.f.Add(section, key, values)
+}
+
+// Delete deletes a key as part of the transaction. See File.Delete for
+// details.
+func (tx *Tx) Delete(section, key string) {
+	tx.f.Delete(section, key)
+}
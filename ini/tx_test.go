@@ -0,0 +1,131 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUpdate(t *testing.T) {
+	const source = "[server]\nhost=localhost\nport=8080\n"
+	bork := errors.New("bork")
+	tests := []struct {
+		name    string
+		fn      func(tx *Tx) error
+		wantErr error
+		want    string
+	}{
+		{
+			name: "Commit",
+			fn: func(tx *Tx) error {
+				tx.Set("server", "host", "example.com")
+				tx.Delete("server", "port")
+				tx.Add("client", "retry", []string{"1", "2"})
+				if got := tx.Get("server", "host"); got != "example.com" {
+					t.Errorf("tx.Get(\"server\", \"host\") = %q; want %q", got, "example.com")
+				}
+				return nil
+			},
+			want: "[server]\nhost=example.com\n\n[client]\nretry=1\nretry=2\n",
+		},
+		{
+			name: "FunctionError",
+			fn: func(tx *Tx) error {
+				tx.Set("server", "host", "example.com")
+				return bork
+			},
+			wantErr: bork,
+			want:    source,
+		},
+		{
+			name: "ValidationError",
+			fn: func(tx *Tx) error {
+				tx.Validate(func(f *File) error {
+					if f.Get("server", "port") == "" {
+						return bork
+					}
+					return nil
+				})
+				tx.Set("server", "host", "example.com")
+				tx.Delete("server", "port")
+				return nil
+			},
+			wantErr: bork,
+			want:    source,
+		},
+		{
+			name: "SchemaValidation",
+			fn: func(tx *Tx) error {
+				schema := &Schema{Sections: map[string]SectionSchema{
+					"server": {Keys: map[string]KeySchema{
+						"host": {Required: true},
+						"port": {Validate: IntRange(1, 65535)},
+					}},
+				}}
+				tx.Validate(schema.Validate)
+				tx.Set("server", "port", "99999")
+				return nil
+			},
+			wantErr: &ValidationError{},
+			want:    source,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(source), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = f.Update(test.fn)
+			switch want := test.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("Update(...) = %v; want <nil>", err)
+				}
+			case *ValidationError:
+				if !errors.As(err, &want) {
+					t.Errorf("Update(...) = %v; want *ValidationError", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Errorf("Update(...) = %v; want %v", err, want)
+				}
+			}
+			got, err := f.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("file after Update (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdatePanic(t *testing.T) {
+	const source = "[server]\nhost=localhost\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Update did not propagate panic")
+			}
+		}()
+		f.Update(func(tx *Tx) error {
+			tx.Set("server", "host", "example.com")
+			tx.Set("server", "[bad", "x")
+			return nil
+		})
+	}()
+	if got := f.Get("server", "host"); got != "localhost" {
+		t.Errorf("f.Get(\"server\", \"host\") = %q after panic; want %q", got, "localhost")
+	}
+}
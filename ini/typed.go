@@ -0,0 +1,287 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// GetStringSlice returns all the values associated with the given key in the
+// given section. It is equivalent to Find.
+func (f *File) GetStringSlice(section, key string) []string {
+	return f.Find(section, key)
+}
+
+// GetIntSlice parses all the values associated with the given key in the
+// given section as base-10 integers, in the order they appear in the file.
+// If a value cannot be parsed, GetIntSlice returns an error that identifies the
+// index of the first such value. If the key is absent, GetIntSlice returns an
+// empty slice and no error.
+func (f *File) GetIntSlice(section, key string) ([]int, error) {
+	values := f.Find(section, key)
+	ints := make([]int, 0, len(values))
+	for i, v := range values {
+		n, err := parseInt(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse ini value %s[%d]: %w", propertyName(section, key), i, err)
+		}
+		ints = append(ints, n)
+	}
+	return ints, nil
+}
+
+// ErrNotFound is returned by the typed getters, like GetInt, when there are no
+// values associated with a key.
+var ErrNotFound = errors.New("ini: key not found")
+
+// GetInt returns the last value associated with the given key in the given
+// section parsed as a base-10 integer. If there are no values associated with
+// the key, GetInt returns an error that wraps ErrNotFound.
+func (f *File) GetInt(section, key string) (int, error) {
+	v, err := f.typedValue(section, key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := parseInt(v)
+	if err != nil {
+		return 0, fmt.Errorf("parse ini value %s: %w", propertyName(section, key), err)
+	}
+	return n, nil
+}
+
+// GetBool returns the last value associated with the given key in the given
+// section parsed as a boolean. Accepted values are those accepted by
+// strconv.ParseBool, such as "true", "false", "1", and "0". If there are no
+// values associated with the key, GetBool returns an error that wraps
+// ErrNotFound.
+func (f *File) GetBool(section, key string) (bool, error) {
+	v, err := f.typedValue(section, key)
+	if err != nil {
+		return false, err
+	}
+	b, err := parseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("parse ini value %s: %w", propertyName(section, key), err)
+	}
+	return b, nil
+}
+
+// GetDuration returns the last value associated with the given key in the
+// given section parsed as a duration with time.ParseDuration, like "1m30s".
+// If there are no values associated with the key, GetDuration returns an
+// error that wraps ErrNotFound.
+func (f *File) GetDuration(section, key string) (time.Duration, error) {
+	v, err := f.typedValue(section, key)
+	if err != nil {
+		return 0, err
+	}
+	d, err := parseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("parse ini value %s: %w", propertyName(section, key), err)
+	}
+	return d, nil
+}
+
+// GetFloat returns the last value associated with the given key in the given
+// section parsed as a floating-point number with strconv.ParseFloat. If there
+// are no values associated with the key, GetFloat returns an error that wraps
+// ErrNotFound.
+func (f *File) GetFloat(section, key string) (float64, error) {
+	v, err := f.typedValue(section, key)
+	if err != nil {
+		return 0, err
+	}
+	x, err := parseFloat(v)
+	if err != nil {
+		return 0, fmt.Errorf("parse ini value %s: %w", propertyName(section, key), err)
+	}
+	return x, nil
+}
+
+// GetIntDefault is like GetInt, but returns def if the key is absent or its
+// value cannot be parsed.
+func (f *File) GetIntDefault(section, key string, def int) int {
+	n, err := f.GetInt(section, key)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// GetBoolDefault is like GetBool, but returns def if the key is absent or its
+// value cannot be parsed.
+func (f *File) GetBoolDefault(section, key string, def bool) bool {
+	b, err := f.GetBool(section, key)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// GetDurationDefault is like GetDuration, but returns def if the key is
+// absent or its value cannot be parsed.
+func (f *File) GetDurationDefault(section, key string, def time.Duration) time.Duration {
+	d, err := f.GetDuration(section, key)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// GetFloatDefault is like GetFloat, but returns def if the key is absent or
+// its value cannot be parsed.
+func (f *File) GetFloatDefault(section, key string, def float64) float64 {
+	x, err := f.GetFloat(section, key)
+	if err != nil {
+		return def
+	}
+	return x
+}
+
+// typedValue returns the last value associated with the given key or an error
+// wrapping ErrNotFound.
+func (f *File) typedValue(section, key string) (string, error) {
+	values := f.Find(section, key)
+	if len(values) == 0 {
+		return "", fmt.Errorf("get ini value %s: %w", propertyName(section, key), ErrNotFound)
+	}
+	return values[len(values)-1], nil
+}
+
+// GetInt returns the last value associated with the given key parsed as a
+// base-10 integer. If there are no values associated with the key, GetInt
+// returns an error that wraps ErrNotFound.
+func (sect Section) GetInt(key string) (int, error) {
+	v, err := sect.typedValue(key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := parseInt(v)
+	if err != nil {
+		return 0, fmt.Errorf("parse ini value %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// GetBool returns the last value associated with the given key parsed as a
+// boolean, using the same rules as File.GetBool. If there are no values
+// associated with the key, GetBool returns an error that wraps ErrNotFound.
+func (sect Section) GetBool(key string) (bool, error) {
+	v, err := sect.typedValue(key)
+	if err != nil {
+		return false, err
+	}
+	b, err := parseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("parse ini value %s: %w", key, err)
+	}
+	return b, nil
+}
+
+// GetDuration returns the last value associated with the given key parsed
+// as a duration with time.ParseDuration, like "1m30s". If there are no values
+// associated with the key, GetDuration returns an error that wraps
+// ErrNotFound.
+func (sect Section) GetDuration(key string) (time.Duration, error) {
+	v, err := sect.typedValue(key)
+	if err != nil {
+		return 0, err
+	}
+	d, err := parseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("parse ini value %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// GetFloat returns the last value associated with the given key parsed as a
+// floating-point number with strconv.ParseFloat. If there are no values
+// associated with the key, GetFloat returns an error that wraps ErrNotFound.
+func (sect Section) GetFloat(key string) (float64, error) {
+	v, err := sect.typedValue(key)
+	if err != nil {
+		return 0, err
+	}
+	x, err := parseFloat(v)
+	if err != nil {
+		return 0, fmt.Errorf("parse ini value %s: %w", key, err)
+	}
+	return x, nil
+}
+
+// GetIntDefault is like GetInt, but returns def if the key is absent or its
+// value cannot be parsed.
+func (sect Section) GetIntDefault(key string, def int) int {
+	n, err := sect.GetInt(key)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// GetBoolDefault is like GetBool, but returns def if the key is absent or its
+// value cannot be parsed.
+func (sect Section) GetBoolDefault(key string, def bool) bool {
+	b, err := sect.GetBool(key)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// GetDurationDefault is like GetDuration, but returns def if the key is
+// absent or its value cannot be parsed.
+func (sect Section) GetDurationDefault(key string, def time.Duration) time.Duration {
+	d, err := sect.GetDuration(key)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// GetFloatDefault is like GetFloat, but returns def if the key is absent or
+// its value cannot be parsed.
+func (sect Section) GetFloatDefault(key string, def float64) float64 {
+	x, err := sect.GetFloat(key)
+	if err != nil {
+		return def
+	}
+	return x
+}
+
+func (sect Section) typedValue(key string) (string, error) {
+	values := sect[key]
+	if len(values) == 0 {
+		return "", fmt.Errorf("get ini value %s: %w", key, ErrNotFound)
+	}
+	return values[len(values)-1], nil
+}
+
+func parseInt(v string) (int, error) {
+	return strconv.Atoi(v)
+}
+
+func parseBool(v string) (bool, error) {
+	return strconv.ParseBool(v)
+}
+
+func parseDuration(v string) (time.Duration, error) {
+	return time.ParseDuration(v)
+}
+
+func parseFloat(v string) (float64, error) {
+	return strconv.ParseFloat(v, 64)
+}
+
+// propertyName returns a human-readable name for a property for use in error
+// messages.
+func propertyName(section, key string) string {
+	if section == "" {
+		return key
+	}
+	return section + "." + key
+}
@@ -0,0 +1,282 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestGetIntSlice(t *testing.T) {
+	const source = "port=80\n" +
+		"[server]\n" +
+		"port=8080\n" +
+		"port=8081\n" +
+		"port=-1\n" +
+		"[bad]\n" +
+		"port=1\n" +
+		"port=http\n" +
+		"port=x\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		section string
+		key     string
+		want    []int
+		wantErr string
+	}{
+		{section: "", key: "port", want: []int{80}},
+		{section: "server", key: "port", want: []int{8080, 8081, -1}},
+		{section: "server", key: "missing", want: []int{}},
+		{section: "missing", key: "port", want: []int{}},
+		{section: "bad", key: "port", wantErr: "bad.port[1]"},
+	}
+	for _, test := range tests {
+		got, err := f.GetIntSlice(test.section, test.key)
+		if test.wantErr != "" {
+			if err == nil {
+				t.Errorf("f.GetIntSlice(%q, %q) = %v, <nil>; want error", test.section, test.key, got)
+				continue
+			}
+			if !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("f.GetIntSlice(%q, %q) error = %v; want to contain %q", test.section, test.key, err, test.wantErr)
+			}
+			if !errors.Is(err, strconv.ErrSyntax) {
+				t.Errorf("f.GetIntSlice(%q, %q) error = %v; want to wrap %v", test.section, test.key, err, strconv.ErrSyntax)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("f.GetIntSlice(%q, %q): %v", test.section, test.key, err)
+			continue
+		}
+		if diff := cmp.Diff(test.want, got, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("f.GetIntSlice(%q, %q) (-want +got):\n%s", test.section, test.key, diff)
+		}
+	}
+}
+
+func TestGetStringSlice(t *testing.T) {
+	f, err := Parse(strings.NewReader("[server]\nhost=a\nhost=b\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a", "b"}
+	if diff := cmp.Diff(want, f.GetStringSlice("server", "host")); diff != "" {
+		t.Errorf("f.GetStringSlice(\"server\", \"host\") (-want +got):\n%s", diff)
+	}
+	if got := f.GetStringSlice("server", "missing"); len(got) > 0 {
+		t.Errorf("f.GetStringSlice(\"server\", \"missing\") = %q; want empty", got)
+	}
+}
+
+func TestSectionDefaultGetters(t *testing.T) {
+	sect := Section{
+		"count":   {"1", "42"},
+		"neg":     {"-7"},
+		"badInt":  {"forty"},
+		"flag":    {"true"},
+		"off":     {"0"},
+		"badBool": {"yes"},
+		"timeout": {"1m30s"},
+		"badDur":  {"90"},
+		"empty":   {""},
+	}
+
+	intTests := []struct {
+		key  string
+		def  int
+		want int
+	}{
+		{key: "count", def: -1, want: 42},
+		{key: "neg", def: 0, want: -7},
+		{key: "badInt", def: 5, want: 5},
+		{key: "empty", def: 5, want: 5},
+		{key: "missing", def: 5, want: 5},
+	}
+	for _, test := range intTests {
+		if got := sect.GetIntDefault(test.key, test.def); got != test.want {
+			t.Errorf("sect.GetIntDefault(%q, %d) = %d; want %d", test.key, test.def, got, test.want)
+		}
+	}
+
+	boolTests := []struct {
+		key  string
+		def  bool
+		want bool
+	}{
+		{key: "flag", def: false, want: true},
+		{key: "off", def: true, want: false},
+		{key: "badBool", def: true, want: true},
+		{key: "empty", def: true, want: true},
+		{key: "missing", def: true, want: true},
+	}
+	for _, test := range boolTests {
+		if got := sect.GetBoolDefault(test.key, test.def); got != test.want {
+			t.Errorf("sect.GetBoolDefault(%q, %t) = %t; want %t", test.key, test.def, got, test.want)
+		}
+	}
+
+	durationTests := []struct {
+		key  string
+		def  time.Duration
+		want time.Duration
+	}{
+		{key: "timeout", def: time.Second, want: 90 * time.Second},
+		{key: "badDur", def: time.Second, want: time.Second},
+		{key: "empty", def: time.Second, want: time.Second},
+		{key: "missing", def: time.Second, want: time.Second},
+	}
+	for _, test := range durationTests {
+		if got := sect.GetDurationDefault(test.key, test.def); got != test.want {
+			t.Errorf("sect.GetDurationDefault(%q, %v) = %v; want %v", test.key, test.def, got, test.want)
+		}
+	}
+}
+
+func TestTypedGetters(t *testing.T) {
+	const source = "count=42\n" +
+		"[s]\n" +
+		"count=1\n" +
+		"count=-7\n" +
+		"flag=1\n" +
+		"timeout=1m30s\n" +
+		"ratio=0.25\n" +
+		"bad=forty\n"
+	f, err := Parse(strings.NewReader(source), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		get     func() (interface{}, error)
+		want    interface{}
+		wantErr error
+	}{
+		{
+			name: "Int/Global",
+			get:  func() (interface{}, error) { return f.GetInt("", "count") },
+			want: 42,
+		},
+		{
+			name: "Int/Last",
+			get:  func() (interface{}, error) { return f.GetInt("s", "count") },
+			want: -7,
+		},
+		{
+			name:    "Int/Bad",
+			get:     func() (interface{}, error) { return f.GetInt("s", "bad") },
+			want:    0,
+			wantErr: strconv.ErrSyntax,
+		},
+		{
+			name:    "Int/Missing",
+			get:     func() (interface{}, error) { return f.GetInt("s", "missing") },
+			want:    0,
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "Bool",
+			get:  func() (interface{}, error) { return f.GetBool("s", "flag") },
+			want: true,
+		},
+		{
+			name:    "Bool/Bad",
+			get:     func() (interface{}, error) { return f.GetBool("s", "bad") },
+			want:    false,
+			wantErr: strconv.ErrSyntax,
+		},
+		{
+			name:    "Bool/Missing",
+			get:     func() (interface{}, error) { return f.GetBool("missing", "flag") },
+			want:    false,
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "Duration",
+			get:  func() (interface{}, error) { return f.GetDuration("s", "timeout") },
+			want: 90 * time.Second,
+		},
+		{
+			name:    "Duration/Missing",
+			get:     func() (interface{}, error) { return f.GetDuration("s", "missing") },
+			want:    time.Duration(0),
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "Float",
+			get:  func() (interface{}, error) { return f.GetFloat("s", "ratio") },
+			want: 0.25,
+		},
+		{
+			name:    "Float/Bad",
+			get:     func() (interface{}, error) { return f.GetFloat("s", "bad") },
+			want:    0.0,
+			wantErr: strconv.ErrSyntax,
+		},
+		{
+			name: "Section/Int",
+			get:  func() (interface{}, error) { return f.Section("s").GetInt("count") },
+			want: -7,
+		},
+		{
+			name:    "Section/Int/Missing",
+			get:     func() (interface{}, error) { return f.Section("s").GetInt("missing") },
+			want:    0,
+			wantErr: ErrNotFound,
+		},
+		{
+			name: "Section/Bool",
+			get:  func() (interface{}, error) { return f.Section("s").GetBool("flag") },
+			want: true,
+		},
+		{
+			name: "Section/Duration",
+			get:  func() (interface{}, error) { return f.Section("s").GetDuration("timeout") },
+			want: 90 * time.Second,
+		},
+		{
+			name: "Section/Float",
+			get:  func() (interface{}, error) { return f.Section("s").GetFloat("ratio") },
+			want: 0.25,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.get()
+			if test.wantErr == nil && err != nil {
+				t.Error(err)
+			} else if test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Errorf("error = %v; want to wrap %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("got %v; want %v", got, test.want)
+			}
+		})
+	}
+
+	if got := f.GetIntDefault("s", "bad", 5); got != 5 {
+		t.Errorf("f.GetIntDefault(\"s\", \"bad\", 5) = %d; want 5", got)
+	}
+	if got := f.GetBoolDefault("s", "missing", true); !got {
+		t.Errorf("f.GetBoolDefault(\"s\", \"missing\", true) = %t; want true", got)
+	}
+	if got := f.GetDurationDefault("s", "timeout", time.Second); got != 90*time.Second {
+		t.Errorf("f.GetDurationDefault(\"s\", \"timeout\", 1s) = %v; want 1m30s", got)
+	}
+	if got := f.GetFloatDefault("s", "missing", 1.5); got != 1.5 {
+		t.Errorf("f.GetFloatDefault(\"s\", \"missing\", 1.5) = %v; want 1.5", got)
+	}
+	if got := f.Section("s").GetFloatDefault("ratio", 1.5); got != 0.25 {
+		t.Errorf("Section(\"s\").GetFloatDefault(\"ratio\", 1.5) = %v; want 0.25", got)
+	}
+}
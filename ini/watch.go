@@ -0,0 +1,168 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// Default timing for WatchFiles.
+const (
+	watchPollInterval = 1 * time.Second
+	watchDebounce     = 2 * time.Second
+)
+
+// A Watcher reloads files when they change. It is created by WatchFiles.
+type Watcher struct {
+	paths    []string
+	opts     *ParseOptions
+	onChange func(FileSet, error)
+	interval time.Duration
+	debounce time.Duration
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// WatchFiles starts watching the files at the given paths for changes. Files
+// are checked for changes in size or modification time about once a second,
+// including files being created or removed. Once any file has changed and none
+// of them have changed for a couple of seconds (to avoid reading a file that is
+// in the middle of being written), the whole set is parsed again with
+// ParseFiles and onChange is called with the new FileSet. If parsing fails,
+// onChange is called with the last set that parsed successfully (nil if none
+// has) and the error.
+//
+// onChange is called from a separate goroutine, one call at a time. The files
+// are parsed once when WatchFiles is called to establish the last good set,
+// but onChange is not called for the initial parse. Call Close on the returned
+// Watcher to stop watching.
+func WatchFiles(paths []string, opts *ParseOptions, onChange func(FileSet, error)) *Watcher {
+	return watchFiles(paths, opts, onChange, watchPollInterval, watchDebounce)
+}
+
+func watchFiles(paths []string, opts *ParseOptions, onChange func(FileSet, error), interval, debounce time.Duration) *Watcher {
+	w := &Watcher{
+		paths:    append([]string(nil), paths...),
+		opts:     opts,
+		onChange: onChange,
+		interval: interval,
+		debounce: debounce,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	state := w.stat()
+	lastGood, err := ParseFiles(opts, w.paths...)
+	if err != nil {
+		lastGood = nil
+	}
+	go w.run(state, lastGood)
+	return w
+}
+
+// Watch watches the file at the given path for changes until ctx is Done,
+// calling onChange with the newly parsed file each time it changes. Changes
+// are detected and debounced as described for WatchFiles. If the file is
+// removed, onChange is called with a nil *File. If parsing fails, onChange is
+// called with the last file that parsed successfully (nil if none has) and the
+// error.
+//
+// onChange is called from a separate goroutine, one call at a time, and is not
+// called for the initial parse. Watch blocks until ctx is Done and any call to
+// onChange in progress has returned, then returns ctx.Err().
+func Watch(ctx context.Context, path string, opts *ParseOptions, onChange func(*File, error)) error {
+	return watch(ctx, path, opts, onChange, watchPollInterval, watchDebounce)
+}
+
+func watch(ctx context.Context, path string, opts *ParseOptions, onChange func(*File, error), interval, debounce time.Duration) error {
+	w := watchFiles([]string{path}, opts, func(fset FileSet, err error) {
+		var f *File
+		if len(fset) > 0 {
+			f = fset[0]
+		}
+		onChange(f, err)
+	}, interval, debounce)
+	<-ctx.Done()
+	w.Close()
+	return ctx.Err()
+}
+
+// Close stops watching the files. After Close returns, onChange will not be
+// called again. Close must not be called from onChange. Close always
+// returns nil.
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+	return nil
+}
+
+func (w *Watcher) run(state []fileState, lastGood FileSet) {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	pending := false
+	var lastChange time.Time
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.stop:
+			return
+		}
+		if newState := w.stat(); !equalFileStates(state, newState) {
+			state = newState
+			pending = true
+			lastChange = time.Now()
+			continue
+		}
+		if !pending || time.Since(lastChange) < w.debounce {
+			continue
+		}
+		pending = false
+		fset, err := ParseFiles(w.opts, w.paths...)
+		if err != nil {
+			w.onChange(lastGood, err)
+			continue
+		}
+		lastGood = fset
+		w.onChange(fset, nil)
+	}
+}
+
+// fileState is the information used to detect a change to a file.
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func (w *Watcher) stat() []fileState {
+	state := make([]fileState, len(w.paths))
+	for i, p := range w.paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		state[i] = fileState{
+			exists:  true,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		}
+	}
+	return state
+}
+
+func equalFileStates(a, b []fileState) bool {
+	for i := range a {
+		if a[i].exists != b[i].exists || a[i].size != b[i].size || !a[i].modTime.Equal(b[i].modTime) {
+			return false
+		}
+	}
+	return true
+}
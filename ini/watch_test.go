@@ -0,0 +1,162 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package ini
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{
+		filepath.Join(dir, "local.ini"),
+		filepath.Join(dir, "global.ini"),
+	}
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := ioutil.WriteFile(path, []byte(content), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(paths[0], "name=local\n")
+	writeFile(paths[1], "name=global\ncolor=blue\n")
+
+	type reload struct {
+		fset FileSet
+		err  error
+	}
+	reloads := make(chan reload, 10)
+	w := watchFiles(paths, nil, func(fset FileSet, err error) {
+		reloads <- reload{fset, err}
+	}, 5*time.Millisecond, 20*time.Millisecond)
+	defer w.Close()
+	waitReload := func() reload {
+		t.Helper()
+		select {
+		case r := <-reloads:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for reload")
+			return reload{}
+		}
+	}
+
+	// Change a lower-precedence file with several rapid writes.
+	writeFile(paths[1], "name=global\ncolor=red\n")
+	writeFile(paths[1], "name=global\ncolor=green\n")
+	writeFile(paths[1], "name=global\ncolor=yellow\n")
+	r := waitReload()
+	if r.err != nil {
+		t.Fatal("reload error:", r.err)
+	}
+	if got, want := r.fset.Get("", "color"), "yellow"; got != want {
+		t.Errorf("after change, color = %q; want %q", got, want)
+	}
+	if got, want := r.fset.Get("", "name"), "local"; got != want {
+		t.Errorf("after change, name = %q; want %q", got, want)
+	}
+	select {
+	case r := <-reloads:
+		t.Errorf("extra reload (debounce failed): %v, %v", r.fset, r.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A parse error keeps the last good set.
+	writeFile(paths[0], "[bad\n")
+	r = waitReload()
+	if r.err == nil {
+		t.Error("reload after bad write did not return an error")
+	}
+	if got, want := r.fset.Get("", "name"), "local"; got != want {
+		t.Errorf("after bad write, name = %q; want %q", got, want)
+	}
+
+	// Removing a file is a change.
+	if err := os.Remove(paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	r = waitReload()
+	if r.err != nil {
+		t.Fatal("reload error:", r.err)
+	}
+	if got, want := r.fset.Get("", "name"), "global"; got != want {
+		t.Errorf("after remove, name = %q; want %q", got, want)
+	}
+
+	// No reloads after Close.
+	if err := w.Close(); err != nil {
+		t.Error("Close:", err)
+	}
+	writeFile(paths[0], "name=closed\n")
+	select {
+	case r := <-reloads:
+		t.Errorf("reload after Close: %v, %v", r.fset, r.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.ini")
+	if err := ioutil.WriteFile(path, []byte("color=blue\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	type reload struct {
+		f   *File
+		err error
+	}
+	reloads := make(chan reload, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watchDone := make(chan error, 1)
+	go func() {
+		watchDone <- watch(ctx, path, nil, func(f *File, err error) {
+			reloads <- reload{f, err}
+		}, 5*time.Millisecond, 20*time.Millisecond)
+	}()
+
+	// Give the watcher a chance to record the initial state.
+	time.Sleep(100 * time.Millisecond)
+	if err := ioutil.WriteFile(path, []byte("color=red\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-reloads:
+		if r.err != nil {
+			t.Fatal("reload error:", r.err)
+		}
+		if got, want := r.f.Get("", "color"), "red"; got != want {
+			t.Errorf("after change, color = %q; want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-reloads:
+		if r.err != nil || r.f != nil {
+			t.Errorf("after remove, reload = %v, %v; want <nil>, <nil>", r.f, r.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+
+	cancel()
+	select {
+	case err := <-watchDone:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Watch = %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not return after cancel")
+	}
+}
@@ -6,6 +6,7 @@ package retry
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"zombiezen.com/go/log"
@@ -22,15 +23,82 @@ type BackoffStrategy interface {
 // nil before the Context is Done. The function is guaranteed to be called at
 // least once.
 //
+// If the function returns an error wrapped with Progress, Do calls the
+// strategy's Reset method (if it has one) and calls the function again without
+// waiting, unless the Context is Done.
+//
 // The operation should be a verb phrase like "talking to Alice" for logging.
 func Do(ctx context.Context, operation string, strategy BackoffStrategy, f func() error) error {
+	return do(ctx, operation, strategy, false, func(context.Context) error {
+		return f()
+	})
+}
+
+// DoCtx is like Do, but passes a Context to the function. If ctx has a
+// deadline, DoCtx returns the function's error without waiting once the
+// deadline would pass before the next attempt. After an attempt fails, DoCtx
+// also obtains the duration to wait after the following attempt from the
+// strategy ahead of time: if the deadline would pass before that duration
+// elapses, the following attempt is called with a Context for which
+// IsLastAttempt reports true. The first attempt is never marked as the last.
+func DoCtx(ctx context.Context, operation string, strategy BackoffStrategy, f func(ctx context.Context) error) error {
+	return do(ctx, operation, strategy, true, f)
+}
+
+// IsLastAttempt reports whether ctx was passed to a function by DoCtx for an
+// attempt that will not be retried because the Context's deadline would pass
+// before the next attempt. The function can use this to take a cheaper or
+// best-effort path.
+func IsLastAttempt(ctx context.Context) bool {
+	last, _ := ctx.Value(lastAttemptKey{}).(bool)
+	return last
+}
+
+type lastAttemptKey struct{}
+
+func do(ctx context.Context, operation string, strategy BackoffStrategy, lookahead bool, f func(context.Context) error) error {
 	var t *time.Timer
+	deadline, hasDeadline := ctx.Deadline()
+	lookahead = lookahead && hasDeadline
+	// next is the duration to wait after the current attempt, obtained from
+	// the strategy after the previous attempt failed. It is only valid if
+	// hasNext is true.
+	var next time.Duration
+	hasNext := false
 	for {
-		err := f()
+		attemptCtx := ctx
+		if hasNext && time.Until(deadline) <= next {
+			attemptCtx = context.WithValue(ctx, lastAttemptKey{}, true)
+		}
+		err := f(attemptCtx)
 		if err == nil {
 			return nil
 		}
-		d := strategy.Duration()
+		if IsProgress(err) {
+			log.Debugf(ctx, "Progress %s (will retry): %v", operation, err)
+			if r, ok := strategy.(resetter); ok {
+				r.Reset()
+			}
+			hasNext = false
+			select {
+			case <-ctx.Done():
+				return err
+			default:
+				continue
+			}
+		}
+		d := next
+		if !hasNext {
+			d = strategy.Duration()
+		}
+		hasNext = false
+		if lookahead {
+			if time.Until(deadline) <= d {
+				return err
+			}
+			next = strategy.Duration()
+			hasNext = true
+		}
 		if d > 0 {
 			log.Warnf(ctx, "Error %s (will retry in %v): %v", operation, d, err)
 			if t == nil {
@@ -45,7 +113,7 @@ func Do(ctx context.Context, operation string, strategy BackoffStrategy, f func(
 				return err
 			}
 		} else {
-			log.Warnf(ctx, "Error %s (will retry): %v", operation, d, err)
+			log.Warnf(ctx, "Error %s (will retry): %v", operation, err)
 			select {
 			case <-ctx.Done():
 				return err
@@ -54,3 +122,38 @@ func Do(ctx context.Context, operation string, strategy BackoffStrategy, f func(
 		}
 	}
 }
+
+// resetter is implemented by BackoffStrategy values that can restart their
+// sequence of durations.
+type resetter interface {
+	Reset()
+}
+
+// Progress wraps an error to signal to Do that the operation made progress
+// even though it did not finish, such as a paginated download that fetched
+// some pages. The returned error unwraps to err. If err is nil, Progress
+// returns an error with a generic message.
+func Progress(err error) error {
+	return progressError{err}
+}
+
+// IsProgress reports whether any error in err's chain was returned by
+// Progress.
+func IsProgress(err error) bool {
+	return errors.As(err, new(progressError))
+}
+
+type progressError struct {
+	err error
+}
+
+func (e progressError) Error() string {
+	if e.err == nil {
+		return "made progress"
+	}
+	return e.err.Error()
+}
+
+func (e progressError) Unwrap() error {
+	return e.err
+}
@@ -6,6 +6,7 @@ package retry
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -168,12 +169,259 @@ func TestSleepLoop(t *testing.T) {
 	})
 }
 
+func TestProgress(t *testing.T) {
+	t.Run("ResetsBackoff", func(t *testing.T) {
+		ctx := testlog.WithTB(context.Background(), t)
+		strategy := new(countingBackoff)
+		ncalls := 0
+		f := func() error {
+			ncalls++
+			switch ncalls {
+			case 1, 2:
+				return errors.New("bork")
+			case 3:
+				return Progress(errors.New("advanced"))
+			case 4:
+				return errors.New("bork")
+			default:
+				return nil
+			}
+		}
+		if err := Do(ctx, "calling a function", strategy, f); err != nil {
+			t.Error("Do:", err)
+		}
+		if ncalls != 5 {
+			t.Errorf("f called %d times; want 5 times", ncalls)
+		}
+		// Errors from calls 1, 2, and 4 ask for a duration. The Progress
+		// return from call 3 resets the count without asking for one.
+		want := []int{1, 2, 1}
+		if !equalInts(strategy.calls, want) {
+			t.Errorf("backoff durations requested at counts %v; want %v", strategy.calls, want)
+		}
+		if strategy.resets != 1 {
+			t.Errorf("strategy reset %d times; want 1 time", strategy.resets)
+		}
+	})
+
+	t.Run("WrappedProgress", func(t *testing.T) {
+		ctx := testlog.WithTB(context.Background(), t)
+		strategy := new(countingBackoff)
+		ncalls := 0
+		f := func() error {
+			ncalls++
+			if ncalls == 1 {
+				return fmt.Errorf("download: %w", Progress(nil))
+			}
+			return nil
+		}
+		if err := Do(ctx, "calling a function", strategy, f); err != nil {
+			t.Error("Do:", err)
+		}
+		if len(strategy.calls) != 0 || strategy.resets != 1 {
+			t.Errorf("strategy durations = %v, resets = %d; want [], 1", strategy.calls, strategy.resets)
+		}
+	})
+
+	t.Run("WithoutReset", func(t *testing.T) {
+		ctx := testlog.WithTB(context.Background(), t)
+		ncalls := 0
+		f := func() error {
+			ncalls++
+			if ncalls == 1 {
+				return Progress(errors.New("advanced"))
+			}
+			return nil
+		}
+		if err := Do(ctx, "calling a function", constBackoff(time.Hour), f); err != nil {
+			t.Error("Do:", err)
+		}
+		if ncalls != 2 {
+			t.Errorf("f called %d times; want 2 times", ncalls)
+		}
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(testlog.WithTB(context.Background(), t))
+		want := errors.New("bork")
+		ncalls := 0
+		f := func() error {
+			ncalls++
+			cancel()
+			return Progress(want)
+		}
+		got := Do(ctx, "calling a function", new(countingBackoff), f)
+		if !errors.Is(got, want) {
+			t.Errorf("Do = %v; want %v", got, want)
+		}
+		if !IsProgress(got) {
+			t.Errorf("IsProgress(%v) = false; want true", got)
+		}
+		if ncalls != 1 {
+			t.Errorf("f called %d times; want 1 time", ncalls)
+		}
+	})
+}
+
+func TestIsLastAttempt(t *testing.T) {
+	t.Run("Deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(testlog.WithTB(context.Background(), t), time.Minute)
+		defer cancel()
+		strategy := &seqBackoff{durations: []time.Duration{0, 0, time.Hour}}
+		var got []bool
+		want := errors.New("bork")
+		f := func(ctx context.Context) error {
+			got = append(got, IsLastAttempt(ctx))
+			return want
+		}
+		start := time.Now()
+		if err := DoCtx(ctx, "calling a function", strategy, f); !errors.Is(err, want) {
+			t.Errorf("DoCtx = %v; want %v", err, want)
+		}
+		if elapsed := time.Since(start); elapsed >= 30*time.Second {
+			t.Errorf("DoCtx took %v; want to return without waiting for the deadline", elapsed)
+		}
+		if !equalBools(got, []bool{false, false, true}) {
+			t.Errorf("IsLastAttempt per call = %v; want [false false true]", got)
+		}
+	})
+
+	t.Run("StrategyCalls", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			failures  int
+			wantCalls []int
+		}{
+			{name: "ImmediateSuccess", failures: 0, wantCalls: nil},
+			{name: "SecondTimeSuccess", failures: 1, wantCalls: []int{1, 2}},
+			{name: "ThirdTimeSuccess", failures: 2, wantCalls: []int{1, 2, 3}},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(testlog.WithTB(context.Background(), t), time.Minute)
+				defer cancel()
+				strategy := new(countingBackoff)
+				ncalls := 0
+				f := func(ctx context.Context) error {
+					ncalls++
+					if ncalls <= test.failures {
+						return errors.New("bork")
+					}
+					return nil
+				}
+				if err := DoCtx(ctx, "calling a function", strategy, f); err != nil {
+					t.Error("DoCtx:", err)
+				}
+				if !equalInts(strategy.calls, test.wantCalls) {
+					t.Errorf("strategy calls = %v; want %v", strategy.calls, test.wantCalls)
+				}
+			})
+		}
+	})
+
+	t.Run("NoDeadline", func(t *testing.T) {
+		ctx := testlog.WithTB(context.Background(), t)
+		strategy := &seqBackoff{durations: []time.Duration{0, 0}}
+		var got []bool
+		f := func(ctx context.Context) error {
+			got = append(got, IsLastAttempt(ctx))
+			if len(got) < 3 {
+				return errors.New("bork")
+			}
+			return nil
+		}
+		if err := DoCtx(ctx, "calling a function", strategy, f); err != nil {
+			t.Error("DoCtx:", err)
+		}
+		if !equalBools(got, []bool{false, false, false}) {
+			t.Errorf("IsLastAttempt per call = %v; want [false false false]", got)
+		}
+		if strategy.calls != 2 {
+			t.Errorf("strategy called %d times; want 2", strategy.calls)
+		}
+	})
+
+	t.Run("Do", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(testlog.WithTB(context.Background(), t), time.Minute)
+		defer cancel()
+		strategy := &seqBackoff{durations: []time.Duration{0}}
+		if err := Do(ctx, "calling a function", strategy, func() error { return nil }); err != nil {
+			t.Error("Do:", err)
+		}
+		if strategy.calls != 0 {
+			t.Errorf("strategy called %d times; want 0", strategy.calls)
+		}
+	})
+
+	if IsLastAttempt(context.Background()) {
+		t.Error("IsLastAttempt(context.Background()) = true; want false")
+	}
+}
+
 type constBackoff time.Duration
 
 func (b constBackoff) Duration() time.Duration {
 	return time.Duration(b)
 }
 
+// countingBackoff always returns a zero duration, recording how many times
+// Duration has been called since the last reset.
+type countingBackoff struct {
+	n      int
+	calls  []int
+	resets int
+}
+
+func (b *countingBackoff) Duration() time.Duration {
+	b.n++
+	b.calls = append(b.calls, b.n)
+	return 0
+}
+
+func (b *countingBackoff) Reset() {
+	b.n = 0
+	b.resets++
+}
+
+// seqBackoff returns durations in sequence, repeating the last one.
+type seqBackoff struct {
+	durations []time.Duration
+	calls     int
+}
+
+func (b *seqBackoff) Duration() time.Duration {
+	i := b.calls
+	if i >= len(b.durations) {
+		i = len(b.durations) - 1
+	}
+	b.calls++
+	return b.durations[i]
+}
+
+func equalBools(a, b []bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func TestMain(m *testing.M) {
 	testlog.Main(nil)
 	os.Exit(m.Run())
@@ -0,0 +1,34 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package xcontext
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrWaitTimeout is returned by WaitDone when the duration elapses before the
+// Context is Done.
+var ErrWaitTimeout = errors.New("timed out waiting for context to be done")
+
+// WaitDone blocks until ctx is Done or d has elapsed, whichever comes first.
+// If ctx is Done first, WaitDone returns ctx.Err(). Otherwise, it returns
+// ErrWaitTimeout.
+func WaitDone(ctx context.Context, d time.Duration) error {
+	done := ctx.Done()
+	select {
+	case <-done:
+		return ctx.Err()
+	default:
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return ctx.Err()
+	case <-timer.C:
+		return ErrWaitTimeout
+	}
+}
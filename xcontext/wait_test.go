@@ -0,0 +1,49 @@
+// Copyright 2020 YourBase Inc.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package xcontext
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitDone(t *testing.T) {
+	t.Run("AlreadyDone", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := WaitDone(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+			t.Errorf("WaitDone(ctx, time.Hour) = %v; want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("DoneBeforeTimeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := WaitDone(ctx, time.Hour); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("WaitDone(ctx, time.Hour) = %v; want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		const d = 10 * time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		start := time.Now()
+		if err := WaitDone(ctx, d); !errors.Is(err, ErrWaitTimeout) {
+			t.Errorf("WaitDone(ctx, %v) = %v; want %v", d, err, ErrWaitTimeout)
+		}
+		if elapsed := time.Since(start); elapsed < d {
+			t.Errorf("WaitDone(ctx, %v) returned after %v", d, elapsed)
+		}
+	})
+
+	t.Run("NeverDone", func(t *testing.T) {
+		const d = 10 * time.Millisecond
+		if err := WaitDone(context.Background(), d); !errors.Is(err, ErrWaitTimeout) {
+			t.Errorf("WaitDone(context.Background(), %v) = %v; want %v", d, err, ErrWaitTimeout)
+		}
+	})
+}